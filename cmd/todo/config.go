@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/Neurostep/todo/config"
+	"github.com/Neurostep/todo/internal/cli"
+)
+
+var configCommand = &cli.Command{
+	Name:        "config",
+	Summary:     "Print the configuration the server would run with",
+	Subcommands: []string{"print"},
+	Run:         runConfig,
+}
+
+func runConfig(ctx context.Context, env *cli.Env, args []string) error {
+	fs := flag.NewFlagSet("todo config", flag.ContinueOnError)
+	fs.SetOutput(env.Stderr)
+	loader := config.NewLoader(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: todo config print [flags]")
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 || args[0] != "print" {
+		fs.Usage()
+		return errors.New("subcommand is required")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	// the settings are printed even when invalid, along with the errors
+	cfg, loadErr := loader.Load()
+	if _, invalid := loadErr.(config.Errors); loadErr != nil && !invalid {
+		return loadErr
+	}
+	out, err := yaml.Marshal(cfg.Redacted())
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(env.Stdout, string(out))
+	return loadErr
+}
@@ -3,49 +3,118 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/go-kit/kit/log"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
 	"go.opencensus.io/stats/view"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/Neurostep/todo/config"
+	"github.com/Neurostep/todo/internal/cli"
 	"github.com/Neurostep/todo/internal/server"
+	"github.com/Neurostep/todo/pkg/cache"
 	"github.com/Neurostep/todo/pkg/database"
+	"github.com/Neurostep/todo/pkg/idempotency"
+	"github.com/Neurostep/todo/pkg/ratelimit"
 	"github.com/Neurostep/todo/pkg/services/todo"
+	"github.com/Neurostep/todo/pkg/tools/health"
+	"github.com/Neurostep/todo/pkg/tools/logging"
 	"github.com/Neurostep/todo/pkg/tools/metrics"
 )
 
+const (
+	poolStatsInterval     = 10 * time.Second
+	overdueInterval       = time.Minute
+	defaultIdempotencyTTL = 24 * time.Hour
+)
+
+var serveCommand = &cli.Command{
+	Name:    "serve",
+	Summary: "Start the API server (default when no command is given)",
+	Run:     serve,
+}
+
 func main() {
-	ctx, cancelFunc := context.WithCancel(context.Background())
+	app := cli.New(os.Stdin, os.Stdout, os.Stderr)
+	app.Register(serveCommand)
+	app.Register(migrateCommand)
+	app.Register(configCommand)
+
+	args := os.Args[1:]
+	// keep "todo -cfg config.yaml" starting the server
+	if len(args) == 0 || !app.IsCommand(args[0]) && strings.HasPrefix(args[0], "-") {
+		args = append([]string{serveCommand.Name}, args...)
+	}
+
+	os.Exit(app.Run(context.Background(), args))
+}
+
+func serve(ctx context.Context, env *cli.Env, args []string) error {
+	ctx, cancelFunc := context.WithCancel(ctx)
 	defer cancelFunc()
 
-	logger := log.NewJSONLogger(log.NewSyncWriter(os.Stdout))
+	logger := logging.Filter(log.NewJSONLogger(log.NewSyncWriter(env.Stdout)))
 	logger = log.With(logger, "ts", log.DefaultTimestampUTC, "caller", log.DefaultCaller)
 
-	configPath := flag.String("cfg", "", "config file")
-	flag.Parse()
+	fs := flag.NewFlagSet("todo serve", flag.ContinueOnError)
+	fs.SetOutput(env.Stderr)
+	loader := config.NewLoader(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
 
-	cfg, err := config.ReadConfigFile(*configPath)
+	cfg, err := loader.Load()
 	if err != nil {
-		logger.Log("error", "failed to read config file", "configPath", configPath, "cause", err)
-		os.Exit(1)
+		logger.Log("error", "failed to load configuration", "configPath", loader.Path)
+		return err
 	}
 
-	cfgDatabase := database.Config{
-		Address: cfg.Database.Address,
+	if err := logging.SetTraceFormat(cfg.Logging.TraceFormat, cfg.Logging.Project); err != nil {
+		return err
+	}
+	if err := logging.SetLevel(cfg.Logging.Level); err != nil {
+		return err
 	}
-	db, err := database.New(cfgDatabase, log.With(logger, "service", "database"))
+
+	checks := health.New()
+
+	repository, closeRepository, err := newRepository(ctx, cfg.Database, checks, log.With(logger, "service", "database"))
 	if err != nil {
 		logger.Log("error", "failed to setup database connection", "cause", err)
-		os.Exit(1)
+		return err
 	}
-	defer db.Close()
+	defer closeRepository()
 
+	if err := view.Register(todo.Views...); err != nil {
+		logger.Log("event", "todo_monitoring_view_register_failed", "error", err)
+	}
+	repository = todo.NewInstrumentedRepository(repository)
+	go todo.RecordOverdue(ctx, repository, overdueInterval)
+
+	exporterStatus := &metrics.ExporterStatus{}
 	cfgMetrics := metrics.Config{
 		TracingEnable: cfg.Metrics.TracingEnable,
+		Tracing: metrics.Tracing{
+			Exporter:    cfg.Metrics.Tracing.Exporter,
+			Endpoint:    cfg.Metrics.Tracing.Endpoint,
+			Insecure:    cfg.Metrics.Tracing.Insecure,
+			Headers:     cfg.Metrics.Tracing.Headers,
+			Sampler:     cfg.Metrics.Tracing.Sampler,
+			Ratio:       cfg.Metrics.Tracing.Ratio,
+			PerSecond:   cfg.Metrics.Tracing.PerSecond,
+			ParentBased: cfg.Metrics.Tracing.ParentBased,
+		},
+		OnError: exporterStatus.Report,
+	}
+	if cfg.Metrics.TracingEnable || cfg.Metrics.Tracing.Exporter != "" {
+		checks.Register(health.Check{Name: "tracing_exporter", Func: exporterStatus.Check})
 	}
 	tracingDone, err := metrics.SetupTracer("todo_service", cfgMetrics, log.With(logger, "service", "tracing"))
 	if err != nil {
@@ -57,28 +126,85 @@ func main() {
 	if err != nil {
 		logger.Log("error", "failed to setup prometheus monitoring", "cause", err)
 		prometheusExporter = nil
+		setupErr := err
+		checks.Register(health.Check{Name: "metrics_exporter", Func: func(context.Context) error {
+			return setupErr
+		}})
 	} else {
 		defer func() {
 			view.UnregisterExporter(prometheusExporter)
 		}()
 	}
 
-	todoService := todo.New(todo.Config{
-		DB:     db,
-		Logger: log.With(logger, "service", "todo"),
+	var todoService todo.ServiceProvider = todo.New(todo.Config{
+		Repository: repository,
+		Logger:     log.With(logger, "service", "todo"),
+		Notifier:   todo.NewLogNotifier(log.With(logger, "service", "notifier")),
 	})
+	if c, closeCache := newCache(cfg.Cache, checks, log.With(logger, "service", "cache")); c != nil {
+		defer closeCache()
+		cacheCfg := todo.CacheConfig{
+			Service: todoService,
+			Cache:   c,
+			TTL:     cfg.Cache.TTL,
+			Logger:  log.With(logger, "service", "cache"),
+		}
+		// clients reading their own writes from the primary skip the cache
+		if r, ok := repository.(*todo.ReplicatedRepository); ok {
+			cacheCfg.Bypass = r.Sticky
+		}
+		todoService = todo.NewCachedService(cacheCfg)
+	}
+
+	rateLimiter, closeRateLimiter := newRateLimiter(cfg.RateLimit, checks, log.With(logger, "service", "ratelimit"))
+	defer closeRateLimiter()
+
+	idempotencyStore, closeIdempotencyStore := newIdempotencyStore(cfg.Idempotency, checks)
+	defer closeIdempotencyStore()
+	idempotencyTTL := cfg.Idempotency.TTL
+	if idempotencyTTL == 0 {
+		idempotencyTTL = defaultIdempotencyTTL
+	}
 
 	serverCfg := server.Config{
-		Debug:              cfg.Server.Debug,
-		Port:               cfg.Server.Port,
-		AuthEnabled:        cfg.Server.AuthEnabled,
-		DB:                 db,
-		TodoService:        todoService,
-		Logger:             log.With(logger, "service", "http"),
-		PrometheusExporter: prometheusExporter,
+		Debug:            cfg.Server.Debug,
+		Port:             cfg.Server.Port,
+		AuthEnabled:      cfg.Server.AuthEnabled,
+		TrustTraceParent: cfg.Metrics.Tracing.TrustTraceParent,
+		ReadTimeout:      cfg.Server.ReadTimeout,
+		WriteTimeout:     cfg.Server.WriteTimeout,
+		IdleTimeout:      cfg.Server.IdleTimeout,
+		RequestTimeout:   cfg.Server.RequestTimeout,
+		RouteTimeouts:    cfg.Server.RouteTimeouts,
+		TodoService:      todoService,
+		Logger:           log.With(logger, "service", "http"),
+		CORS:             server.CORSConfig(cfg.Server.CORS),
+		JWTKeys:          jwtKeys(cfg.Server.JWTKeys),
+		RateLimiter:      rateLimiter,
+		Idempotency:      idempotencyStore,
+		IdempotencyTTL:   idempotencyTTL,
+		Health:           checks,
+		ShutdownDelay:    cfg.Server.ShutdownDelay,
 	}
 	s := server.New(serverCfg)
 
+	reloader := &reloader{
+		loader:  loader,
+		current: cfg,
+		server:  s,
+		limiter: rateLimiter,
+		logger:  log.With(logger, "service", "config"),
+	}
+
+	adminServer := server.NewAdmin(server.AdminConfig{
+		Port:               cfg.Server.AdminPort,
+		Logger:             log.With(logger, "service", "admin"),
+		PrometheusExporter: prometheusExporter,
+		Health:             checks,
+		Settings:           func() interface{} { return reloader.config().Redacted() },
+		ShutdownDelay:      cfg.Server.ShutdownDelay,
+	})
+
 	group, groupCtx := errgroup.WithContext(ctx)
 
 	// server start
@@ -88,28 +214,230 @@ func main() {
 		return err
 	})
 
-	// signal handlers
+	group.Go(func() error {
+		return adminServer.Start(groupCtx)
+	})
+
+	// signal handlers, SIGHUP reloads the configuration
 	interrupt := make(chan os.Signal, 2)
+	hangup := make(chan os.Signal, 1)
 	cancel := make(chan struct{})
 	defer close(interrupt)
 
 	group.Go(func() error {
 		signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+		signal.Notify(hangup, syscall.SIGHUP)
+		defer signal.Stop(hangup)
 
-		select {
-		case sig := <-interrupt:
-			logger.Log("event", "captured signal", sig)
-			cancelFunc()
-		case <-groupCtx.Done():
-		case <-cancel:
-		}
+		for {
+			select {
+			case <-hangup:
+				reloader.reload()
+				continue
+			case sig := <-interrupt:
+				logger.Log("event", "captured signal", sig)
+				cancelFunc()
+			case <-groupCtx.Done():
+			case <-cancel:
+			}
 
-		return nil
+			return nil
+		}
 	})
 
 	if err := group.Wait(); err != nil {
 		logger.Log("event", "error", "cause", err)
 		close(cancel)
 		signal.Stop(interrupt)
+		return err
+	}
+
+	return nil
+}
+
+// newRepository creates the todo repository for the configured database
+// driver and registers its health checks. The returned func releases the
+// connection. Postgres is migrated when configured to, and refused when its
+// schema is older than expected.
+func newRepository(ctx context.Context, cfg config.Database, checks *health.Registry, logger log.Logger) (todo.Repository, func(), error) {
+	switch cfg.Driver {
+	case database.DriverMemory:
+		logger.Log("event", "using in-memory storage, data is lost on exit")
+		return todo.NewMemoryRepository(), func() {}, nil
+	case database.DriverSQLite:
+		db, err := database.New(ctx, databaseConfig(cfg), logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := todo.AutoMigrate(db); err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		checks.Register(pingCheck("database", db, true))
+		return todo.NewGormRepository(db), func() { db.Close() }, nil
+	}
+
+	db, migrator, err := newMigrator(ctx, cfg, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.MigrateOnStart {
+		if err := migrator.Up(ctx); err != nil {
+			db.Close()
+			return nil, nil, errors.Wrap(err, "failed to migrate the database")
+		}
+	}
+	if err := migrator.Check(ctx); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	checks.Register(pingCheck("database", db, true))
+	checks.Register(health.Check{
+		Name:     "migrations",
+		Critical: true,
+		TTL:      30 * time.Second,
+		Func:     migrator.Check,
+	})
+
+	if err := view.Register(database.PoolViews...); err != nil {
+		logger.Log("event", "database_monitoring_view_register_failed", "error", err)
+	}
+	bgCtx, stop := context.WithCancel(ctx)
+	go database.RecordPoolStats(bgCtx, "primary", db.DB(), poolStatsInterval)
+
+	dbs := []*gorm.DB{db}
+	closeAll := func() {
+		stop()
+		for _, db := range dbs {
+			db.Close()
+		}
+	}
+
+	if len(cfg.Replicas) == 0 {
+		return todo.NewGormRepository(db), closeAll, nil
+	}
+
+	var replicas []todo.Replica
+	for i, address := range cfg.Replicas {
+		name := fmt.Sprintf("replica-%d", i)
+		replicaCfg := databaseConfig(cfg)
+		replicaCfg.Address = address
+
+		replica, err := database.New(ctx, replicaCfg, log.With(logger, "replica", name))
+		if err != nil {
+			closeAll()
+			return nil, nil, errors.Wrapf(err, "failed to connect to %s", name)
+		}
+		dbs = append(dbs, replica)
+
+		checks.Register(pingCheck("database_"+name, replica, false))
+		go database.RecordPoolStats(bgCtx, name, replica.DB(), poolStatsInterval)
+		replicas = append(replicas, todo.Replica{
+			Name:       name,
+			Repository: todo.NewGormRepository(replica),
+			Ping:       replica.DB().PingContext,
+		})
+	}
+
+	repository := todo.NewReplicatedRepository(todo.ReplicationConfig{
+		Primary:      todo.NewGormRepository(db),
+		Replicas:     replicas,
+		StickyWindow: cfg.ReplicaStickyWindow,
+		Logger:       logger,
+	})
+	go repository.Run(bgCtx)
+
+	return repository, closeAll, nil
+}
+
+// newCache creates the configured cache and registers its health check, it
+// returns a nil cache when caching is disabled.
+func newCache(cfg config.Cache, checks *health.Registry, logger log.Logger) (cache.Cache, func()) {
+	if cfg.Driver == "" || cfg.Driver == cache.DriverNone {
+		return nil, nil
+	}
+	if err := view.Register(cache.Views...); err != nil {
+		logger.Log("event", "cache_monitoring_view_register_failed", "error", err)
+	}
+
+	switch cfg.Driver {
+	case cache.DriverMemory:
+		return cache.NewLRU(cfg.Size), func() {}
+	case cache.DriverRedis:
+		c := cache.NewRedis(cache.RedisConfig{
+			Address:  cfg.Address,
+			Password: cfg.Password,
+			DB:       cfg.DB,
+		})
+		// reads go to the database while the cache is unreachable
+		checks.Register(health.Check{Name: "cache", Func: c.Ping})
+		return c, func() { c.Close() }
+	}
+	return nil, nil
+}
+
+// newRateLimiter creates the limiter of the configured groups, it returns
+// nil when no group is limited.
+func newRateLimiter(cfg config.RateLimit, checks *health.Registry, logger log.Logger) (*ratelimit.Limiter, func()) {
+	if len(cfg.Groups) == 0 {
+		return nil, func() {}
+	}
+	if err := view.Register(ratelimit.Views...); err != nil {
+		logger.Log("event", "ratelimit_monitoring_view_register_failed", "error", err)
+	}
+
+	limits := rateLimits(cfg)
+	if cfg.Store != ratelimit.StoreRedis {
+		return ratelimit.NewLimiter(ratelimit.NewMemory(), limits, logger), func() {}
+	}
+
+	client := cache.NewRedis(cache.RedisConfig{
+		Address:  cfg.Address,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	// requests pass while the store is unreachable
+	checks.Register(health.Check{Name: "rate_limit_store", Func: client.Ping})
+	return ratelimit.NewLimiter(ratelimit.NewRedis(client), limits, logger), func() { client.Close() }
+}
+
+// newIdempotencyStore creates the configured store of idempotent responses.
+func newIdempotencyStore(cfg config.Idempotency, checks *health.Registry) (idempotency.Store, func()) {
+	if cfg.Store != idempotency.StoreRedis {
+		return idempotency.NewMemory(), func() {}
+	}
+
+	client := cache.NewRedis(cache.RedisConfig{
+		Address:  cfg.Address,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	// requests are served without deduplication while it is unreachable
+	checks.Register(health.Check{Name: "idempotency_store", Func: client.Ping})
+	return idempotency.NewRedis(client), func() { client.Close() }
+}
+
+func pingCheck(name string, db *gorm.DB, critical bool) health.Check {
+	return health.Check{
+		Name:     name,
+		Critical: critical,
+		Func:     db.DB().PingContext,
+	}
+}
+
+// databaseConfig maps the database section of the config file.
+func databaseConfig(cfg config.Database) database.Config {
+	return database.Config{
+		Driver:             cfg.Driver,
+		Address:            cfg.Address,
+		MaxOpenConns:       cfg.MaxOpenConns,
+		MaxIdleConns:       cfg.MaxIdleConns,
+		ConnMaxLifetime:    cfg.ConnMaxLifetime,
+		StatementTimeout:   cfg.StatementTimeout,
+		ConnectRetries:     cfg.ConnectRetries,
+		ConnectBackoff:     cfg.ConnectBackoff,
+		LogQueries:         cfg.LogQueries,
+		SlowQueryThreshold: cfg.SlowQueryThreshold,
 	}
 }
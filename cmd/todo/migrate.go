@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+
+	"github.com/Neurostep/todo/config"
+	"github.com/Neurostep/todo/internal/cli"
+	"github.com/Neurostep/todo/migrations"
+	"github.com/Neurostep/todo/pkg/database"
+	"github.com/Neurostep/todo/pkg/database/migrate"
+)
+
+var migrateCommand = &cli.Command{
+	Name:        "migrate",
+	Summary:     "Apply, revert or inspect the database migrations",
+	Subcommands: []string{"up", "down", "status", "create"},
+	Run:         runMigrate,
+}
+
+func runMigrate(ctx context.Context, env *cli.Env, args []string) error {
+	fs := flag.NewFlagSet("todo migrate", flag.ContinueOnError)
+	fs.SetOutput(env.Stderr)
+	loader := config.NewLoader(fs)
+	dir := fs.String("dir", "migrations", "directory to create new migrations in")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: todo migrate [flags] up | down [steps] | status | create <name>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("subcommand is required")
+	}
+
+	if fs.Arg(0) == "create" {
+		if fs.NArg() != 2 {
+			return errors.New("migration name is required")
+		}
+		up, down, err := migrate.Create(*dir, fs.Arg(1), time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(env.Stdout, "created %s\ncreated %s\n", up, down)
+		return nil
+	}
+
+	cfg, err := loader.Load()
+	if err != nil {
+		return err
+	}
+
+	logger := log.NewLogfmtLogger(log.NewSyncWriter(env.Stderr))
+	db, migrator, err := newMigrator(ctx, cfg.Database, logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch fs.Arg(0) {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if fs.NArg() > 1 {
+			if steps, err = strconv.Atoi(fs.Arg(1)); err != nil || steps < 1 {
+				return errors.Errorf("invalid number of steps %q", fs.Arg(1))
+			}
+		}
+		return migrator.Down(ctx, steps)
+	case "status":
+		s, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printMigrationStatus(env, s)
+		return nil
+	}
+
+	return errors.Errorf("unknown subcommand %q", fs.Arg(0))
+}
+
+// newMigrator connects to the configured database. Migrations only exist
+// for Postgres: SQLite and in-memory storage set up their schema on start.
+func newMigrator(ctx context.Context, cfg config.Database, logger log.Logger) (*gorm.DB, *migrate.Migrator, error) {
+	if cfg.Driver != "" && cfg.Driver != database.DriverPostgres {
+		return nil, nil, errors.Errorf("migrations apply to postgres only, %s sets up its schema on start", cfg.Driver)
+	}
+
+	db, err := database.New(ctx, databaseConfig(cfg), logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrator, err := migrate.New(db.DB(), migrations.FS, logger)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	return db, migrator, nil
+}
+
+func printMigrationStatus(env *cli.Env, s *migrate.Status) {
+	state := "up to date"
+	switch {
+	case s.Dirty:
+		state = "dirty"
+	case s.Version < s.Latest:
+		state = fmt.Sprintf("%d pending", len(s.Pending))
+	case s.Version > s.Latest:
+		state = "newer than this binary"
+	}
+	fmt.Fprintf(env.Stdout, "version %d, latest %d: %s\n\n", s.Version, s.Latest, state)
+
+	tw := tabwriter.NewWriter(env.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATE")
+	for _, m := range s.Applied {
+		fmt.Fprintf(tw, "%d\t%s\tapplied\n", m.Version, m.Name)
+	}
+	for _, m := range s.Pending {
+		fmt.Fprintf(tw, "%d\t%s\tpending\n", m.Version, m.Name)
+	}
+	tw.Flush()
+}
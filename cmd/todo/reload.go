@@ -0,0 +1,123 @@
+package main
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/Neurostep/todo/config"
+	"github.com/Neurostep/todo/internal/server"
+	"github.com/Neurostep/todo/pkg/ratelimit"
+	"github.com/Neurostep/todo/pkg/tools/logging"
+)
+
+// reloadable are the settings applied on SIGHUP, by path or by the prefix
+// of their path. The others take a restart.
+var reloadable = []string{"logging.level", "rateLimit.groups", "server.cors.", "server.jwtKeys"}
+
+type (
+	// policies is the part of the server which can change while serving.
+	policies interface {
+		SetCORS(server.CORSConfig)
+		SetJWTKeys([]server.JWTKey)
+	}
+
+	// reloader applies the reloadable settings of the configuration to the
+	// running components.
+	reloader struct {
+		mu      sync.Mutex
+		loader  *config.Loader
+		current config.Config
+		server  policies
+		// limiter is nil when no route is limited, limits then take a
+		// restart
+		limiter *ratelimit.Limiter
+		logger  log.Logger
+	}
+)
+
+func isReloadable(setting string) bool {
+	for _, r := range reloadable {
+		if setting == r || strings.HasSuffix(r, ".") && strings.HasPrefix(setting, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// config returns the configuration in use.
+func (r *reloader) config() config.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// reload loads the configuration again and applies its reloadable settings.
+// An invalid configuration is rejected as a whole, the settings in use are
+// kept.
+func (r *reloader) reload() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := r.loader.Load()
+	if err != nil {
+		r.logger.Log("event", "config_reload_rejected", "error", err)
+		return
+	}
+
+	var applied, ignored []string
+	for _, setting := range config.Changed(r.current, cfg) {
+		if isReloadable(setting) && (setting != "rateLimit.groups" || r.limiter != nil) {
+			applied = append(applied, setting)
+		} else {
+			ignored = append(ignored, setting)
+		}
+	}
+	if len(applied) == 0 && len(ignored) == 0 {
+		r.logger.Log("event", "config_reloaded", "changed", "none")
+		return
+	}
+
+	// the settings in use keep their values unless applied
+	next := r.current
+	for _, setting := range applied {
+		switch {
+		case setting == "logging.level":
+			logging.SetLevel(cfg.Logging.Level)
+			next.Logging.Level = cfg.Logging.Level
+		case setting == "rateLimit.groups":
+			r.limiter.SetLimits(rateLimits(cfg.RateLimit))
+			next.RateLimit.Groups = cfg.RateLimit.Groups
+		case strings.HasPrefix(setting, "server.cors."):
+			r.server.SetCORS(server.CORSConfig(cfg.Server.CORS))
+			next.Server.CORS = cfg.Server.CORS
+		case setting == "server.jwtKeys":
+			r.server.SetJWTKeys(jwtKeys(cfg.Server.JWTKeys))
+			next.Server.JWTKeys = cfg.Server.JWTKeys
+		}
+	}
+	r.current = next
+
+	kv := []interface{}{"event", "config_reloaded", "changed", strings.Join(applied, ",")}
+	if len(ignored) != 0 {
+		kv = append(kv, "restart_required", strings.Join(ignored, ","))
+	}
+	r.logger.Log(kv...)
+}
+
+func jwtKeys(keys []config.JWTKey) []server.JWTKey {
+	res := make([]server.JWTKey, 0, len(keys))
+	for _, k := range keys {
+		res = append(res, server.JWTKey{ID: k.ID, Secret: []byte(k.Secret)})
+	}
+	return res
+}
+
+func rateLimits(cfg config.RateLimit) map[string]ratelimit.Limit {
+	limits := map[string]ratelimit.Limit{}
+	for group, l := range cfg.Groups {
+		limits[group] = ratelimit.Limit{Requests: l.Requests, Period: l.Period, Burst: l.Burst}
+	}
+	return limits
+}
@@ -1,45 +1,369 @@
 package config
 
 import (
-	"io/ioutil"
-	"os"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/pkg/errors"
 	"gopkg.in/go-playground/validator.v9"
-	"gopkg.in/yaml.v2"
 )
 
 type (
+	// Errors lists the invalid settings of a configuration.
+	Errors []string
+
 	Config struct {
-		Database Database `yaml:"database" validate:"required,dive"`
-		Server   Server   `yaml:"server" validate:"required,dive"`
-		Metrics  Metrics  `yaml:"metrics"`
+		Database    Database    `yaml:"database" validate:"required,dive"`
+		Server      Server      `yaml:"server" validate:"required,dive"`
+		Cache       Cache       `yaml:"cache"`
+		RateLimit   RateLimit   `yaml:"rateLimit"`
+		Idempotency Idempotency `yaml:"idempotency"`
+		Metrics     Metrics     `yaml:"metrics"`
+		Logging     Logging     `yaml:"logging"`
 	}
 
 	Database struct {
-		Address string `yaml:"address" validate:"required"`
+		// Driver is one of postgres (default), sqlite or memory
+		Driver  string `yaml:"driver" validate:"omitempty,oneof=postgres sqlite memory"`
+		Address string `yaml:"address"`
+		// MigrateOnStart applies pending Postgres migrations before serving
+		MigrateOnStart bool `yaml:"migrateOnStart"`
+
+		// Connection pool, zero values use the defaults of the database package
+		MaxOpenConns     int           `yaml:"maxOpenConns" validate:"gte=0"`
+		MaxIdleConns     int           `yaml:"maxIdleConns" validate:"gte=0"`
+		ConnMaxLifetime  time.Duration `yaml:"connMaxLifetime" validate:"gte=0"`
+		StatementTimeout time.Duration `yaml:"statementTimeout" validate:"gte=0"`
+		// ConnectRetries on start, waiting ConnectBackoff doubled after every attempt
+		ConnectRetries int           `yaml:"connectRetries" validate:"gte=0"`
+		ConnectBackoff time.Duration `yaml:"connectBackoff" validate:"gte=0"`
+		// LogQueries logs every SQL statement, SlowQueryThreshold the slow ones only
+		LogQueries         bool          `yaml:"logQueries"`
+		SlowQueryThreshold time.Duration `yaml:"slowQueryThreshold" validate:"gte=0"`
+
+		// Replicas are the DSNs of Postgres read replicas, reads are spread
+		// over them and go to the primary for ReplicaStickyWindow after a
+		// client wrote
+		Replicas            []string      `yaml:"replicas"`
+		ReplicaStickyWindow time.Duration `yaml:"replicaStickyWindow" validate:"gte=0"`
 	}
 
 	Server struct {
 		Debug       bool `yaml:"debug"`
-		Port        int  `yaml:"port"`
+		Port        int  `yaml:"port" validate:"min=1,max=65535"`
 		AuthEnabled bool `yaml:"authEnabled"`
+		// AdminPort serves the probes, metrics and profiles, it must not be
+		// reachable from the public network
+		AdminPort int `yaml:"adminPort" validate:"min=1,max=65535,nefield=Port"`
+		// ShutdownDelay keeps serving while readiness fails on shutdown
+		ShutdownDelay time.Duration `yaml:"shutdownDelay" validate:"gte=0"`
+		CORS          CORS          `yaml:"cors"`
+		// ReadTimeout, WriteTimeout and IdleTimeout configure connections,
+		// RequestTimeout is the deadline of requests, overridden by endpoint
+		// id in RouteTimeouts. See server.Config for the defaults.
+		ReadTimeout    time.Duration            `yaml:"readTimeout" validate:"gte=0"`
+		WriteTimeout   time.Duration            `yaml:"writeTimeout" validate:"gte=0"`
+		IdleTimeout    time.Duration            `yaml:"idleTimeout" validate:"gte=0"`
+		RequestTimeout time.Duration            `yaml:"requestTimeout" validate:"gte=0"`
+		RouteTimeouts  map[string]time.Duration `yaml:"routeTimeouts" validate:"dive,keys,required,endkeys,gt=0"`
+		// JWTKeys sign and verify the tokens, the first one signs, they are
+		// required when authEnabled is set
+		JWTKeys []JWTKey `yaml:"jwtKeys" validate:"dive"`
+	}
+
+	// JWTKey is a key tokens are signed with, its ID tells the key of a
+	// token when rotating keys.
+	JWTKey struct {
+		ID     string `yaml:"id"`
+		Secret string `yaml:"secret" validate:"required"`
+	}
+
+	// CORS is the policy for cross-origin requests, see server.CORSConfig.
+	CORS struct {
+		AllowedOrigins   []string      `yaml:"allowedOrigins" validate:"dive,required"`
+		AllowedMethods   []string      `yaml:"allowedMethods" validate:"dive,required"`
+		AllowedHeaders   []string      `yaml:"allowedHeaders" validate:"dive,required"`
+		ExposedHeaders   []string      `yaml:"exposedHeaders" validate:"dive,required"`
+		AllowCredentials bool          `yaml:"allowCredentials"`
+		MaxAge           time.Duration `yaml:"maxAge" validate:"gte=0"`
+	}
+
+	Cache struct {
+		// Driver is one of none (default), memory or redis
+		Driver string        `yaml:"driver" validate:"omitempty,oneof=none memory redis"`
+		TTL    time.Duration `yaml:"ttl" validate:"gte=0"`
+		// Size is the number of entries of the memory cache
+		Size int `yaml:"size" validate:"gte=0"`
+		// Address, Password and DB of the redis server
+		Address  string `yaml:"address"`
+		Password string `yaml:"password"`
+		DB       int    `yaml:"db" validate:"gte=0"`
+	}
+
+	RateLimit struct {
+		// Store is memory (default), limiting every instance on its own, or
+		// redis, sharing the limits between all of them
+		Store    string `yaml:"store" validate:"omitempty,oneof=memory redis"`
+		Address  string `yaml:"address"`
+		Password string `yaml:"password"`
+		DB       int    `yaml:"db" validate:"gte=0"`
+		// Groups are the limits of the api (/api/v1) and auth (/signin,
+		// /refresh) routes, groups without a limit are not limited
+		Groups map[string]RateLimitGroup `yaml:"groups" validate:"dive,keys,oneof=api auth,endkeys,required"`
+	}
+
+	// RateLimitGroup allows Requests per Period, in bursts of up to Burst
+	// requests which defaults to Requests.
+	RateLimitGroup struct {
+		Requests int           `yaml:"requests" validate:"gt=0"`
+		Period   time.Duration `yaml:"period" validate:"gt=0"`
+		Burst    int           `yaml:"burst" validate:"gte=0"`
+	}
+
+	Idempotency struct {
+		// Store is memory (default) or redis, which recognizes retries
+		// reaching another instance
+		Store    string `yaml:"store" validate:"omitempty,oneof=memory redis"`
+		Address  string `yaml:"address"`
+		Password string `yaml:"password"`
+		DB       int    `yaml:"db" validate:"gte=0"`
+		// TTL is how long responses are kept for retries, a day by default
+		TTL time.Duration `yaml:"ttl" validate:"gte=0"`
+	}
+
+	Logging struct {
+		// Level is the least level of the entries: debug, info (default),
+		// warn or error
+		Level string `yaml:"level" validate:"omitempty,oneof=debug info warn error"`
+		// TraceFormat names the fields correlating entries with traces:
+		// otel (default), ecs or stackdriver
+		TraceFormat string `yaml:"traceFormat" validate:"omitempty,oneof=otel ecs stackdriver"`
+		// Project is the Google Cloud project of the stackdriver traces
+		Project string `yaml:"project"`
 	}
 
 	Metrics struct {
-		TracingEnable bool `yaml:"tracingEnable"`
+		// TracingEnable exports traces to Stackdriver, unless Tracing
+		// names another exporter
+		TracingEnable bool    `yaml:"tracingEnable"`
+		Tracing       Tracing `yaml:"tracing"`
+	}
+
+	// Tracing configures the export and the sampling of traces, see
+	// metrics.Tracing.
+	Tracing struct {
+		Exporter    string            `yaml:"exporter" validate:"omitempty,oneof=stackdriver otlp-grpc otlp-http zipkin stdout"`
+		Endpoint    string            `yaml:"endpoint"`
+		Insecure    bool              `yaml:"insecure"`
+		Headers     map[string]string `yaml:"headers"`
+		Sampler     string            `yaml:"sampler" validate:"omitempty,oneof=always never ratio ratelimited"`
+		Ratio       float64           `yaml:"ratio" validate:"gte=0,lte=1"`
+		PerSecond   float64           `yaml:"perSecond" validate:"gte=0"`
+		ParentBased bool              `yaml:"parentBased"`
+		// TrustTraceParent continues the traces of the W3C traceparent
+		// header of requests, which start traces of their own otherwise
+		TrustTraceParent bool `yaml:"trustTraceParent"`
 	}
 )
 
+// redacted replaces the secrets of the configuration when it is shown.
+const redacted = "xxxxx"
+
+// dsnPassword is the password of key=value DSNs.
+var dsnPassword = regexp.MustCompile(`password=('[^']*'|\S+)`)
+
+// ReadConfigFile loads the configuration of the file at configPath, see
+// Loader. The environment variables override it.
 func ReadConfigFile(configPath string) (Config, error) {
-	var c Config
-	raw, err := ioutil.ReadFile(configPath)
-	if err != nil {
-		return c, errors.Errorf("failed to read config file %q: %s", configPath, err)
+	l := NewLoader(nil)
+	l.Path = configPath
+	return l.Load()
+}
+
+// Error lists every invalid setting of the configuration, by the path of
+// the setting in the file, such as server.port.
+func (e Errors) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e, "\n  ")
+}
+
+func validate(c Config) error {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		return strings.Split(f.Tag.Get("yaml"), ",")[0]
+	})
+	v.RegisterStructValidation(validateDatabase, Database{})
+	v.RegisterStructValidation(validateServer, Server{})
+	v.RegisterStructValidation(validateCache, Cache{})
+	v.RegisterStructValidation(validateRateLimit, RateLimit{})
+	v.RegisterStructValidation(validateIdempotency, Idempotency{})
+	v.RegisterStructValidation(validateCORS, CORS{})
+	v.RegisterStructValidation(validateTracing, Tracing{})
+
+	err := v.Struct(c)
+	fieldErrs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+	errs := make(Errors, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		errs = append(errs, strings.TrimPrefix(fe.Namespace(), "Config.")+": "+message(fe))
+	}
+	return errs
+}
+
+// message describes the failed validation in words.
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "gte", "min":
+		if fe.Param() == "0" {
+			return "must not be negative"
+		}
+		return "must be at least " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "lte", "max":
+		return "must be at most " + fe.Param()
+	case "nefield":
+		return "must differ from " + strings.ToLower(fe.Param()[:1]) + fe.Param()[1:]
+	case "excludes":
+		return "must not contain " + strconv.Quote(fe.Param())
+	case "postgres_only":
+		return "requires the postgres driver"
+	case "key_ids":
+		return "must have distinct ids"
+	default:
+		return "fails the " + fe.Tag() + " validation"
+	}
+}
+
+// validateDatabase requires an address for every driver but memory, and
+// allows replicas for Postgres only.
+func validateDatabase(sl validator.StructLevel) {
+	db := sl.Current().Interface().(Database)
+	if db.Driver != "memory" && db.Address == "" {
+		sl.ReportError(db.Address, "address", "Address", "required", "")
+	}
+	if len(db.Replicas) != 0 && db.Driver != "" && db.Driver != "postgres" {
+		sl.ReportError(db.Replicas, "replicas", "Replicas", "postgres_only", "")
 	}
-	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(raw))), &c); err != nil {
-		return c, errors.Wrap(err, "yaml parse failed")
+}
+
+// validateServer requires keys when authorization is enabled, and
+// identifies keys by distinct ids when there are several.
+func validateServer(sl validator.StructLevel) {
+	s := sl.Current().Interface().(Server)
+	if s.AuthEnabled && len(s.JWTKeys) == 0 {
+		sl.ReportError(s.JWTKeys, "jwtKeys", "JWTKeys", "required", "")
+	}
+	if len(s.JWTKeys) < 2 {
+		return
+	}
+	ids := map[string]bool{}
+	for _, k := range s.JWTKeys {
+		if k.ID == "" || ids[k.ID] {
+			sl.ReportError(s.JWTKeys, "jwtKeys", "JWTKeys", "key_ids", "")
+			return
+		}
+		ids[k.ID] = true
+	}
+}
+
+// validateCache requires an address for redis.
+func validateCache(sl validator.StructLevel) {
+	c := sl.Current().Interface().(Cache)
+	if c.Driver == "redis" && c.Address == "" {
+		sl.ReportError(c.Address, "address", "Address", "required", "")
+	}
+}
+
+// validateRateLimit requires an address for the redis store.
+func validateRateLimit(sl validator.StructLevel) {
+	rl := sl.Current().Interface().(RateLimit)
+	if rl.Store == "redis" && rl.Address == "" {
+		sl.ReportError(rl.Address, "address", "Address", "required", "")
+	}
+}
+
+// validateIdempotency requires an address for the redis store.
+func validateIdempotency(sl validator.StructLevel) {
+	i := sl.Current().Interface().(Idempotency)
+	if i.Store == "redis" && i.Address == "" {
+		sl.ReportError(i.Address, "address", "Address", "required", "")
+	}
+}
+
+// validateCORS rejects credentials for any origin, browsers refuse them.
+func validateCORS(sl validator.StructLevel) {
+	c := sl.Current().Interface().(CORS)
+	if !c.AllowCredentials {
+		return
+	}
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			sl.ReportError(c.AllowedOrigins, "allowedOrigins", "AllowedOrigins", "excludes", "*")
+		}
 	}
+}
+
+// validateTracing requires the settings the exporter and the sampler need.
+func validateTracing(sl validator.StructLevel) {
+	t := sl.Current().Interface().(Tracing)
+	if t.Exporter == "zipkin" && t.Endpoint == "" {
+		sl.ReportError(t.Endpoint, "endpoint", "Endpoint", "required", "")
+	}
+	if t.Sampler == "ratelimited" && t.PerSecond == 0 {
+		sl.ReportError(t.PerSecond, "perSecond", "PerSecond", "required", "")
+	}
+}
+
+// Redacted returns the configuration without its secrets, such as passwords
+// and the credentials of DSNs, so that it can be shown.
+func (c Config) Redacted() Config {
+	c.Database.Address = redactDSN(c.Database.Address)
+	replicas := make([]string, 0, len(c.Database.Replicas))
+	for _, r := range c.Database.Replicas {
+		replicas = append(replicas, redactDSN(r))
+	}
+	c.Database.Replicas = replicas
+
+	keys := make([]JWTKey, 0, len(c.Server.JWTKeys))
+	for _, k := range c.Server.JWTKeys {
+		keys = append(keys, JWTKey{ID: k.ID, Secret: redact(k.Secret)})
+	}
+	c.Server.JWTKeys = keys
 
-	return c, validator.New().Struct(c)
+	c.Cache.Password = redact(c.Cache.Password)
+	c.RateLimit.Password = redact(c.RateLimit.Password)
+	c.Idempotency.Password = redact(c.Idempotency.Password)
+
+	headers := make(map[string]string, len(c.Metrics.Tracing.Headers))
+	for k, v := range c.Metrics.Tracing.Headers {
+		headers[k] = redact(v)
+	}
+	c.Metrics.Tracing.Headers = headers
+	return c
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+		return u.String()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "password="+redacted)
 }
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// envPrefix starts the names of the environment variables of the settings,
+// such as TODO_SERVER_PORT for server.port.
+const envPrefix = "TODO_"
+
+type (
+	// Loader builds the configuration out of layers, each overriding the
+	// previous one: the defaults, the file, the TODO_* environment variables
+	// and the command line flags.
+	Loader struct {
+		// Path is the configuration file, YAML unless its extension is
+		// .toml or .json. No file is read when empty.
+		Path string
+
+		settings  []setting
+		flags     map[string]string
+		lookupEnv func(string) (string, bool)
+	}
+
+	// setting is a single value of the configuration.
+	setting struct {
+		// name is the path of the value in the file, such as server.port,
+		// and the name of its flag
+		name  string
+		env   string
+		index []int
+		typ   reflect.Type
+	}
+
+	// flagValue records the value of a setting given on the command line.
+	flagValue struct {
+		l *Loader
+		s setting
+	}
+)
+
+// Defaults returns the configuration the layers apply to.
+func Defaults() Config {
+	return Config{
+		Server: Server{Port: 9000, AdminPort: 9001},
+	}
+}
+
+// NewLoader creates a loader reading the flags of fs, it registers -cfg
+// for the file and a flag per setting, such as -server.port. fs may be nil.
+func NewLoader(fs *flag.FlagSet) *Loader {
+	l := &Loader{settings: collect(reflect.TypeOf(Config{}), nil, nil), flags: map[string]string{}, lookupEnv: os.LookupEnv}
+	if fs == nil {
+		return l
+	}
+	fs.StringVar(&l.Path, "cfg", "", "config file, YAML, TOML or JSON")
+	for _, s := range l.settings {
+		fs.Var(flagValue{l: l, s: s}, s.name, "overrides "+s.name+", env "+s.env)
+	}
+	return l
+}
+
+// Load builds and validates the configuration. The errors of the values of
+// the variables and the flags, and the invalid settings, are listed by
+// Errors.
+func (l *Loader) Load() (Config, error) {
+	c := Defaults()
+	if l.Path != "" {
+		if err := readFile(l.Path, &c); err != nil {
+			return c, err
+		}
+	}
+
+	var errs Errors
+	for _, s := range l.settings {
+		raw, ok, err := l.env(s.env)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := s.apply(&c, raw); err != nil {
+			errs = append(errs, s.env+": "+err.Error())
+		}
+	}
+	for _, s := range l.settings {
+		raw, ok := l.flags[s.name]
+		if !ok {
+			continue
+		}
+		if err := s.apply(&c, raw); err != nil {
+			errs = append(errs, "-"+s.name+": "+err.Error())
+		}
+	}
+	if len(errs) != 0 {
+		return c, errs
+	}
+
+	return c, validate(c)
+}
+
+// env returns the value of the variable name, or the content of the file
+// named by name_FILE, suited to secrets mounted as files.
+func (l *Loader) env(name string) (string, bool, error) {
+	value, ok := l.lookupEnv(name)
+	path, fromFile := l.lookupEnv(name + "_FILE")
+	switch {
+	case ok && fromFile:
+		return "", false, errors.Errorf("%s: both %s and %s_FILE are set", name, name, name)
+	case fromFile:
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", false, errors.Errorf("%s_FILE: %s", name, err)
+		}
+		return strings.TrimRight(string(raw), "\r\n"), true, nil
+	}
+	return value, ok, nil
+}
+
+// Changed lists the settings which differ between the configurations, by
+// their path in the file.
+func Changed(before, after Config) []string {
+	var changed []string
+	b, a := reflect.ValueOf(before), reflect.ValueOf(after)
+	for _, s := range collect(reflect.TypeOf(Config{}), nil, nil) {
+		if !reflect.DeepEqual(b.FieldByIndex(s.index).Interface(), a.FieldByIndex(s.index).Interface()) {
+			changed = append(changed, s.name)
+		}
+	}
+	return changed
+}
+
+func readFile(path string, c *Config) error {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Errorf("failed to read config file %q: %s", path, err)
+	}
+	raw = []byte(os.ExpandEnv(string(raw)))
+
+	// TOML and JSON are converted to YAML, so durations such as "5s" and
+	// the keys are decoded the same way in every format
+	var values map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		if err := toml.Unmarshal(raw, &values); err != nil {
+			return errors.Wrap(err, "toml parse failed")
+		}
+		if raw, err = yaml.Marshal(values); err != nil {
+			return errors.Wrap(err, "toml parse failed")
+		}
+	case ".json":
+		if err := json.Unmarshal(raw, &values); err != nil {
+			return errors.Wrap(err, "json parse failed")
+		}
+		if raw, err = yaml.Marshal(values); err != nil {
+			return errors.Wrap(err, "json parse failed")
+		}
+	}
+
+	if err := yaml.Unmarshal(raw, c); err != nil {
+		return errors.Wrap(err, "yaml parse failed")
+	}
+	return nil
+}
+
+// collect lists the settings of the fields of t, the fields of nested
+// structs are settings of their own.
+func collect(t reflect.Type, path []string, index []int) []setting {
+	var settings []setting
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		p := append(append([]string{}, path...), key)
+		idx := append(append([]int{}, index...), i)
+		if f.Type.Kind() == reflect.Struct {
+			settings = append(settings, collect(f.Type, p, idx)...)
+			continue
+		}
+		settings = append(settings, setting{name: strings.Join(p, "."), env: envName(p), index: idx, typ: f.Type})
+	}
+	return settings
+}
+
+// envName turns the keys of a setting into the name of its variable, such
+// as TODO_DATABASE_MAX_OPEN_CONNS for database.maxOpenConns.
+func envName(path []string) string {
+	var b strings.Builder
+	b.WriteString(envPrefix)
+	for i, key := range path {
+		if i > 0 {
+			b.WriteByte('_')
+		}
+		for j, r := range key {
+			if j > 0 && unicode.IsUpper(r) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return b.String()
+}
+
+// apply sets the setting of c to raw. Strings are taken as is, lists of
+// strings may be separated by commas, any other value is parsed as YAML,
+// such as 5s, [a, b] or {api: {requests: 100, period: 1m}}.
+func (s setting) apply(c *Config, raw string) error {
+	v := reflect.ValueOf(c).Elem().FieldByIndex(s.index)
+	switch {
+	case s.typ.Kind() == reflect.String:
+		v.SetString(raw)
+		return nil
+	case s.typ.Kind() == reflect.Slice && s.typ.Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(raw), "["):
+		items := reflect.MakeSlice(s.typ, 0, 0)
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = reflect.Append(items, reflect.ValueOf(item).Convert(s.typ.Elem()))
+			}
+		}
+		v.Set(items)
+		return nil
+	}
+
+	ptr := reflect.New(s.typ)
+	if err := yaml.UnmarshalStrict([]byte(raw), ptr.Interface()); err != nil {
+		return errors.Errorf("invalid value %q", raw)
+	}
+	v.Set(ptr.Elem())
+	return nil
+}
+
+func (f flagValue) String() string {
+	if f.l == nil {
+		return ""
+	}
+	return f.l.flags[f.s.name]
+}
+
+func (f flagValue) Set(raw string) error {
+	f.l.flags[f.s.name] = raw
+	return nil
+}
+
+func (f flagValue) IsBoolFlag() bool {
+	return f.s.typ.Kind() == reflect.Bool
+}
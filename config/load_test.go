@@ -0,0 +1,126 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func envOf(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func TestLoaderLayers(t *testing.T) {
+	secret := writeFile(t, "dsn", "postgres://postgres:secret@db:5432/todo\n")
+	path := writeFile(t, "config.toml", `
+[database]
+address = "postgres://localhost/todo"
+maxOpenConns = 10
+
+[server]
+port = 8000
+requestTimeout = "3s"
+`)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	l := NewLoader(fs)
+	l.lookupEnv = envOf(map[string]string{
+		"TODO_SERVER_PORT":                 "8080",
+		"TODO_SERVER_DEBUG":                "true",
+		"TODO_DATABASE_ADDRESS_FILE":       secret,
+		"TODO_SERVER_CORS_ALLOWED_ORIGINS": "https://a.example, https://b.example",
+		"TODO_RATE_LIMIT_GROUPS":           "{api: {requests: 100, period: 1m}}",
+		"TODO_SERVER_JWT_KEYS":             "[{id: 2022-08, secret: s3cr3t}]",
+	})
+	require.NoError(t, fs.Parse([]string{"-cfg", path, "-server.port=8081", "-server.authEnabled"}))
+
+	c, err := l.Load()
+	require.NoError(t, err)
+	require.Equal(t, 8081, c.Server.Port, "flags override the variables")
+	require.Equal(t, 9001, c.Server.AdminPort, "defaults apply to unset settings")
+	require.True(t, c.Server.Debug)
+	require.True(t, c.Server.AuthEnabled)
+	require.Equal(t, 3*time.Second, c.Server.RequestTimeout)
+	require.Equal(t, 10, c.Database.MaxOpenConns)
+	require.Equal(t, "postgres://postgres:secret@db:5432/todo", c.Database.Address, "secrets are read from files")
+	require.Equal(t, []string{"https://a.example", "https://b.example"}, c.Server.CORS.AllowedOrigins)
+	require.Equal(t, RateLimitGroup{Requests: 100, Period: time.Minute}, c.RateLimit.Groups["api"])
+	require.Equal(t, []JWTKey{{ID: "2022-08", Secret: "s3cr3t"}}, c.Server.JWTKeys)
+}
+
+func TestLoaderFormats(t *testing.T) {
+	for name, content := range map[string]string{
+		"config.yaml": "database:\n  driver: memory\nserver:\n  port: 8000\n  idleTimeout: 1m",
+		"config.json": `{"database": {"driver": "memory"}, "server": {"port": 8000, "idleTimeout": "1m"}}`,
+		"config.toml": "[database]\ndriver = \"memory\"\n[server]\nport = 8000\nidleTimeout = \"1m\"",
+	} {
+		t.Run(name, func(t *testing.T) {
+			l := NewLoader(nil)
+			l.Path = writeFile(t, name, content)
+			l.lookupEnv = envOf(nil)
+
+			c, err := l.Load()
+			require.NoError(t, err)
+			require.Equal(t, "memory", c.Database.Driver)
+			require.Equal(t, 8000, c.Server.Port)
+			require.Equal(t, time.Minute, c.Server.IdleTimeout)
+		})
+	}
+}
+
+func TestLoaderErrors(t *testing.T) {
+	l := NewLoader(nil)
+	l.lookupEnv = envOf(map[string]string{
+		"TODO_DATABASE_DRIVER": "mysql",
+		"TODO_SERVER_PORT":     "70000",
+		"TODO_CACHE_DRIVER":    "redis",
+	})
+	_, err := l.Load()
+	require.EqualError(t, err, `invalid configuration:
+  database.driver: must be one of postgres, sqlite, memory
+  database.address: is required
+  server.port: must be at most 65535
+  cache.address: is required`)
+
+	l.lookupEnv = envOf(map[string]string{
+		"TODO_SERVER_PORT":           "http",
+		"TODO_SERVER_READ_TIMEOUT":   "soon",
+		"TODO_DATABASE_ADDRESS":      "postgres://localhost/todo",
+		"TODO_DATABASE_ADDRESS_FILE": "/run/secrets/dsn",
+	})
+	_, err = l.Load()
+	require.EqualError(t, err, `invalid configuration:
+  TODO_DATABASE_ADDRESS: both TODO_DATABASE_ADDRESS and TODO_DATABASE_ADDRESS_FILE are set
+  TODO_SERVER_PORT: invalid value "http"
+  TODO_SERVER_READ_TIMEOUT: invalid value "soon"`)
+}
+
+func TestEnvName(t *testing.T) {
+	require.Equal(t, "TODO_DATABASE_MAX_OPEN_CONNS", envName([]string{"database", "maxOpenConns"}))
+	require.Equal(t, "TODO_METRICS_TRACING_TRUST_TRACE_PARENT", envName([]string{"metrics", "tracing", "trustTraceParent"}))
+	require.Equal(t, "TODO_IDEMPOTENCY_TTL", envName([]string{"idempotency", "ttl"}))
+}
+
+func TestChanged(t *testing.T) {
+	before := Defaults()
+	after := Defaults()
+	after.Logging.Level = "debug"
+	after.Server.CORS.AllowedOrigins = []string{"https://todo.example.com"}
+	after.RateLimit.Groups = map[string]RateLimitGroup{"api": {Requests: 100, Period: time.Minute}}
+
+	require.Empty(t, Changed(before, Defaults()))
+	require.Equal(t, []string{"server.cors.allowedOrigins", "rateLimit.groups", "logging.level"}, Changed(before, after))
+}
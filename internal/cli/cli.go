@@ -0,0 +1,161 @@
+// Package cli implements the subcommands of the todo binary. Most of them
+// are thin wrappers around pkg/client talking to a running server.
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+)
+
+type (
+	// Command is a subcommand of the todo binary.
+	Command struct {
+		Name    string
+		Summary string
+		// Subcommands are offered by shell completion after Name.
+		Subcommands []string
+		Run         func(ctx context.Context, env *Env, args []string) error
+	}
+
+	// Env is what commands use to interact with the outside world.
+	Env struct {
+		Stdin  io.Reader
+		Stdout io.Writer
+		Stderr io.Writer
+
+		commands map[string]*Command
+	}
+
+	// usageError makes Run print the usage of the command along with the error.
+	usageError struct {
+		fs  *flag.FlagSet
+		msg string
+	}
+)
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// New creates the command line interface with the client commands registered.
+func New(stdin io.Reader, stdout, stderr io.Writer) *Env {
+	env := &Env{
+		Stdin:    stdin,
+		Stdout:   stdout,
+		Stderr:   stderr,
+		commands: map[string]*Command{},
+	}
+
+	for _, cmd := range []*Command{
+		loginCommand,
+		addCommand,
+		lsCommand,
+		doneCommand,
+		commentCommand,
+		labelCommand,
+		tuiCommand,
+		completionCommand,
+	} {
+		env.Register(cmd)
+	}
+
+	return env
+}
+
+// Register adds a command, e.g. the ones living in the main package.
+func (env *Env) Register(cmd *Command) {
+	env.commands[cmd.Name] = cmd
+}
+
+// IsCommand reports whether name is a registered command.
+func (env *Env) IsCommand(name string) bool {
+	_, ok := env.commands[name]
+	return ok || name == "help"
+}
+
+// Run executes the command named by args[0] and returns the exit code.
+func (env *Env) Run(ctx context.Context, args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		env.usage(env.Stdout)
+		return 0
+	}
+
+	cmd, ok := env.commands[args[0]]
+	if !ok {
+		fmt.Fprintf(env.Stderr, "todo: unknown command %q\n\n", args[0])
+		env.usage(env.Stderr)
+		return 2
+	}
+
+	if err := cmd.Run(ctx, env, args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		fmt.Fprintf(env.Stderr, "todo %s: %s\n", cmd.Name, err)
+		if uerr, ok := err.(*usageError); ok {
+			uerr.fs.SetOutput(env.Stderr)
+			uerr.fs.Usage()
+			return 2
+		}
+		return 1
+	}
+
+	return 0
+}
+
+func (env *Env) names() []string {
+	names := make([]string, 0, len(env.commands))
+	for name := range env.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (env *Env) usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: todo <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, name := range env.names() {
+		fmt.Fprintf(tw, "  %s\t%s\n", name, env.commands[name].Summary)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "todo <command> -h" for the flags of a command.`)
+}
+
+// flagSet creates the flag set of a command with a usage line listing its
+// positional arguments.
+func (env *Env) flagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet("todo "+name, flag.ContinueOnError)
+	fs.SetOutput(env.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: todo %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func usageErrorf(fs *flag.FlagSet, format string, args ...interface{}) error {
+	return &usageError{fs: fs, msg: fmt.Sprintf(format, args...)}
+}
+
+// configPath returns the location of the CLI config file, which can be
+// overridden with the TODO_CLI_CONFIG environment variable.
+func configPath() (string, error) {
+	if p := os.Getenv("TODO_CLI_CONFIG"); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "todo", "cli.json"), nil
+}
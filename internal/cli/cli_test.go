@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Neurostep/todo/pkg/client"
+)
+
+// stubServer answers the subset of the API used by the tests.
+func stubServer(t *testing.T) *httptest.Server {
+	token := client.Token{Token: "token", Expires: time.Now().Add(time.Hour).Unix()}
+	todos := []map[string]interface{}{
+		{
+			"id": 1, "title": "buy milk", "due_date": "2022-08-01", "done": false, "assignee": "alice",
+			"priority": "P0", "estimate_minutes": 15, "fields": map[string]interface{}{"store": "corner"},
+			"description": "skimmed, ask @bob",
+		},
+		{"id": 2, "title": "write cli", "due_date": "2022-08-02", "done": true},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/signin", func(w http.ResponseWriter, r *http.Request) {
+		var creds map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&creds))
+		if creds["password"] != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(token)
+	})
+	mux.HandleFunc("/api/v1/todos", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != token.Token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodPost {
+			var req map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			req["id"], req["done"] = 3, false
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(req)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"has_more": false, "total_count": len(todos), "data": todos,
+		})
+	})
+
+	mux.HandleFunc("/api/v1/todos/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != token.Token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/v1/todos/"))
+		if err != nil || id < 1 || id > len(todos) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodPut {
+			var req map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			req["id"] = id
+			todos[id-1] = req
+		}
+		json.NewEncoder(w).Encode(todos[id-1])
+	})
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func run(t *testing.T, stdin string, args ...string) (string, string, int) {
+	var stdout, stderr bytes.Buffer
+	env := New(strings.NewReader(stdin), &stdout, &stderr)
+	code := env.Run(context.Background(), args)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestCommands(t *testing.T) {
+	ts := stubServer(t)
+	cfgPath := filepath.Join(t.TempDir(), "cli.json")
+	os.Setenv("TODO_CLI_CONFIG", cfgPath)
+	defer os.Unsetenv("TODO_CLI_CONFIG")
+
+	_, stderr, code := run(t, "", "ls", "-server", ts.URL)
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "todo login")
+
+	_, _, code = run(t, "password\n", "login", "-server", ts.URL, "-u", "user")
+	require.Equal(t, 0, code)
+
+	info, err := os.Stat(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	cfg, err := LoadConfig(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, ts.URL, cfg.Server)
+	require.True(t, cfg.Token.Valid())
+	raw, err := ioutil.ReadFile(cfgPath)
+	require.NoError(t, err)
+	require.NotContains(t, string(raw), "password", "only the token is stored")
+
+	stdout, _, code := run(t, "", "ls", "-pending")
+	require.Equal(t, 0, code)
+	require.Contains(t, stdout, "buy milk")
+	require.NotContains(t, stdout, "write cli")
+
+	stdout, _, code = run(t, "", "ls", "-o", "json")
+	require.Equal(t, 0, code)
+	var todos []client.Todo
+	require.NoError(t, json.Unmarshal([]byte(stdout), &todos))
+	require.Len(t, todos, 2)
+
+	stdout, _, code = run(t, "", "add", "-due", "2022-09-01", "walk", "the", "dog")
+	require.Equal(t, 0, code)
+	require.Contains(t, stdout, "2022-09-01")
+	require.Contains(t, stdout, "walk the dog")
+
+	_, stderr, code = run(t, "", "add", "-due", "tomorrow", "oops")
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "invalid date")
+
+	_, _, code = run(t, "", "done")
+	require.Equal(t, 2, code)
+
+	expire := func() {
+		cfg.Token.Expires = time.Now().Add(-time.Minute).Unix()
+		require.NoError(t, cfg.Save(cfgPath))
+	}
+	expire()
+	_, stderr, code = run(t, "", "ls")
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "TODO_PASSWORD")
+	_, stderr, code = run(t, "password\n", "ls")
+	require.Equal(t, 0, code, stderr)
+	require.Contains(t, stderr, "Password for user", "the password is asked for once the token expired")
+	cfg, err = LoadConfig(cfgPath)
+	require.NoError(t, err)
+	require.True(t, cfg.Token.Valid(), "the new token is stored")
+
+	expire()
+	os.Setenv("TODO_PASSWORD", "password")
+	defer os.Unsetenv("TODO_PASSWORD")
+	_, stderr, code = run(t, "", "ls")
+	require.Equal(t, 0, code, stderr)
+	require.NotContains(t, stderr, "Password")
+
+	stdout, _, code = run(t, "", "done", "-o", "json", "1")
+	require.Equal(t, 0, code)
+	todos = nil
+	require.NoError(t, json.Unmarshal([]byte(stdout), &todos))
+	require.Len(t, todos, 1)
+	require.True(t, todos[0].Done)
+	require.Equal(t, "alice", todos[0].Assignee, "done keeps the other fields")
+	require.Equal(t, "skimmed, ask @bob", todos[0].Description)
+	require.Equal(t, "P0", todos[0].Priority)
+	require.Equal(t, uint(15), todos[0].EstimateMinutes)
+	require.Equal(t, map[string]interface{}{"store": "corner"}, todos[0].Fields)
+}
+
+func TestCompletion(t *testing.T) {
+	stdout, _, code := run(t, "", "completion", "bash")
+	require.Equal(t, 0, code)
+	require.Contains(t, stdout, "complete -F _todo todo")
+	require.Contains(t, stdout, "add comment completion done label login ls")
+
+	_, _, code = run(t, "", "completion", "tcsh")
+	require.Equal(t, 2, code)
+
+	_, _, code = run(t, "", "frobnicate")
+	require.Equal(t, 2, code)
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"strings"
+
+	"github.com/Neurostep/todo/pkg/client"
+)
+
+var commentCommand = &Command{
+	Name:        "comment",
+	Summary:     "Add, list or remove comments of a todo",
+	Subcommands: []string{"add", "ls", "rm"},
+	Run: func(ctx context.Context, env *Env, args []string) error {
+		var s session
+		fs := env.flagSet("comment", "add <id> <text> | ls <id> | rm <id> <comment id>")
+		s.register(fs)
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() < 2 {
+			return usageErrorf(fs, "subcommand and todo id are required")
+		}
+
+		todoID, err := parseID(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+
+		switch fs.Arg(0) {
+		case "add":
+			text := strings.Join(fs.Args()[2:], " ")
+			if text == "" {
+				return usageErrorf(fs, "text is required")
+			}
+			return s.with(ctx, env, func(c *client.Client) error {
+				cmnt, err := c.AddComment(ctx, todoID, text)
+				if err != nil {
+					return err
+				}
+				return render(env.Stdout, s.output, cmnt, commentsTable([]client.Comment{*cmnt}))
+			})
+		case "ls":
+			return s.with(ctx, env, func(c *client.Client) error {
+				comments, err := c.ListComments(ctx, todoID)
+				if err != nil {
+					return err
+				}
+				return render(env.Stdout, s.output, comments, commentsTable(comments))
+			})
+		case "rm":
+			id, err := childID(fs)
+			if err != nil {
+				return err
+			}
+			return s.with(ctx, env, func(c *client.Client) error {
+				return c.RemoveComment(ctx, todoID, id)
+			})
+		}
+
+		return usageErrorf(fs, "unknown subcommand %q", fs.Arg(0))
+	},
+}
+
+// childID parses the id of a comment or label following the todo id.
+func childID(fs *flag.FlagSet) (uint, error) {
+	if fs.NArg() != 3 {
+		return 0, usageErrorf(fs, "todo id and %s id are required", fs.Name()[len("todo "):])
+	}
+	return parseID(fs.Arg(2))
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var completionCommand = &Command{
+	Name:        "completion",
+	Summary:     "Print the shell completion script (bash, zsh or fish)",
+	Subcommands: []string{"bash", "zsh", "fish"},
+	Run: func(ctx context.Context, env *Env, args []string) error {
+		fs := env.flagSet("completion", "bash|zsh|fish")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return usageErrorf(fs, "shell is required")
+		}
+
+		switch fs.Arg(0) {
+		case "bash":
+			env.bashCompletion(env.Stdout)
+		case "zsh":
+			// zsh understands bash completion scripts through bashcompinit
+			fmt.Fprintln(env.Stdout, "autoload -U +X bashcompinit && bashcompinit")
+			env.bashCompletion(env.Stdout)
+		case "fish":
+			env.fishCompletion(env.Stdout)
+		default:
+			return usageErrorf(fs, "unsupported shell %q", fs.Arg(0))
+		}
+		return nil
+	},
+}
+
+func (env *Env) bashCompletion(w io.Writer) {
+	fmt.Fprintln(w, "_todo() {")
+	fmt.Fprintln(w, `  local cur="${COMP_WORDS[COMP_CWORD]}"`)
+	fmt.Fprintln(w, `  if [ "$COMP_CWORD" -eq 1 ]; then`)
+	fmt.Fprintf(w, "    COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(env.names(), " "))
+	fmt.Fprintln(w, "    return")
+	fmt.Fprintln(w, "  fi")
+	fmt.Fprintln(w, `  if [ "$COMP_CWORD" -eq 2 ]; then`)
+	fmt.Fprintln(w, `    case "${COMP_WORDS[1]}" in`)
+	for _, name := range env.names() {
+		if subs := env.commands[name].Subcommands; len(subs) != 0 {
+			fmt.Fprintf(w, "      %s) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", name, strings.Join(subs, " "))
+		}
+	}
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "  fi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -F _todo todo")
+}
+
+func (env *Env) fishCompletion(w io.Writer) {
+	fmt.Fprintln(w, "complete -c todo -f")
+	for _, name := range env.names() {
+		cmd := env.commands[name]
+		fmt.Fprintf(w, "complete -c todo -n __fish_use_subcommand -a %s -d %q\n", name, cmd.Summary)
+		if len(cmd.Subcommands) != 0 {
+			fmt.Fprintf(w, "complete -c todo -n '__fish_seen_subcommand_from %s' -a %q\n", name, strings.Join(cmd.Subcommands, " "))
+		}
+	}
+}
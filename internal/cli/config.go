@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/Neurostep/todo/pkg/client"
+)
+
+const defaultServer = "http://localhost:19000"
+
+type (
+	// Config is persisted by "todo login" and used by the other commands.
+	// The password is not stored, it is asked for again once the token has
+	// expired.
+	Config struct {
+		Server   string       `json:"server"`
+		Username string       `json:"username,omitempty"`
+		Token    client.Token `json:"token"`
+	}
+
+	// session holds the flags shared by the commands talking to the server.
+	session struct {
+		server string
+		output string
+	}
+)
+
+// LoadConfig reads the CLI config file. A missing file yields an empty config.
+func LoadConfig(path string) (*Config, error) {
+	raw, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", path)
+	}
+	return &cfg, nil
+}
+
+// Save writes the config readable by the current user only, as it holds
+// the token.
+func (cfg *Config) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrap(err, "failed to create config directory")
+	}
+	raw, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, raw, 0600); err != nil {
+		return errors.Wrapf(err, "failed to write %s", path)
+	}
+	return os.Chmod(path, 0600)
+}
+
+func (s *session) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.server, "server", "", "server address (default from todo login, then "+defaultServer+")")
+	fs.StringVar(&s.output, "o", formatTable, "output format: table or json")
+}
+
+// with runs fn with a client configured from the config file. A token
+// renewed by the client on the way is written back.
+func (s *session) with(ctx context.Context, env *Env, fn func(c *client.Client) error) error {
+	if s.output != formatTable && s.output != formatJSON {
+		return errors.Errorf("unknown output format %q", s.output)
+	}
+
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	server := s.server
+	if server == "" {
+		server = cfg.Server
+	}
+	if server == "" {
+		server = defaultServer
+	}
+
+	opts := []client.Option{client.WithToken(cfg.Token)}
+	if !cfg.Token.Valid() && cfg.Username != "" {
+		password, err := signinPassword(env, cfg.Username)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, client.WithCredentials(cfg.Username, password))
+	}
+	c, err := client.New(server, opts...)
+	if err != nil {
+		return err
+	}
+
+	err = fn(c)
+	if err != nil && client.IsUnauthorized(err) {
+		err = errors.Wrap(err, `run "todo login" first`)
+	}
+
+	if token := c.Token(); token != cfg.Token && server == cfg.Server {
+		cfg.Token = token
+		if serr := cfg.Save(path); serr != nil && err == nil {
+			err = serr
+		}
+	}
+
+	return err
+}
+
+// signinPassword is the password to sign in again with once the stored token
+// has expired, TODO_PASSWORD or else prompted for.
+func signinPassword(env *Env, username string) (string, error) {
+	if password := os.Getenv("TODO_PASSWORD"); password != "" {
+		return password, nil
+	}
+	password, err := prompt(env, bufio.NewReader(env.Stdin), "Password for "+username)
+	if err != nil {
+		return "", errors.Wrap(err, `the token has expired, run "todo login" or set TODO_PASSWORD`)
+	}
+	return password, nil
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Neurostep/todo/pkg/client"
+)
+
+var labelCommand = &Command{
+	Name:        "label",
+	Summary:     "Add, list or remove labels of a todo",
+	Subcommands: []string{"add", "ls", "rm"},
+	Run: func(ctx context.Context, env *Env, args []string) error {
+		var s session
+		fs := env.flagSet("label", "add <id> <text> | ls <id> | rm <id> <label id>")
+		s.register(fs)
+		color := fs.String("color", "", "color of the added label")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() < 2 {
+			return usageErrorf(fs, "subcommand and todo id are required")
+		}
+
+		todoID, err := parseID(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+
+		switch fs.Arg(0) {
+		case "add":
+			text := strings.Join(fs.Args()[2:], " ")
+			if text == "" {
+				return usageErrorf(fs, "text is required")
+			}
+			return s.with(ctx, env, func(c *client.Client) error {
+				lbl, err := c.AddLabel(ctx, todoID, client.NewLabel{Text: text, Color: *color})
+				if err != nil {
+					return err
+				}
+				return render(env.Stdout, s.output, lbl, labelsTable([]client.Label{*lbl}))
+			})
+		case "ls":
+			return s.with(ctx, env, func(c *client.Client) error {
+				labels, err := c.ListLabels(ctx, todoID)
+				if err != nil {
+					return err
+				}
+				return render(env.Stdout, s.output, labels, labelsTable(labels))
+			})
+		case "rm":
+			id, err := childID(fs)
+			if err != nil {
+				return err
+			}
+			return s.with(ctx, env, func(c *client.Client) error {
+				return c.RemoveLabel(ctx, todoID, id)
+			})
+		}
+
+		return usageErrorf(fs, "unknown subcommand %q", fs.Arg(0))
+	},
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/Neurostep/todo/pkg/client"
+)
+
+var loginCommand = &Command{
+	Name:    "login",
+	Summary: "Sign in and store the token for the other commands",
+	Run: func(ctx context.Context, env *Env, args []string) error {
+		fs := env.flagSet("login", "")
+		server := fs.String("server", defaultServer, "server address")
+		username := fs.String("u", "", "username (prompted when empty)")
+		password := fs.String("p", "", "password (read from stdin when empty)")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		in := bufio.NewReader(env.Stdin)
+		for _, f := range []struct {
+			label string
+			value *string
+		}{{"Username", username}, {"Password", password}} {
+			if *f.value != "" {
+				continue
+			}
+			value, err := prompt(env, in, f.label)
+			if err != nil {
+				return err
+			}
+			*f.value = value
+		}
+
+		c, err := client.New(*server)
+		if err != nil {
+			return err
+		}
+		token, err := c.Signin(ctx, *username, *password)
+		if err != nil {
+			return err
+		}
+
+		path, err := configPath()
+		if err != nil {
+			return err
+		}
+		cfg := &Config{
+			Server:   *server,
+			Username: *username,
+			Token:    token,
+		}
+		if err := cfg.Save(path); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(env.Stdout, "Logged in to %s as %s, token stored in %s\n", *server, *username, path)
+		return nil
+	},
+}
+
+// prompt asks for a line on stdin, the label written to stderr.
+func prompt(env *Env, in *bufio.Reader, label string) (string, error) {
+	fmt.Fprintf(env.Stderr, "%s: ", label)
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.Wrapf(err, "failed to read %s", strings.ToLower(label))
+	}
+	return strings.TrimSpace(line), nil
+}
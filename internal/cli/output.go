@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Neurostep/todo/pkg/client"
+	"github.com/Neurostep/todo/pkg/types"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// render writes v as indented JSON or, for the table format, lets table
+// render the rows.
+func render(w io.Writer, format string, v interface{}, table func(tw *tabwriter.Writer)) error {
+	if format == formatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	table(tw)
+	return tw.Flush()
+}
+
+func todosTable(todos []client.Todo) func(tw *tabwriter.Writer) {
+	return func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "ID\tDUE\tDONE\tTITLE")
+		for _, td := range todos {
+			done := ""
+			if td.Done {
+				done = "x"
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", td.ID, formatDate(td.DueDate), done, td.Title)
+		}
+	}
+}
+
+func commentsTable(comments []client.Comment) func(tw *tabwriter.Writer) {
+	return func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "ID\tTEXT")
+		for _, c := range comments {
+			fmt.Fprintf(tw, "%d\t%s\n", c.ID, c.Text)
+		}
+	}
+}
+
+func labelsTable(labels []client.Label) func(tw *tabwriter.Writer) {
+	return func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "ID\tCOLOR\tTEXT")
+		for _, l := range labels {
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", l.ID, l.Color, l.Text)
+		}
+	}
+}
+
+func formatDate(d types.DueDate) string {
+	return time.Time(d).Format(types.DueDateFormat)
+}
+
+func parseDate(s string) (types.DueDate, error) {
+	t, err := time.Parse(types.DueDateFormat, s)
+	if err != nil {
+		return types.DueDate{}, fmt.Errorf("invalid date %q, expected format %s", s, types.DueDateFormat)
+	}
+	return types.DueDate(t), nil
+}
+
+func parseID(s string) (uint, error) {
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("invalid id %q", s)
+	}
+	return uint(id), nil
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Neurostep/todo/pkg/client"
+	"github.com/Neurostep/todo/pkg/types"
+)
+
+var addCommand = &Command{
+	Name:    "add",
+	Summary: "Create a todo",
+	Run: func(ctx context.Context, env *Env, args []string) error {
+		var s session
+		fs := env.flagSet("add", "<title>")
+		s.register(fs)
+		due := fs.String("due", "", "due date in "+types.DueDateFormat+" format (default today)")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		title := strings.Join(fs.Args(), " ")
+		if title == "" {
+			return usageErrorf(fs, "title is required")
+		}
+
+		dueDate := types.DueDate(time.Now())
+		if *due != "" {
+			var err error
+			if dueDate, err = parseDate(*due); err != nil {
+				return err
+			}
+		}
+
+		return s.with(ctx, env, func(c *client.Client) error {
+			td, err := c.CreateTodo(ctx, client.NewTodo{Title: title, DueDate: dueDate})
+			if err != nil {
+				return err
+			}
+			return render(env.Stdout, s.output, td, todosTable([]client.Todo{*td}))
+		})
+	},
+}
+
+var lsCommand = &Command{
+	Name:    "ls",
+	Summary: "List todos",
+	Run: func(ctx context.Context, env *Env, args []string) error {
+		var s session
+		fs := env.flagSet("ls", "")
+		s.register(fs)
+		limit := fs.Int("limit", 0, "maximum number of todos to list (default all)")
+		pending := fs.Bool("pending", false, "list only todos which are not done")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() != 0 {
+			return usageErrorf(fs, "unexpected arguments %v", fs.Args())
+		}
+
+		return s.with(ctx, env, func(c *client.Client) error {
+			todos := []client.Todo{}
+			it := c.Todos(client.ListTodosOptions{Limit: 100})
+			for (*limit <= 0 || len(todos) < *limit) && it.Next(ctx) {
+				if td := it.Todo(); !*pending || !td.Done {
+					todos = append(todos, td)
+				}
+			}
+			if err := it.Err(); err != nil {
+				return err
+			}
+			return render(env.Stdout, s.output, todos, todosTable(todos))
+		})
+	},
+}
+
+var doneCommand = &Command{
+	Name:    "done",
+	Summary: "Mark todos as done",
+	Run: func(ctx context.Context, env *Env, args []string) error {
+		var s session
+		fs := env.flagSet("done", "<id>...")
+		s.register(fs)
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			return usageErrorf(fs, "at least one id is required")
+		}
+
+		ids := make([]uint, 0, fs.NArg())
+		for _, arg := range fs.Args() {
+			id, err := parseID(arg)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+
+		return s.with(ctx, env, func(c *client.Client) error {
+			todos := make([]client.Todo, 0, len(ids))
+			for _, id := range ids {
+				td, err := c.GetTodo(ctx, id)
+				if err != nil {
+					return err
+				}
+				// updates replace the todo, the fields not changed are sent as they are
+				td, err = c.UpdateTodo(ctx, id, client.UpdateTodo{
+					Title:           td.Title,
+					Description:     td.Description,
+					DueDate:         td.DueDate,
+					Done:            true,
+					Assignee:        td.Assignee,
+					Priority:        td.Priority,
+					EstimateMinutes: td.EstimateMinutes,
+					Fields:          td.Fields,
+				})
+				if err != nil {
+					return err
+				}
+				todos = append(todos, *td)
+			}
+			return render(env.Stdout, s.output, todos, todosTable(todos))
+		})
+	},
+}
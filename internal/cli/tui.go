@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/Neurostep/todo/internal/tui"
+	"github.com/Neurostep/todo/pkg/client"
+)
+
+var tuiCommand = &Command{
+	Name:    "tui",
+	Summary: "Browse and edit todos in a full-screen terminal UI",
+	Run: func(ctx context.Context, env *Env, args []string) error {
+		var s session
+		fs := env.flagSet("tui", "")
+		fs.StringVar(&s.server, "server", "", "server address (default from todo login, then "+defaultServer+")")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		s.output = formatTable
+
+		in, ok := env.Stdin.(*os.File)
+		if !ok {
+			return errors.New("the terminal UI requires an interactive terminal")
+		}
+
+		return s.with(ctx, env, func(c *client.Client) error {
+			return tui.Run(ctx, c, in, env.Stdout)
+		})
+	},
+}
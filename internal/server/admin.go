@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime/debug"
+	"time"
+
+	"contrib.go.opencensus.io/exporter/prometheus"
+	"github.com/gin-gonic/gin"
+	"github.com/go-kit/kit/log"
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/Neurostep/todo/pkg/tools/health"
+)
+
+const (
+	DefaultAdminPort = 9001
+
+	// adminShutdownTimeout bounds the requests in flight on shutdown, such
+	// as scrapes and probes.
+	adminShutdownTimeout = 5 * time.Second
+)
+
+type (
+	// AdminConfig configures the admin listener, serving the probes,
+	// metrics, profiles, build information and the configuration of the
+	// service. It must not be reachable from the public network.
+	AdminConfig struct {
+		// Port defaults to DefaultAdminPort
+		Port               int
+		Logger             log.Logger
+		PrometheusExporter *prometheus.Exporter
+		// Health decides readiness, an empty registry is used when nil
+		Health *health.Registry
+		// Settings returns the configuration served at /config, its
+		// secrets redacted
+		Settings func() interface{}
+		// ShutdownDelay keeps answering probes while readiness fails on
+		// shutdown, it matches the one of the public listener
+		ShutdownDelay time.Duration
+	}
+
+	admin struct {
+		*http.Server
+		conf   AdminConfig
+		logger log.Logger
+		health *health.Registry
+	}
+
+	// BuildInfo describes the binary, as recorded by the Go toolchain.
+	BuildInfo struct {
+		// Version is the version of the module, (devel) for local builds
+		Version   string `json:"version"`
+		GoVersion string `json:"go_version"`
+		// Revision, Time and Modified describe the VCS checkout the binary
+		// was built from, when known
+		Revision string `json:"revision,omitempty"`
+		Time     string `json:"time,omitempty"`
+		Modified bool   `json:"modified,omitempty"`
+	}
+)
+
+func NewAdmin(c AdminConfig) *admin {
+	a := &admin{conf: c, logger: c.Logger, health: c.Health}
+	if a.health == nil {
+		a.health = health.New()
+	}
+	port := c.Port
+	if port == 0 {
+		port = DefaultAdminPort
+	}
+	a.Server = &http.Server{
+		Addr:        fmt.Sprintf(":%d", port),
+		Handler:     a.routes(),
+		ReadTimeout: defaultReadTimeout,
+		IdleTimeout: defaultIdleTimeout,
+		// no WriteTimeout, CPU profiles and execution traces take as long
+		// as asked for
+	}
+	return a
+}
+
+func (a *admin) routes() *gin.Engine {
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	router.GET("/healthz", a.healthz)
+	router.GET("/readyz", a.readyz)
+	router.GET("/buildinfo", a.buildInfo)
+	router.GET("/config", a.settings)
+	if a.conf.PrometheusExporter != nil && a.setupPrometheusMetrics() == nil {
+		router.GET("/metrics", gin.WrapH(a.conf.PrometheusExporter))
+	}
+
+	profiles := router.Group("/debug/pprof")
+	profiles.GET("/", gin.WrapF(pprof.Index))
+	profiles.GET("/:profile", gin.WrapF(pprof.Index))
+	profiles.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	profiles.GET("/profile", gin.WrapF(pprof.Profile))
+	profiles.GET("/symbol", gin.WrapF(pprof.Symbol))
+	profiles.POST("/symbol", gin.WrapF(pprof.Symbol))
+	profiles.GET("/trace", gin.WrapF(pprof.Trace))
+
+	return router
+}
+
+// setupPrometheusMetrics registers the views of the requests of the public
+// listener.
+func (a *admin) setupPrometheusMetrics() error {
+	err := view.Register(
+		ochttp.ServerRequestCountView,
+		ochttp.ServerRequestBytesView,
+		ochttp.ServerResponseBytesView,
+		ochttp.ServerLatencyView,
+		ochttp.ServerRequestCountByMethod,
+		ochttp.ServerResponseCountByStatusCode,
+		&view.View{
+			Name:        "opencensus.io/http/server/route_latency",
+			Description: "Latency distribution",
+			TagKeys:     []tag.Key{ochttp.KeyServerRoute, ochttp.StatusCode},
+			Measure:     ochttp.ServerLatency,
+			Aggregation: ochttp.DefaultLatencyDistribution,
+		},
+	)
+	if err != nil {
+		a.logger.Log("event", "http_monitoring_view_register_failed", "error", err)
+	}
+	return err
+}
+
+func (a *admin) buildInfo(c *gin.Context) {
+	c.JSON(http.StatusOK, readBuildInfo())
+}
+
+func readBuildInfo() BuildInfo {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return BuildInfo{Version: "unknown"}
+	}
+	info := BuildInfo{Version: bi.Main.Version, GoVersion: bi.GoVersion}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Revision = s.Value
+		case "vcs.time":
+			info.Time = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	return info
+}
+
+// settings dumps the configuration the service runs with, in the format of
+// the configuration file.
+func (a *admin) settings(c *gin.Context) {
+	if a.conf.Settings == nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.YAML(http.StatusOK, a.conf.Settings())
+}
+
+func (a *admin) Start(ctx context.Context) error {
+	errChan := make(chan error, 1)
+	go func() {
+		a.logger.Log("event", "starting_admin_server", "address", a.Addr)
+		if err := a.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			a.logger.Log("error", err)
+			errChan <- err
+		}
+		close(errChan)
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		// probes keep getting answers while the public listener drains
+		if a.conf.ShutdownDelay > 0 {
+			time.Sleep(a.conf.ShutdownDelay)
+		}
+		c, cancel := context.WithTimeout(context.Background(), adminShutdownTimeout)
+		defer cancel()
+		if err := a.Shutdown(c); err != nil {
+			a.logger.Log("error", err)
+		}
+	}
+	return nil
+}
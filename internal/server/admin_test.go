@@ -0,0 +1,51 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmin(t *testing.T) {
+	settings := map[string]interface{}{"server": map[string]int{"port": 9000}}
+	a := NewAdmin(AdminConfig{Logger: log.NewNopLogger(), Settings: func() interface{} { return settings }})
+	require.Equal(t, ":9001", a.Addr)
+
+	for path, code := range map[string]int{
+		"/healthz":             http.StatusOK,
+		"/readyz":              http.StatusOK,
+		"/debug/pprof/":        http.StatusOK,
+		"/debug/pprof/cmdline": http.StatusOK,
+		"/debug/pprof/heap":    http.StatusOK,
+		"/metrics":             http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		a.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, code, rec.Code, path)
+	}
+
+	rec := httptest.NewRecorder()
+	a.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/buildinfo", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var info BuildInfo
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	require.NotEmpty(t, info.GoVersion)
+
+	rec = httptest.NewRecorder()
+	a.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "server:\n  port: 9000\n", rec.Body.String())
+}
+
+func TestPublicRoutesHideAdmin(t *testing.T) {
+	s := New(Config{Port: 9000, Logger: log.NewNopLogger()})
+	for _, path := range []string{"/healthz", "/readyz", "/metrics", "/debug/pprof/", "/config"} {
+		rec := httptest.NewRecorder()
+		s.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusNotFound, rec.Code, path)
+	}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/Neurostep/todo/pkg/services/todo"
+	"github.com/Neurostep/todo/pkg/tools/logging"
+)
+
+// permission is what an endpoint does with the project of the request.
+type permission string
+
+const (
+	// permView reads the project, its todos and its members
+	permView permission = "view"
+	// permComment comments the todos of the project
+	permComment permission = "comment"
+	// permEdit creates, changes and deletes the todos of the project
+	permEdit permission = "edit"
+	// permManage changes the project and its members, and invites users
+	permManage permission = "manage"
+)
+
+// projectKey holds the project of the authorized request in the gin context.
+const projectKey = "todo.project"
+
+// errNotMember is returned when a todo is given to a user outside its
+// project.
+var errNotMember = errors.New("the user is not a member of the project")
+
+// grants are the permissions of the roles of the members.
+var grants = map[todo.Role][]permission{
+	todo.RoleOwner:     {permView, permComment, permEdit, permManage},
+	todo.RoleEditor:    {permView, permComment, permEdit},
+	todo.RoleCommenter: {permView, permComment},
+	todo.RoleViewer:    {permView},
+}
+
+// projectOf returns the project a request addresses, zero for the todos
+// outside projects. Lookups run under ctx, the context of the authorize span.
+type projectOf func(ctx context.Context, c *gin.Context) (uint, error)
+
+func allows(role todo.Role, p permission) bool {
+	for _, granted := range grants[role] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize lets the request through when the user has the permission of
+// the endpoint in the project of the request. The projects of others are
+// answered with 404 as if they did not exist, members lacking the
+// permission with 403. Todos outside projects are shared by every user, and
+// projects have no members when the API does not require authorization.
+func (r *api) authorize(e endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := trace.StartSpan(c.Request.Context(), "authorize")
+		defer span.End()
+		logger := logging.FromContext(ctx, r.logger)
+
+		projectId, err := e.project(ctx, c)
+		if err != nil {
+			respondErrors(c, logger, serviceErrorCode(err), newError("authorization", err.Error()))
+			return
+		}
+		c.Set(projectKey, projectId)
+		if projectId == 0 {
+			c.Next()
+			return
+		}
+
+		if !r.conf.AuthEnabled {
+			if _, err := r.conf.TodoService.GetProject(ctx, projectId); err != nil {
+				respondErrors(c, logger, serviceErrorCode(err), newError("authorization", err.Error()))
+				return
+			}
+			c.Next()
+			return
+		}
+
+		m, err := r.conf.TodoService.GetMembership(ctx, projectId, c.GetString(usernameKey))
+		if err != nil {
+			respondErrors(c, logger, serviceErrorCode(err), newError("authorization", err.Error()))
+			return
+		}
+		if !allows(m.Role, e.permission) {
+			respondErrors(c, logger, http.StatusForbidden,
+				newError("authorization", fmt.Sprintf("the %s role may not %s the project", m.Role, e.permission)))
+			return
+		}
+		c.Next()
+	}
+}
+
+// checkMember tells whether the user may be given a todo of the project of
+// the authorized request, assigned or watching. Todos outside projects are
+// shared by every user, as are all the todos when the API does not require
+// authorization.
+func (r *api) checkMember(ctx context.Context, c *gin.Context, username string) error {
+	projectId := c.GetUint(projectKey)
+	if username == "" || projectId == 0 || !r.conf.AuthEnabled {
+		return nil
+	}
+	_, err := r.conf.TodoService.GetMembership(ctx, projectId, username)
+	if errors.Is(err, todo.ErrNotFound) {
+		return errors.Wrap(errNotMember, username)
+	}
+	return err
+}
+
+// pathProject is the project of the projectId path parameter.
+func (r *api) pathProject(ctx context.Context, c *gin.Context) (uint, error) {
+	return pathID(c, "projectId"), nil
+}
+
+// todoProject is the project of the todo of the id path parameter.
+func (r *api) todoProject(ctx context.Context, c *gin.Context) (uint, error) {
+	td, err := r.conf.TodoService.GetTodo(ctx, pathID(c, "id"))
+	if err != nil {
+		return 0, err
+	}
+	return td.ProjectId, nil
+}
+
+// bodyProject is the project a todo is created in.
+func (r *api) bodyProject(ctx context.Context, c *gin.Context) (uint, error) {
+	return requestBody(c).(*NewTodo).ProjectID, nil
+}
+
+// queryProject is the project todos are listed of.
+func (r *api) queryProject(ctx context.Context, c *gin.Context) (uint, error) {
+	return requestQuery(c).(*TodosQuery).ProjectID, nil
+}
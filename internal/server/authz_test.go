@@ -0,0 +1,125 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-kit/kit/log"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Neurostep/todo/pkg/services/todo"
+)
+
+func TestAuthorization(t *testing.T) {
+	as := newAuthorizedAPI(t, "alice", "bob")
+
+	var project ProjectResponse
+	require.Equal(t, http.StatusCreated, as("alice", http.MethodPost, "/api/v1/projects", `{"name":"home"}`, &project))
+	var td, shared TodoResponse
+	require.Equal(t, http.StatusCreated, as("alice", http.MethodPost, "/api/v1/todos",
+		fmt.Sprintf(`{"title":"groceries","due_date":"2022-09-01","project_id":%d}`, project.ID), &td))
+	require.Equal(t, project.ID, td.ProjectID)
+	require.Equal(t, http.StatusCreated, as("bob", http.MethodPost, "/api/v1/todos", `{"title":"shared","due_date":"2022-09-01"}`, &shared))
+
+	todoPath := fmt.Sprintf("/api/v1/todos/%d", td.ID)
+	projectPath := fmt.Sprintf("/api/v1/projects/%d", project.ID)
+	require.Equal(t, http.StatusNotFound, as("bob", http.MethodGet, todoPath, "", nil), "projects of others look missing")
+	require.Equal(t, http.StatusNotFound, as("bob", http.MethodGet, "/api/v1/todos?project_id="+fmt.Sprint(project.ID), "", nil))
+	require.Equal(t, http.StatusOK, as("alice", http.MethodGet, fmt.Sprintf("/api/v1/todos/%d", shared.ID), "", nil), "todos outside projects are shared")
+
+	require.Equal(t, http.StatusBadRequest, as("alice", http.MethodPost, projectPath+"/invitations", `{"username":"bob","role":"admin"}`, nil))
+	var inv InvitationResponse
+	require.Equal(t, http.StatusCreated, as("alice", http.MethodPost, projectPath+"/invitations", `{"username":"bob","role":"commenter"}`, &inv))
+	require.Equal(t, http.StatusConflict, as("alice", http.MethodPost, projectPath+"/invitations", `{"username":"bob","role":"viewer"}`, nil))
+
+	invitePath := fmt.Sprintf("/api/v1/invitations/%d", inv.ID)
+	require.Equal(t, http.StatusNotFound, as("alice", http.MethodPost, invitePath+"/accept", "", nil), "invitations of others")
+	var invitations []InvitationResponse
+	require.Equal(t, http.StatusOK, as("bob", http.MethodGet, "/api/v1/invitations", "", &invitations))
+	require.Equal(t, []InvitationResponse{inv}, invitations)
+	var bob MemberResponse
+	require.Equal(t, http.StatusCreated, as("bob", http.MethodPost, invitePath+"/accept", "", &bob))
+	require.Equal(t, "commenter", bob.Role)
+
+	require.Equal(t, http.StatusOK, as("bob", http.MethodGet, todoPath, "", nil))
+	require.Equal(t, http.StatusCreated, as("bob", http.MethodPost, todoPath+"/comments", `{"text":"milk"}`, nil))
+	require.Equal(t, http.StatusForbidden, as("bob", http.MethodPut, todoPath, `{"title":"groceries","due_date":"2022-09-02"}`, nil))
+	require.Equal(t, http.StatusForbidden, as("bob", http.MethodPost, projectPath+"/invitations", `{"username":"carol","role":"viewer"}`, nil))
+
+	memberPath := fmt.Sprintf("%s/members/%d", projectPath, bob.ID)
+	require.Equal(t, http.StatusOK, as("alice", http.MethodPut, memberPath, `{"role":"editor"}`, nil))
+	require.Equal(t, http.StatusOK, as("bob", http.MethodPut, todoPath, `{"title":"groceries","due_date":"2022-09-02"}`, nil))
+
+	var members []MemberResponse
+	require.Equal(t, http.StatusOK, as("bob", http.MethodGet, projectPath+"/members", "", &members))
+	require.Len(t, members, 2)
+	alice := members[0]
+	require.Equal(t, MemberResponse{ID: alice.ID, Username: "alice", Role: "owner"}, alice)
+	require.Equal(t, http.StatusConflict, as("alice", http.MethodDelete, fmt.Sprintf("%s/members/%d", projectPath, alice.ID), "", nil),
+		"projects keep an owner")
+
+	var projects []ProjectResponse
+	require.Equal(t, http.StatusOK, as("bob", http.MethodGet, "/api/v1/projects", "", &projects))
+	require.Equal(t, []ProjectResponse{project}, projects)
+
+	require.Equal(t, http.StatusNoContent, as("alice", http.MethodDelete, memberPath, "", nil))
+	require.Equal(t, http.StatusNotFound, as("bob", http.MethodGet, todoPath, "", nil), "removed members lose access")
+}
+
+func TestGrants(t *testing.T) {
+	for _, role := range todo.Roles {
+		require.True(t, allows(role, permView), "every role views")
+	}
+	require.True(t, allows(todo.RoleCommenter, permComment))
+	require.False(t, allows(todo.RoleViewer, permComment))
+	require.False(t, allows(todo.RoleCommenter, permEdit))
+	require.False(t, allows(todo.RoleEditor, permManage))
+	require.True(t, allows(todo.RoleOwner, permManage))
+}
+
+// newAuthorizedAPI serves an API requiring authorization in front of an
+// in-memory service. The function returned makes requests as one of the
+// users, decoding the response into res unless it is an error.
+func newAuthorizedAPI(t *testing.T, users ...string) func(username, method, target, body string, res interface{}) int {
+	r := &api{
+		conf: Config{
+			AuthEnabled: true,
+			TodoService: todo.New(todo.Config{Repository: todo.NewMemoryRepository(), Logger: log.NewNopLogger()}),
+		},
+		logger: log.NewNopLogger(),
+	}
+	router := r.routes()
+
+	tokens := map[string]string{}
+	for _, username := range users {
+		token, err := r.signToken(&Claims{
+			Username:         username,
+			RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+		})
+		require.NoError(t, err)
+		tokens[username] = token
+	}
+	return func(username, method, target, body string, res interface{}) int {
+		rec := serveAs(router, tokens[username], method, target, body)
+		if res != nil && rec.Code < http.StatusBadRequest {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), res))
+		}
+		return rec.Code
+	}
+}
+
+func serveAs(router *gin.Engine, token, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	router.ServeHTTP(rec, req)
+	return rec
+}
@@ -2,7 +2,6 @@ package server
 
 import (
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"go.opencensus.io/trace"
@@ -16,32 +15,17 @@ func (r *api) addCommentToTodo(c *gin.Context) {
 	defer span.End()
 	logger := logging.FromContext(ctx, r.logger)
 
-	var req NewComment
-	if err := c.ShouldBindJSON(&req); err != nil {
-		errs := extractBindErrors(err)
-		respondErrors(c, logger, http.StatusBadRequest, errs...)
-		return
-	}
+	req := requestBody(c).(*NewComment)
 
-	idStr := c.Param("id")
-	if idStr == "" {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo.comment", "id is empty"))
-		return
-	}
+	id := pathID(c, "id")
 
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo.comment", "id is not numeric"))
-		return
-	}
-
-	comment, err := r.conf.TodoService.AddComment(c, r.conf.DB, todo.AddComment{
-		TodoId: uint(id),
+	comment, err := r.conf.TodoService.AddComment(ctx, todo.AddComment{
+		TodoId: id,
 		Text:   req.Text,
 	})
 
 	if err != nil {
-		respondErrors(c, logger, http.StatusInternalServerError, newError("todo.comment", err.Error()))
+		respondErrors(c, logger, serviceErrorCode(err), newError("todo.comment", err.Error()))
 		return
 	}
 
@@ -56,34 +40,14 @@ func (r *api) removeCommentFromTodo(c *gin.Context) {
 	defer span.End()
 	logger := logging.FromContext(ctx, r.logger)
 
-	idStr := c.Param("id")
-	if idStr == "" {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo.comment", "id is empty"))
-		return
-	}
-
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo.comment", "id is not numeric"))
-		return
-	}
-
-	commentIdStr := c.Param("commentId")
-	if commentIdStr == "" {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo.comment", "commentId is empty"))
-		return
-	}
+	id := pathID(c, "id")
 
-	commentId, err := strconv.Atoi(commentIdStr)
-	if err != nil {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo.comment", "commentId is not numeric"))
-		return
-	}
+	commentId := pathID(c, "commentId")
 
-	err = r.conf.TodoService.RemoveComment(c, r.conf.DB, uint(id), uint(commentId))
+	err := r.conf.TodoService.RemoveComment(ctx, id, commentId)
 
 	if err != nil {
-		respondErrors(c, logger, http.StatusInternalServerError, newError("todo.comment", err.Error()))
+		respondErrors(c, logger, serviceErrorCode(err), newError("todo.comment", err.Error()))
 		return
 	}
 
@@ -95,22 +59,12 @@ func (r *api) getComments(c *gin.Context) {
 	defer span.End()
 	logger := logging.FromContext(ctx, r.logger)
 
-	idStr := c.Param("id")
-	if idStr == "" {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo.comment", "id is empty"))
-		return
-	}
-
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo.comment", "id is not numeric"))
-		return
-	}
+	id := pathID(c, "id")
 
-	labels, err := r.conf.TodoService.GetComments(c, r.conf.DB, uint(id))
+	labels, err := r.conf.TodoService.GetComments(ctx, id)
 
 	if err != nil {
-		respondErrors(c, logger, http.StatusInternalServerError, newError("todo.comment", err.Error()))
+		respondErrors(c, logger, serviceErrorCode(err), newError("todo.comment", err.Error()))
 		return
 	}
 
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// defaultCORSMethods are the methods of the route table.
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+	// defaultCORSHeaders are the request headers the API reads.
+	defaultCORSHeaders = []string{"Accept", "Authorization", "Content-Type", idempotencyKeyHeader, requestIDHeader}
+	// defaultCORSExposedHeaders are the response headers clients act upon.
+	defaultCORSExposedHeaders = []string{
+		"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", idempotentReplayedHeader,
+		requestIDHeader,
+	}
+)
+
+const defaultCORSMaxAge = time.Hour
+
+type (
+	// CORSConfig is the policy for cross-origin requests of browsers.
+	CORSConfig struct {
+		// AllowedOrigins are the origins allowed to call the API, such as
+		// https://todo.example.com. A * matches any part of a host name or
+		// port, https://*.example.com or http://localhost:*, a lone * any
+		// origin. No cross-origin request is allowed when empty.
+		AllowedOrigins []string
+		// AllowedMethods, AllowedHeaders and ExposedHeaders default to the
+		// ones of the API
+		AllowedMethods []string
+		AllowedHeaders []string
+		ExposedHeaders []string
+		// AllowCredentials lets browsers send cookies, it is ignored with a
+		// lone * origin
+		AllowCredentials bool
+		// MaxAge is how long browsers cache preflight responses, an hour by
+		// default
+		MaxAge time.Duration
+	}
+
+	cors struct {
+		anyOrigin        bool
+		origins          []*regexp.Regexp
+		methods          map[string]bool
+		headers          map[string]bool
+		allowMethods     string
+		allowHeaders     string
+		exposeHeaders    string
+		allowCredentials bool
+		maxAge           string
+	}
+)
+
+// originPattern matches origins against a pattern where * stands for any
+// part of a host name or port.
+func originPattern(origin string) *regexp.Regexp {
+	parts := strings.Split(strings.ToLower(origin), "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, "[a-z0-9.-]*") + "$")
+}
+
+func newCORS(c CORSConfig) *cors {
+	p := &cors{
+		methods: map[string]bool{},
+		headers: map[string]bool{},
+	}
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			p.anyOrigin = true
+			continue
+		}
+		p.origins = append(p.origins, originPattern(o))
+	}
+	// browsers refuse credentials along with a * origin, reflecting the
+	// origin instead would let any site make credentialed requests
+	p.allowCredentials = c.AllowCredentials && !p.anyOrigin
+
+	methods := orDefaultList(c.AllowedMethods, defaultCORSMethods)
+	for i, m := range methods {
+		methods[i] = strings.ToUpper(m)
+		p.methods[methods[i]] = true
+	}
+	headers := orDefaultList(c.AllowedHeaders, defaultCORSHeaders)
+	for _, h := range headers {
+		p.headers[strings.ToLower(h)] = true
+	}
+	p.allowMethods = strings.Join(methods, ", ")
+	p.allowHeaders = strings.Join(headers, ", ")
+	p.exposeHeaders = strings.Join(orDefaultList(c.ExposedHeaders, defaultCORSExposedHeaders), ", ")
+
+	maxAge := c.MaxAge
+	if maxAge == 0 {
+		maxAge = defaultCORSMaxAge
+	}
+	p.maxAge = strconv.Itoa(int(maxAge.Seconds()))
+	return p
+}
+
+// SetCORS replaces the policy for cross-origin requests, it applies to the
+// requests received from then on.
+func (r *api) SetCORS(c CORSConfig) {
+	r.cors.Store(newCORS(c))
+}
+
+func (r *api) handleCORS(c *gin.Context) {
+	r.cors.Load().(*cors).handle(c)
+}
+
+func orDefaultList(list, def []string) []string {
+	if len(list) == 0 {
+		list = def
+	}
+	return append([]string(nil), list...)
+}
+
+func (p *cors) allowedOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, o := range p.origins {
+		if o.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedHeaders tells whether all the headers of a preflight request, a
+// comma separated list, are allowed. Headers safelisted by the Fetch
+// standard are always allowed.
+func (p *cors) allowedHeaders(list string) bool {
+	for _, h := range strings.Split(list, ",") {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" || p.headers[h] || h == "accept" || h == "accept-language" || h == "content-language" {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// handle applies the policy. Requests of disallowed origins are served
+// without the Access-Control-* headers, so browsers do not expose the
+// response, and their preflight requests are rejected with 403.
+func (p *cors) handle(c *gin.Context) {
+	origin := c.GetHeader("Origin")
+	preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+	h := c.Writer.Header()
+	// the response depends on the origin unless every origin gets the same
+	if !p.anyOrigin {
+		h.Add("Vary", "Origin")
+	}
+	if preflight {
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+	}
+
+	if origin == "" || !p.allowedOrigin(origin) {
+		p.next(c, preflight)
+		return
+	}
+
+	if p.anyOrigin {
+		c.Header("Access-Control-Allow-Origin", "*")
+	} else {
+		c.Header("Access-Control-Allow-Origin", origin)
+	}
+	if p.allowCredentials {
+		c.Header("Access-Control-Allow-Credentials", "true")
+	}
+
+	if !preflight {
+		if p.exposeHeaders != "" {
+			c.Header("Access-Control-Expose-Headers", p.exposeHeaders)
+		}
+		p.next(c, false)
+		return
+	}
+
+	if !p.methods[strings.ToUpper(c.GetHeader("Access-Control-Request-Method"))] ||
+		!p.allowedHeaders(c.GetHeader("Access-Control-Request-Headers")) {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	c.Header("Access-Control-Allow-Methods", p.allowMethods)
+	c.Header("Access-Control-Allow-Headers", p.allowHeaders)
+	c.Header("Access-Control-Max-Age", p.maxAge)
+	c.AbortWithStatus(http.StatusNoContent)
+}
+
+// next serves the request, answering the OPTIONS requests which are not
+// routed with the allowed methods.
+func (p *cors) next(c *gin.Context, preflight bool) {
+	switch {
+	case preflight:
+		c.AbortWithStatus(http.StatusForbidden)
+	case c.Request.Method == http.MethodOptions:
+		c.Header("Allow", "HEAD, OPTIONS, "+p.allowMethods)
+		c.AbortWithStatus(http.StatusNoContent)
+	default:
+		c.Next()
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Neurostep/todo/pkg/services/todo"
+)
+
+func TestCORS(t *testing.T) {
+	newRouter := func(c CORSConfig) http.Handler {
+		r := &api{
+			conf: Config{
+				TodoService: todo.New(todo.Config{Repository: todo.NewMemoryRepository(), Logger: log.NewNopLogger()}),
+				CORS:        c,
+			},
+			logger: log.NewNopLogger(),
+		}
+		return r.routes()
+	}
+	listed := newRouter(CORSConfig{AllowedOrigins: []string{"https://todo.example.com", "https://*.example.org", "http://localhost:*"}})
+	anyOrigin := newRouter(CORSConfig{AllowedOrigins: []string{"*"}})
+	credentials := newRouter(CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+
+	for name, tc := range map[string]struct {
+		router  http.Handler
+		method  string
+		headers map[string]string
+		code    int
+		// expected response headers, empty ones must be missing
+		expect map[string]string
+	}{
+		"same origin": {
+			router: listed, method: http.MethodGet, code: http.StatusOK,
+			expect: map[string]string{"Access-Control-Allow-Origin": "", "Vary": "Origin"},
+		},
+		"allowed origin": {
+			router: listed, method: http.MethodGet, code: http.StatusOK,
+			headers: map[string]string{"Origin": "https://todo.example.com"},
+			expect: map[string]string{
+				"Access-Control-Allow-Origin":      "https://todo.example.com",
+				"Access-Control-Allow-Credentials": "",
+				"Access-Control-Expose-Headers":    "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After, Idempotent-Replayed, X-Request-ID",
+				"Vary":                             "Origin",
+			},
+		},
+		"wildcard subdomain": {
+			router: listed, method: http.MethodGet, code: http.StatusOK,
+			headers: map[string]string{"Origin": "https://app.example.org"},
+			expect:  map[string]string{"Access-Control-Allow-Origin": "https://app.example.org"},
+		},
+		"wildcard port": {
+			router: listed, method: http.MethodGet, code: http.StatusOK,
+			headers: map[string]string{"Origin": "http://localhost:3000"},
+			expect:  map[string]string{"Access-Control-Allow-Origin": "http://localhost:3000"},
+		},
+		"wildcard does not span the domain": {
+			router: listed, method: http.MethodGet, code: http.StatusOK,
+			headers: map[string]string{"Origin": "https://example.org.evil.com"},
+			expect:  map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		"disallowed origin": {
+			router: listed, method: http.MethodGet, code: http.StatusOK,
+			headers: map[string]string{"Origin": "https://evil.com"},
+			expect:  map[string]string{"Access-Control-Allow-Origin": "", "Vary": "Origin"},
+		},
+		"preflight": {
+			router: listed, method: http.MethodOptions, code: http.StatusNoContent,
+			headers: map[string]string{
+				"Origin":                         "https://todo.example.com",
+				"Access-Control-Request-Method":  "POST",
+				"Access-Control-Request-Headers": "authorization, content-type, idempotency-key",
+			},
+			expect: map[string]string{
+				"Access-Control-Allow-Origin":  "https://todo.example.com",
+				"Access-Control-Allow-Methods": "GET, POST, PUT, DELETE",
+				"Access-Control-Allow-Headers": "Accept, Authorization, Content-Type, Idempotency-Key, X-Request-ID",
+				"Access-Control-Max-Age":       "3600",
+			},
+		},
+		"preflight of disallowed origin": {
+			router: listed, method: http.MethodOptions, code: http.StatusForbidden,
+			headers: map[string]string{"Origin": "https://evil.com", "Access-Control-Request-Method": "GET"},
+			expect:  map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		"preflight of disallowed method": {
+			router: listed, method: http.MethodOptions, code: http.StatusForbidden,
+			headers: map[string]string{"Origin": "https://todo.example.com", "Access-Control-Request-Method": "PATCH"},
+			expect:  map[string]string{"Access-Control-Allow-Methods": ""},
+		},
+		"preflight of disallowed header": {
+			router: listed, method: http.MethodOptions, code: http.StatusForbidden,
+			headers: map[string]string{
+				"Origin":                         "https://todo.example.com",
+				"Access-Control-Request-Method":  "GET",
+				"Access-Control-Request-Headers": "x-custom",
+			},
+			expect: map[string]string{"Access-Control-Allow-Headers": ""},
+		},
+		"options without preflight": {
+			router: listed, method: http.MethodOptions, code: http.StatusNoContent,
+			expect: map[string]string{"Allow": "HEAD, OPTIONS, GET, POST, PUT, DELETE"},
+		},
+		"any origin": {
+			router: anyOrigin, method: http.MethodGet, code: http.StatusOK,
+			headers: map[string]string{"Origin": "https://evil.com"},
+			expect:  map[string]string{"Access-Control-Allow-Origin": "*", "Vary": ""},
+		},
+		"any origin with credentials": {
+			router: credentials, method: http.MethodGet, code: http.StatusOK,
+			headers: map[string]string{"Origin": "https://todo.example.com"},
+			expect: map[string]string{
+				"Access-Control-Allow-Origin":      "*",
+				"Access-Control-Allow-Credentials": "",
+				"Vary":                             "",
+			},
+		},
+		"no policy": {
+			router: newRouter(CORSConfig{}), method: http.MethodGet, code: http.StatusOK,
+			headers: map[string]string{"Origin": "https://todo.example.com"},
+			expect:  map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, "/api/v1/todos", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			tc.router.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.code, rec.Code)
+			for k, v := range tc.expect {
+				require.Equal(t, v, rec.Header().Get(k), k)
+			}
+		})
+	}
+}
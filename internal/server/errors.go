@@ -2,13 +2,14 @@ package server
 
 import (
 	"fmt"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-kit/kit/log"
-	"gopkg.in/go-playground/validator.v8"
-)
+	"github.com/pkg/errors"
 
-const errorMsg = "validation for '%s' failed on the '%s' tag"
+	"github.com/Neurostep/todo/pkg/services/todo"
+)
 
 type (
 	Error struct {
@@ -25,7 +26,13 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("%s:%s", e.Label, e.Message)
 }
 
+// respondErrors aborts the request with the errors. Server errors of
+// requests past their deadline are answered with 503 and a timeout error.
 func respondErrors(c *gin.Context, logger log.Logger, code int, errors ...*Error) {
+	if code >= http.StatusInternalServerError && timedOut(c) {
+		code = http.StatusServiceUnavailable
+		errors = []*Error{newError("timeout", "the request timed out, retry later")}
+	}
 	errs := &Errors{
 		Errors: errors,
 	}
@@ -42,20 +49,30 @@ func newError(label, message string) *Error {
 	}
 }
 
-func extractBindErrors(err error) []*Error {
-	switch e := err.(type) {
-	case validator.ValidationErrors:
-		res := []*Error{}
-		for _, v := range e {
-			res = append(res, &Error{
-				Label:   v.Field,
-				Message: fmt.Sprintf(errorMsg, v.Field, v.Tag),
-			})
-		}
-		if len(res) == 0 {
-			return []*Error{newError("validation", err.Error())}
-		}
-		return res
-	}
-	return []*Error{newError("bind_error", err.Error())}
+// serviceErrorCode maps an error of the todo service, or of its
+// authorization, to the response code.
+func serviceErrorCode(err error) int {
+	switch {
+	case errors.Is(err, todo.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, todo.ErrConflict), errors.Is(err, todo.ErrLastOwner):
+		return http.StatusConflict
+	case errors.Is(err, errNotMember):
+		return http.StatusBadRequest
+	}
+	var invalid *todo.ValidationError
+	if errors.As(err, &invalid) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// serviceError labels an error of the todo service, validation errors by
+// the field they are about.
+func serviceError(label string, err error) *Error {
+	var invalid *todo.ValidationError
+	if errors.As(err, &invalid) {
+		return newError(invalid.Field, invalid.Message)
+	}
+	return newError(label, err.Error())
 }
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opencensus.io/trace"
+
+	"github.com/Neurostep/todo/pkg/services/todo"
+	"github.com/Neurostep/todo/pkg/tools/logging"
+)
+
+func (r *api) createField(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "create_field")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	req := requestBody(c).(*NewField)
+
+	f, err := r.conf.TodoService.CreateField(ctx, todo.CreateField{
+		ProjectId: pathID(c, "projectId"),
+		Name:      req.Name,
+		Type:      todo.FieldType(req.Type),
+		Options:   req.Options,
+		Required:  req.Required,
+	})
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), serviceError("project.field", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, fieldResponse(f))
+}
+
+func (r *api) getFields(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "list_fields")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	fields, err := r.conf.TodoService.GetFields(ctx, pathID(c, "projectId"))
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("project.field", err.Error()))
+		return
+	}
+
+	response := make([]FieldResponse, len(fields))
+	for i, f := range fields {
+		response[i] = fieldResponse(&f)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (r *api) deleteField(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "delete_field")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	err := r.conf.TodoService.DeleteField(ctx, pathID(c, "projectId"), pathID(c, "fieldId"))
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("project.field", err.Error()))
+		return
+	}
+
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+func fieldResponse(f *todo.Field) FieldResponse {
+	return FieldResponse{
+		ID:       f.ID,
+		Name:     f.Name,
+		Type:     string(f.Type),
+		Options:  f.Options,
+		Required: f.Required,
+	}
+}
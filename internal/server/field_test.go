@@ -0,0 +1,91 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Neurostep/todo/pkg/services/todo"
+)
+
+func TestCustomFields(t *testing.T) {
+	r := &api{
+		conf: Config{
+			TodoService: todo.New(todo.Config{Repository: todo.NewMemoryRepository(), Logger: log.NewNopLogger()}),
+		},
+		logger: log.NewNopLogger(),
+	}
+	router := r.routes()
+
+	do := func(method, target, body string, res interface{}) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rec, req)
+		if res != nil && rec.Code < http.StatusBadRequest {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), res))
+		}
+		return rec
+	}
+
+	var project ProjectResponse
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/v1/projects", `{"name":"home"}`, &project).Code)
+	fieldsPath := fmt.Sprintf("/api/v1/projects/%d/fields", project.ID)
+	var room FieldResponse
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, fieldsPath, `{"name":"room","type":"enum","options":["kitchen","garden"]}`, &room).Code)
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, fieldsPath, `{"name":"size","type":"number"}`, nil).Code)
+	rec := do(http.MethodPost, fieldsPath, `{"name":"color","type":"enum"}`, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.JSONEq(t, `{"errors":[{"label":"options","message":"are required by enum fields"}]}`, rec.Body.String())
+
+	var fields []FieldResponse
+	require.Equal(t, http.StatusOK, do(http.MethodGet, fieldsPath, "", &fields).Code)
+	require.Len(t, fields, 2)
+	require.Equal(t, room, fields[0])
+
+	newTodo := func(title, extra string) string {
+		return fmt.Sprintf(`{"title":%q,"due_date":"2022-09-01","project_id":%d%s}`, title, project.ID, extra)
+	}
+	var dishes TodoResponse
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/v1/todos",
+		newTodo("dishes", `,"priority":"P1","estimate_minutes":20,"fields":{"room":"kitchen","size":3}`), &dishes).Code)
+	require.Equal(t, "P1", dishes.Priority)
+	require.Equal(t, uint(20), dishes.EstimateMinutes)
+	require.Equal(t, map[string]interface{}{"room": "kitchen", "size": 3.0}, dishes.Fields)
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/v1/todos", newTodo("weeds", `,"fields":{"room":"garden","size":10}`), nil).Code)
+
+	rec = do(http.MethodPost, "/api/v1/todos", newTodo("attic", `,"fields":{"room":"attic"}`), nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.JSONEq(t, `{"errors":[{"label":"fields.room","message":"must be one of kitchen, garden"}]}`, rec.Body.String())
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/v1/todos", newTodo("urgent", `,"priority":"P9"`), nil).Code)
+
+	list := func(query string) []string {
+		var todos TodosResponse
+		rec := do(http.MethodGet, fmt.Sprintf("/api/v1/todos?project_id=%d&%s", project.ID, query), "", &todos)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		titles := []string{}
+		for _, td := range todos.Data {
+			titles = append(titles, td.Title)
+		}
+		return titles
+	}
+	require.Equal(t, []string{"weeds", "dishes"}, list("sort=-field.size"))
+	require.Equal(t, []string{"dishes", "weeds"}, list("sort=priority"))
+	require.Equal(t, []string{"weeds"}, list("priority=P2"))
+	require.Equal(t, []string{"dishes"}, list("field=room:kitchen"))
+	require.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/api/v1/todos?sort=title", "", nil).Code)
+	require.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/api/v1/todos?field=room:kitchen", "", nil).Code,
+		"fields of a single project")
+
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, fmt.Sprintf("%s/%d", fieldsPath, room.ID), "", nil).Code)
+	require.Equal(t, http.StatusNotFound, do(http.MethodDelete, fmt.Sprintf("%s/%d", fieldsPath, room.ID), "", nil).Code)
+	var td TodoResponse
+	require.Equal(t, http.StatusOK, do(http.MethodGet, fmt.Sprintf("/api/v1/todos/%d", dishes.ID), "", &td).Code)
+	require.Equal(t, map[string]interface{}{"size": 3.0}, td.Fields)
+}
@@ -6,10 +6,19 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func (r *api) healthz(c *gin.Context) {
+// healthz only tells the process is serving, restarting it would not fix a
+// failing dependency.
+func (a *admin) healthz(c *gin.Context) {
 	c.String(http.StatusOK, "OK")
 }
 
-func (r *api) readyz(c *gin.Context) {
-	c.String(http.StatusOK, "OK")
+// readyz answers 503 while a critical check fails or the server is shutting
+// down, with the result of every check in the body.
+func (a *admin) readyz(c *gin.Context) {
+	report, ready := a.health.Ready(c.Request.Context())
+	code := http.StatusOK
+	if !ready {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, report)
 }
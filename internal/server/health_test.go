@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Neurostep/todo/pkg/tools/health"
+)
+
+func TestReadyz(t *testing.T) {
+	for name, tc := range map[string]struct {
+		dbErr    error
+		shutdown bool
+		code     int
+		status   string
+	}{
+		"ready":         {code: http.StatusOK, status: health.StatusOK},
+		"database down": {dbErr: errors.New("connection refused"), code: http.StatusServiceUnavailable, status: health.StatusFailing},
+		"shutting down": {shutdown: true, code: http.StatusServiceUnavailable, status: health.StatusShuttingDown},
+	} {
+		t.Run(name, func(t *testing.T) {
+			checks := health.New()
+			checks.Register(health.Check{Name: "database", Critical: true, Func: func(context.Context) error {
+				return tc.dbErr
+			}})
+			if tc.shutdown {
+				checks.Shutdown()
+			}
+
+			a := NewAdmin(AdminConfig{Logger: log.NewNopLogger(), Health: checks})
+			rec := httptest.NewRecorder()
+			a.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			require.Equal(t, tc.code, rec.Code)
+
+			var report health.Report
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+			require.Equal(t, tc.status, report.Status)
+			require.Len(t, report.Checks, 1)
+			if tc.dbErr != nil {
+				require.Equal(t, tc.dbErr.Error(), report.Checks[0].Error)
+			}
+		})
+	}
+}
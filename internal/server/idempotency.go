@@ -0,0 +1,111 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Neurostep/todo/pkg/idempotency"
+	"github.com/Neurostep/todo/pkg/tools/logging"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader marks responses replayed from the store
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// recordingWriter keeps a copy of the response body.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotent makes retries of a request with the same Idempotency-Key
+// header get the response of the first one. Keys are per client, reusing a
+// key for a different request is rejected with 422, and retrying while the
+// first request is in progress with 409. Failed requests (5xx) are not
+// stored, so they can be retried.
+func (r *api) idempotent(c *gin.Context) {
+	key := c.GetHeader(idempotencyKeyHeader)
+	if key == "" {
+		c.Next()
+		return
+	}
+	ctx := c.Request.Context()
+	logger := logging.FromContext(ctx, r.logger)
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		respondErrors(c, logger, http.StatusBadRequest, newError("body", err.Error()))
+		return
+	}
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	fingerprint := requestFingerprint(c.Request.Method, c.Request.URL.Path, body)
+	storeKey := clientID(c) + ":" + key
+
+	rec, err := r.conf.Idempotency.Start(ctx, storeKey, fingerprint)
+	switch {
+	case err != nil:
+		// rather risk a duplicate than fail the request
+		logger.Log("event", "idempotency_store_failed", "error", err)
+		c.Next()
+		return
+	case rec == nil:
+	case rec.Fingerprint != fingerprint:
+		respondErrors(c, logger, http.StatusUnprocessableEntity,
+			newError("idempotency_key", "was used for a different request"))
+		return
+	case !rec.Done:
+		respondErrors(c, logger, http.StatusConflict,
+			newError("idempotency_key", "a request with this key is in progress"))
+		return
+	default:
+		c.Header(idempotentReplayedHeader, "true")
+		c.Data(rec.Status, rec.ContentType, rec.Body)
+		c.Abort()
+		return
+	}
+
+	w := &recordingWriter{ResponseWriter: c.Writer}
+	c.Writer = w
+	c.Next()
+
+	if w.Status() >= http.StatusInternalServerError {
+		err = r.conf.Idempotency.Abandon(ctx, storeKey)
+	} else {
+		err = r.conf.Idempotency.Finish(ctx, storeKey, idempotency.Record{
+			Fingerprint: fingerprint,
+			Status:      w.Status(),
+			ContentType: w.Header().Get("Content-Type"),
+			Body:        w.body.Bytes(),
+		}, r.conf.IdempotencyTTL)
+	}
+	if err != nil {
+		logger.Log("event", "idempotency_store_failed", "error", err)
+	}
+}
+
+// requestFingerprint identifies the request a key is used for.
+func requestFingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
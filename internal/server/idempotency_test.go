@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Neurostep/todo/pkg/idempotency"
+	"github.com/Neurostep/todo/pkg/services/todo"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	service := todo.New(todo.Config{Repository: todo.NewMemoryRepository(), Logger: log.NewNopLogger()})
+	store := idempotency.NewMemory()
+	r := &api{
+		conf:   Config{TodoService: service, Idempotency: store, IdempotencyTTL: time.Hour},
+		logger: log.NewNopLogger(),
+	}
+	router := r.routes()
+
+	post := func(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/todos", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(idempotencyKeyHeader, key)
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	body := `{"title":"buy milk","due_date":"2022-08-01"}`
+
+	first := post(router, "k1", body)
+	require.Equal(t, http.StatusCreated, first.Code)
+	require.Empty(t, first.Header().Get(idempotentReplayedHeader))
+
+	retry := post(router, "k1", body)
+	require.Equal(t, http.StatusCreated, retry.Code)
+	require.Equal(t, "true", retry.Header().Get(idempotentReplayedHeader))
+	require.JSONEq(t, first.Body.String(), retry.Body.String())
+
+	page, err := service.GetTodos(ctx, todo.PaginateTodos{})
+	require.NoError(t, err)
+	require.Equal(t, 1, page.TotalCount, "the retry created nothing")
+
+	rec := post(router, "k1", `{"title":"buy bread","due_date":"2022-08-01"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Equal(t, []string{"idempotency_key"}, errorLabels(t, rec))
+
+	// httptest requests come from 192.0.2.1
+	_, err = store.Start(ctx, "ip:192.0.2.1:k2", requestFingerprint(http.MethodPost, "/api/v1/todos", []byte(body)))
+	require.NoError(t, err)
+	rec = post(router, "k2", body)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	rec = post(router, "k2", `{"title":"buy bread","due_date":"2022-08-01"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, "a different request is rejected while the first is in progress")
+
+	rec = post(router, strings.Repeat("k", maxIdempotencyKeyLength+1), body)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, []string{idempotencyKeyHeader}, errorLabels(t, rec))
+
+	rec = post(router, "", body)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	page, err = service.GetTodos(ctx, todo.PaginateTodos{})
+	require.NoError(t, err)
+	require.Equal(t, 2, page.TotalCount)
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opencensus.io/trace"
+
+	"github.com/Neurostep/todo/pkg/services/todo"
+	"github.com/Neurostep/todo/pkg/tools/logging"
+)
+
+func (r *api) inviteMember(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "invite_member")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	req := requestBody(c).(*NewInvitation)
+
+	inv, err := r.conf.TodoService.Invite(ctx, todo.Invite{
+		ProjectId: pathID(c, "projectId"),
+		Username:  req.Username,
+		Role:      todo.Role(req.Role),
+		InvitedBy: c.GetString(usernameKey),
+	})
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("project.invitation", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitationResponse(inv))
+}
+
+func (r *api) getProjectInvitations(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "list_project_invitations")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	invitations, err := r.conf.TodoService.GetInvitations(ctx, pathID(c, "projectId"), "")
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("project.invitation", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, invitationsResponse(invitations))
+}
+
+func (r *api) revokeInvitation(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "revoke_invitation")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	inv, err := r.conf.TodoService.GetInvitation(ctx, pathID(c, "invitationId"))
+	if err == nil && inv.ProjectId != pathID(c, "projectId") {
+		err = todo.ErrNotFound
+	}
+	if err == nil {
+		err = r.conf.TodoService.DeleteInvitation(ctx, inv.ID)
+	}
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("project.invitation", err.Error()))
+		return
+	}
+
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+// getInvitations lists the invitations of the user.
+func (r *api) getInvitations(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "list_invitations")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	invitations, err := r.conf.TodoService.GetInvitations(ctx, 0, c.GetString(usernameKey))
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("invitation", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, invitationsResponse(invitations))
+}
+
+func (r *api) acceptInvitation(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "accept_invitation")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	inv, err := r.invitation(ctx, c)
+	var m *todo.Membership
+	if err == nil {
+		m, err = r.conf.TodoService.AcceptInvitation(ctx, inv.ID)
+	}
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("invitation", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, memberResponse(m))
+}
+
+func (r *api) declineInvitation(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "decline_invitation")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	inv, err := r.invitation(ctx, c)
+	if err == nil {
+		err = r.conf.TodoService.DeleteInvitation(ctx, inv.ID)
+	}
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("invitation", err.Error()))
+		return
+	}
+
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+// invitation returns the invitation of the id path parameter, the
+// invitations of other users are not found.
+func (r *api) invitation(ctx context.Context, c *gin.Context) (*todo.Invitation, error) {
+	inv, err := r.conf.TodoService.GetInvitation(ctx, pathID(c, "id"))
+	if err != nil {
+		return nil, err
+	}
+	if r.conf.AuthEnabled && inv.Username != c.GetString(usernameKey) {
+		return nil, todo.ErrNotFound
+	}
+	return inv, nil
+}
+
+func invitationResponse(inv *todo.Invitation) InvitationResponse {
+	return InvitationResponse{
+		ID:        inv.ID,
+		ProjectID: inv.ProjectId,
+		Username:  inv.Username,
+		Role:      string(inv.Role),
+		InvitedBy: inv.InvitedBy,
+	}
+}
+
+func invitationsResponse(invitations []todo.Invitation) []InvitationResponse {
+	response := make([]InvitationResponse, len(invitations))
+	for i, inv := range invitations {
+		response[i] = invitationResponse(&inv)
+	}
+	return response
+}
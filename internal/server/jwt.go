@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/pkg/errors"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"go.opencensus.io/trace"
@@ -13,7 +15,19 @@ import (
 	"github.com/Neurostep/todo/pkg/tools/logging"
 )
 
-var jwtKey = []byte("secret")
+// usernameKey holds the username of the authorized request in the gin context.
+const usernameKey = "todo.username"
+
+// legacyJWTKey signs the tokens when no key is configured, it is public and
+// must not be relied upon when the API requires authorization.
+var legacyJWTKey = JWTKey{Secret: []byte("secret")}
+
+// JWTKey is a key tokens are signed with. Its ID is the kid header of the
+// tokens, so they are verified with the key which signed them.
+type JWTKey struct {
+	ID     string
+	Secret []byte
+}
 
 var users = map[string]string{
 	"user": "password",
@@ -39,12 +53,7 @@ func (r *api) signin(c *gin.Context) {
 	defer span.End()
 	logger := logging.FromContext(ctx, r.logger)
 
-	var creds Credentials
-	if err := c.ShouldBindJSON(&creds); err != nil {
-		errs := extractBindErrors(err)
-		respondErrors(c, logger, http.StatusBadRequest, errs...)
-		return
-	}
+	creds := requestBody(c).(*Credentials)
 
 	expectedPassword, ok := users[creds.Username]
 
@@ -61,8 +70,7 @@ func (r *api) signin(c *gin.Context) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtKey)
+	tokenString, err := r.signToken(claims)
 	if err != nil {
 		respondErrors(c, logger, http.StatusInternalServerError, newError("signin", err.Error()))
 		return
@@ -85,13 +93,7 @@ func (r *api) refresh(c *gin.Context) {
 		return
 	}
 	claims := &Claims{}
-	tkn, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
-	if !tkn.Valid {
-		respondErrors(c, logger, http.StatusUnauthorized, newError("refresh", "not valid token"))
-		return
-	}
+	tkn, err := jwt.ParseWithClaims(tokenString, claims, r.verificationKey)
 	if err != nil {
 		if err == jwt.ErrSignatureInvalid {
 			respondErrors(c, logger, http.StatusUnauthorized, newError("refresh", err.Error()))
@@ -100,6 +102,10 @@ func (r *api) refresh(c *gin.Context) {
 		respondErrors(c, logger, http.StatusBadRequest, newError("refresh", err.Error()))
 		return
 	}
+	if !tkn.Valid {
+		respondErrors(c, logger, http.StatusUnauthorized, newError("refresh", "not valid token"))
+		return
+	}
 
 	if time.Unix(claims.ExpiresAt.Unix(), 0).Sub(time.Now()) > 30*time.Second {
 		respondErrors(c, logger, http.StatusBadRequest, newError("refresh", "too early refresh"))
@@ -108,8 +114,7 @@ func (r *api) refresh(c *gin.Context) {
 
 	expirationTime := time.Now().Add(5 * time.Minute)
 	claims.ExpiresAt = &jwt.NumericDate{Time: expirationTime}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenSignedString, err := token.SignedString(jwtKey)
+	tokenSignedString, err := r.signToken(claims)
 	if err != nil {
 		respondErrors(c, logger, http.StatusInternalServerError, newError("refresh", err.Error()))
 		return
@@ -121,7 +126,7 @@ func (r *api) refresh(c *gin.Context) {
 	})
 }
 
-func authMiddleware(c *gin.Context) {
+func (r *api) authenticate(c *gin.Context) {
 	tokenString := c.GetHeader("Authorization")
 	if tokenString == "" {
 		c.AbortWithStatus(http.StatusUnauthorized)
@@ -130,21 +135,51 @@ func authMiddleware(c *gin.Context) {
 
 	claims := &Claims{}
 
-	tkn, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
-	if err != nil {
-		if err == jwt.ErrSignatureInvalid {
-			c.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
-		c.AbortWithStatus(http.StatusBadRequest)
-		return
-	}
-	if !tkn.Valid {
+	tkn, err := jwt.ParseWithClaims(tokenString, claims, r.verificationKey)
+	// malformed, expired and forged tokens alike are not authorized
+	if err != nil || !tkn.Valid {
 		c.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
 
+	c.Set(usernameKey, claims.Username)
 	c.Next()
 }
+
+// SetJWTKeys replaces the keys of the tokens, the first one signs the new
+// tokens and the others still verify the tokens they signed, so keys can be
+// rotated. The legacy key is used when keys is empty.
+func (r *api) SetJWTKeys(keys []JWTKey) {
+	if len(keys) == 0 {
+		keys = []JWTKey{legacyJWTKey}
+	}
+	r.jwtKeys.Store(append([]JWTKey(nil), keys...))
+}
+
+func (r *api) signToken(claims *Claims) (string, error) {
+	key := r.jwtKeys.Load().([]JWTKey)[0]
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.Secret)
+}
+
+// verificationKey is the key of the kid of the token, tokens without one
+// were signed with the first key.
+func (r *api) verificationKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, errors.Errorf("unexpected signing method %q", token.Method.Alg())
+	}
+	keys := r.jwtKeys.Load().([]JWTKey)
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return keys[0].Secret, nil
+	}
+	for _, k := range keys {
+		if k.ID == kid {
+			return k.Secret, nil
+		}
+	}
+	return nil, errors.Errorf("unknown key %q", kid)
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Neurostep/todo/pkg/services/todo"
+)
+
+func TestJWTKeyRotation(t *testing.T) {
+	r := &api{
+		conf: Config{
+			AuthEnabled: true,
+			TodoService: todo.New(todo.Config{Repository: todo.NewMemoryRepository(), Logger: log.NewNopLogger()}),
+			JWTKeys:     []JWTKey{{ID: "2022-07", Secret: []byte("old secret")}},
+		},
+		logger: log.NewNopLogger(),
+	}
+	router := r.routes()
+
+	signin := func() string {
+		rec := serve(router, http.MethodPost, "/signin", `{"username":"user","password":"password"}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var res AuthResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res.Token
+	}
+	listTodos := func(token string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/todos", nil)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	old := signin()
+	require.Equal(t, http.StatusOK, listTodos(old))
+
+	r.SetJWTKeys([]JWTKey{{ID: "2022-08", Secret: []byte("new secret")}, {ID: "2022-07", Secret: []byte("old secret")}})
+	current := signin()
+	require.NotEqual(t, old, current)
+	require.Equal(t, http.StatusOK, listTodos(old), "tokens of the previous key are still verified")
+	require.Equal(t, http.StatusOK, listTodos(current))
+
+	r.SetJWTKeys([]JWTKey{{ID: "2022-08", Secret: []byte("new secret")}})
+	require.Equal(t, http.StatusUnauthorized, listTodos(old), "tokens of removed keys are rejected")
+	require.Equal(t, http.StatusOK, listTodos(current))
+}
+
+func TestSetCORS(t *testing.T) {
+	r := &api{
+		conf: Config{
+			TodoService: todo.New(todo.Config{Repository: todo.NewMemoryRepository(), Logger: log.NewNopLogger()}),
+			CORS:        CORSConfig{AllowedOrigins: []string{"https://todo.example.com"}},
+		},
+		logger: log.NewNopLogger(),
+	}
+	router := r.routes()
+
+	allowed := func(router *gin.Engine, origin string) string {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+		req.Header.Set("Origin", origin)
+		router.ServeHTTP(rec, req)
+		return rec.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	require.Equal(t, "https://todo.example.com", allowed(router, "https://todo.example.com"))
+	require.Empty(t, allowed(router, "https://app.example.com"))
+
+	r.SetCORS(CORSConfig{AllowedOrigins: []string{"https://app.example.com"}})
+	require.Empty(t, allowed(router, "https://todo.example.com"))
+	require.Equal(t, "https://app.example.com", allowed(router, "https://app.example.com"))
+}
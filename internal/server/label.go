@@ -2,7 +2,6 @@ package server
 
 import (
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"go.opencensus.io/trace"
@@ -16,33 +15,18 @@ func (r *api) addLabelToTodo(c *gin.Context) {
 	defer span.End()
 	logger := logging.FromContext(ctx, r.logger)
 
-	var req NewLabel
-	if err := c.ShouldBindJSON(&req); err != nil {
-		errs := extractBindErrors(err)
-		respondErrors(c, logger, http.StatusBadRequest, errs...)
-		return
-	}
+	req := requestBody(c).(*NewLabel)
 
-	idStr := c.Param("id")
-	if idStr == "" {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo.label", "id is empty"))
-		return
-	}
+	id := pathID(c, "id")
 
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo.label", "id is not numeric"))
-		return
-	}
-
-	label, err := r.conf.TodoService.AddLabel(c, r.conf.DB, todo.AddLabel{
-		TodoId: uint(id),
+	label, err := r.conf.TodoService.AddLabel(ctx, todo.AddLabel{
+		TodoId: id,
 		Color:  req.Color,
 		Text:   req.Text,
 	})
 
 	if err != nil {
-		respondErrors(c, logger, http.StatusInternalServerError, newError("todo.label", err.Error()))
+		respondErrors(c, logger, serviceErrorCode(err), newError("todo.label", err.Error()))
 		return
 	}
 
@@ -58,34 +42,14 @@ func (r *api) removeLabelFromTodo(c *gin.Context) {
 	defer span.End()
 	logger := logging.FromContext(ctx, r.logger)
 
-	idStr := c.Param("id")
-	if idStr == "" {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo.label", "id is empty"))
-		return
-	}
-
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo.label", "id is not numeric"))
-		return
-	}
-
-	labelIdStr := c.Param("labelId")
-	if labelIdStr == "" {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo.label", "labelId is empty"))
-		return
-	}
+	id := pathID(c, "id")
 
-	labelId, err := strconv.Atoi(labelIdStr)
-	if err != nil {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo.label", "labelId is not numeric"))
-		return
-	}
+	labelId := pathID(c, "labelId")
 
-	err = r.conf.TodoService.RemoveLabel(c, r.conf.DB, uint(id), uint(labelId))
+	err := r.conf.TodoService.RemoveLabel(ctx, id, labelId)
 
 	if err != nil {
-		respondErrors(c, logger, http.StatusInternalServerError, newError("todo.label", err.Error()))
+		respondErrors(c, logger, serviceErrorCode(err), newError("todo.label", err.Error()))
 		return
 	}
 
@@ -97,22 +61,12 @@ func (r *api) getLabels(c *gin.Context) {
 	defer span.End()
 	logger := logging.FromContext(ctx, r.logger)
 
-	idStr := c.Param("id")
-	if idStr == "" {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo.label", "id is empty"))
-		return
-	}
-
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo.label", "id is not numeric"))
-		return
-	}
+	id := pathID(c, "id")
 
-	labels, err := r.conf.TodoService.GetLabels(c, r.conf.DB, uint(id))
+	labels, err := r.conf.TodoService.GetLabels(ctx, id)
 
 	if err != nil {
-		respondErrors(c, logger, http.StatusInternalServerError, newError("todo.label", err.Error()))
+		respondErrors(c, logger, serviceErrorCode(err), newError("todo.label", err.Error()))
 		return
 	}
 
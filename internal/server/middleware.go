@@ -6,6 +6,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-kit/kit/log"
+	"go.opencensus.io/trace"
+
+	"github.com/Neurostep/todo/pkg/services/todo"
 )
 
 func requireContentType(logger log.Logger, contentType string) gin.HandlerFunc {
@@ -23,22 +26,37 @@ func requireContentType(logger log.Logger, contentType string) gin.HandlerFunc {
 	}
 }
 
-func CORS(c *gin.Context) {
-	origin := c.Request.Header.Get("Origin")
-	if len(origin) == 0 {
-		c.Header("Access-Control-Allow-Origin", "*")
-	} else {
-		c.Header("Access-Control-Allow-Origin", origin)
+// clientContext tags the request context with the client, so the storage
+// can give it read-your-writes consistency, see todo.WithClient.
+func clientContext(c *gin.Context) {
+	c.Request = c.Request.WithContext(todo.WithClient(c.Request.Context(), clientID(c)))
+	c.Next()
+}
+
+// userContext tags the request context with the authorized user, who makes
+// the changes the watchers of todos are told about, see todo.WithUser.
+func userContext(c *gin.Context) {
+	if username := c.GetString(usernameKey); username != "" {
+		c.Request = c.Request.WithContext(todo.WithUser(c.Request.Context(), username))
 	}
-	c.Header("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
-	c.Header("Access-Control-Allow-Headers", "origin, content-type, accept")
-	c.Header("Access-Control-Allow-Credentials", "true")
-	c.Header("Allow", "HEAD,GET,POST,PUT,PATCH,DELETE,OPTIONS")
-	c.Header("Access-Control-Max-Age", "86400") // 24 hours
+	c.Next()
+}
 
-	if c.Request.Method != "OPTIONS" {
-		c.Next()
-	} else {
-		c.AbortWithStatus(http.StatusNoContent)
+// clientID identifies the client by the authorized username, or by its
+// address when authorization is disabled.
+func clientID(c *gin.Context) string {
+	if username := c.GetString(usernameKey); username != "" {
+		return "user:" + username
 	}
+	return "ip:" + c.ClientIP()
 }
+
+// ignoreTraceParent starts a trace for every request, whatever the trace
+// context it carries.
+type ignoreTraceParent struct{}
+
+func (ignoreTraceParent) SpanContextFromRequest(*http.Request) (trace.SpanContext, bool) {
+	return trace.SpanContext{}, false
+}
+
+func (ignoreTraceParent) SpanContextToRequest(trace.SpanContext, *http.Request) {}
@@ -0,0 +1,412 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Neurostep/todo/pkg/types"
+)
+
+const (
+	openAPIVersion = "3.0.3"
+	apiVersion     = "0.0.1"
+
+	securitySchemeName = "Token"
+	schemaRefPrefix    = "#/components/schemas/"
+)
+
+type (
+	Schema struct {
+		Ref        string             `json:"$ref,omitempty"`
+		Type       string             `json:"type,omitempty"`
+		Format     string             `json:"format,omitempty"`
+		Properties map[string]*Schema `json:"properties,omitempty"`
+		Required   []string           `json:"required,omitempty"`
+		Items      *Schema            `json:"items,omitempty"`
+		Minimum    *float64           `json:"minimum,omitempty"`
+		Maximum    *float64           `json:"maximum,omitempty"`
+		MinLength  *int               `json:"minLength,omitempty"`
+		MaxLength  *int               `json:"maxLength,omitempty"`
+		Enum       []string           `json:"enum,omitempty"`
+	}
+
+	OpenAPI struct {
+		OpenAPI    string              `json:"openapi"`
+		Info       openAPIInfo         `json:"info"`
+		Paths      map[string]PathItem `json:"paths"`
+		Components openAPIComponents   `json:"components"`
+	}
+
+	PathItem map[string]*Operation
+
+	Operation struct {
+		OperationID string                `json:"operationId"`
+		Summary     string                `json:"summary,omitempty"`
+		Tags        []string              `json:"tags,omitempty"`
+		Parameters  []*Parameter          `json:"parameters,omitempty"`
+		RequestBody *RequestBody          `json:"requestBody,omitempty"`
+		Responses   map[string]*Response  `json:"responses"`
+		Security    []map[string][]string `json:"security,omitempty"`
+	}
+
+	Parameter struct {
+		Name     string  `json:"name"`
+		In       string  `json:"in"`
+		Required bool    `json:"required,omitempty"`
+		Schema   *Schema `json:"schema"`
+	}
+
+	RequestBody struct {
+		Required bool                  `json:"required"`
+		Content  map[string]*MediaType `json:"content"`
+	}
+
+	Response struct {
+		Description string                `json:"description"`
+		Content     map[string]*MediaType `json:"content,omitempty"`
+	}
+
+	MediaType struct {
+		Schema *Schema `json:"schema"`
+	}
+
+	openAPIInfo struct {
+		Title       string         `json:"title"`
+		Description string         `json:"description"`
+		Version     string         `json:"version"`
+		Contact     openAPIContact `json:"contact"`
+	}
+
+	openAPIContact struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+
+	openAPIComponents struct {
+		Schemas         map[string]*Schema         `json:"schemas"`
+		SecuritySchemes map[string]*securityScheme `json:"securitySchemes"`
+	}
+
+	securityScheme struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+		In   string `json:"in"`
+	}
+)
+
+var (
+	dueDateType = reflect.TypeOf(types.DueDate{})
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// NewOpenAPI builds the OpenAPI document describing the given route table.
+// Request and response schemas are derived from the Go types referenced by
+// the endpoints, including the constraints from their `binding` tags.
+func NewOpenAPI(endpoints []endpoint) *OpenAPI {
+	doc := &OpenAPI{
+		OpenAPI: openAPIVersion,
+		Info: openAPIInfo{
+			Title:       "Simple Todo Service",
+			Description: "Simple Todo Service",
+			Version:     apiVersion,
+			Contact: openAPIContact{
+				Name:  "Maksim Terekhin",
+				Email: "maksim@terekhin.me",
+			},
+		},
+		Paths: map[string]PathItem{},
+		Components: openAPIComponents{
+			Schemas: map[string]*Schema{},
+			SecuritySchemes: map[string]*securityScheme{
+				securitySchemeName: {Type: "apiKey", Name: "Authorization", In: "header"},
+			},
+		},
+	}
+
+	for _, e := range endpoints {
+		path := openAPIPath(e.path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = PathItem{}
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(e.method)] = doc.operation(e)
+	}
+
+	return doc
+}
+
+// Resolve follows a $ref to its component schema.
+func (doc *OpenAPI) Resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = doc.Components.Schemas[strings.TrimPrefix(s.Ref, schemaRefPrefix)]
+	}
+	return s
+}
+
+// Operation returns the operation registered for the gin-style path and
+// method, or nil if the document does not describe it.
+func (doc *OpenAPI) Operation(method, path string) *Operation {
+	return doc.Paths[openAPIPath(path)][strings.ToLower(method)]
+}
+
+func (doc *OpenAPI) operation(e endpoint) *Operation {
+	op := &Operation{
+		OperationID: e.id,
+		Summary:     e.summary,
+		Tags:        []string{e.tag},
+		Responses:   map[string]*Response{},
+	}
+
+	for _, name := range pathParams(e.path) {
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "integer", Format: "int64", Minimum: float(1)},
+		})
+	}
+
+	if e.query != nil {
+		op.Parameters = append(op.Parameters, doc.queryParams(reflect.TypeOf(e.query))...)
+	}
+
+	if e.idempotent {
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:   idempotencyKeyHeader,
+			In:     "header",
+			Schema: &Schema{Type: "string", MinLength: intp(1), MaxLength: intp(maxIdempotencyKeyLength)},
+		})
+	}
+
+	if e.body != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]*MediaType{
+				gin.MIMEJSON: {Schema: doc.schemaFor(reflect.TypeOf(e.body))},
+			},
+		}
+	}
+
+	for code, body := range e.responses {
+		res := &Response{Description: http.StatusText(code)}
+		if body != nil {
+			t := reflect.TypeOf(body)
+			mime := gin.MIMEJSON
+			if t.Kind() == reflect.String {
+				mime = gin.MIMEPlain
+			}
+			res.Content = map[string]*MediaType{mime: {Schema: doc.schemaFor(t)}}
+		}
+		op.Responses[strconv.Itoa(code)] = res
+	}
+
+	if e.idempotent {
+		// key reused by a request in progress and for a different request
+		for _, code := range []int{http.StatusConflict, http.StatusUnprocessableEntity} {
+			op.Responses[strconv.Itoa(code)] = &Response{
+				Description: http.StatusText(code),
+				Content:     map[string]*MediaType{gin.MIMEJSON: {Schema: doc.schemaFor(reflect.TypeOf(Errors{}))}},
+			}
+		}
+	}
+
+	if e.auth {
+		op.Security = []map[string][]string{{securitySchemeName: {}}}
+	}
+
+	return op
+}
+
+func (doc *OpenAPI) queryParams(t reflect.Type) []*Parameter {
+	var params []*Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("form"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		s, required := doc.fieldSchema(f)
+		params = append(params, &Parameter{
+			Name:     name,
+			In:       "query",
+			Required: required,
+			Schema:   s,
+		})
+	}
+	return params
+}
+
+// schemaFor returns the schema for t. Named structs are registered as
+// components and referenced, everything else is inlined.
+func (doc *OpenAPI) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case dueDateType:
+		return &Schema{Type: "string", Format: "date"}
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64", Minimum: float(0)}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32", Minimum: float(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: doc.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.Struct:
+		if _, ok := doc.Components.Schemas[t.Name()]; !ok {
+			// register before walking the fields so recursive types terminate
+			s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+			doc.Components.Schemas[t.Name()] = s
+			doc.structFields(t, s)
+		}
+		return &Schema{Ref: schemaRefPrefix + t.Name()}
+	}
+
+	return &Schema{}
+}
+
+func (doc *OpenAPI) structFields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if f.Anonymous && tag == "" {
+			doc.structFields(f.Type, s)
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "-" || f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop, required := doc.fieldSchema(f)
+		s.Properties[name] = prop
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+	sort.Strings(s.Required)
+}
+
+// fieldSchema returns the schema of a struct field with the constraints
+// of its `binding` tag applied, and whether the field is required.
+func (doc *OpenAPI) fieldSchema(f reflect.StructField) (*Schema, bool) {
+	s := doc.schemaFor(f.Type)
+	required := false
+
+	for _, rule := range strings.Split(f.Tag.Get("binding"), ",") {
+		name, value := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, value = rule[:i], rule[i+1:]
+		}
+		n, err := strconv.Atoi(value)
+		switch {
+		case name == "required":
+			required = true
+		case name == "oneof":
+			s.Enum = strings.Fields(value)
+		case err != nil:
+			continue
+		case (name == "max" || name == "lte") && s.Type == "string":
+			s.MaxLength = &n
+		case (name == "min" || name == "gte") && s.Type == "string":
+			s.MinLength = &n
+		case name == "max" || name == "lte":
+			s.Maximum = float(float64(n))
+		case name == "min" || name == "gte":
+			s.Minimum = float(float64(n))
+		}
+	}
+
+	return s, required
+}
+
+// openAPIPath converts a gin path (/todos/:id) into an OpenAPI one (/todos/{id}).
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func pathParams(path string) []string {
+	var params []string
+	for _, s := range strings.Split(path, "/") {
+		if strings.HasPrefix(s, ":") {
+			params = append(params, s[1:])
+		}
+	}
+	return params
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func intp(v int) *int {
+	return &v
+}
+
+func (r *api) openAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, gin.MIMEJSON, r.spec)
+}
+
+func (r *api) openAPIDocs(c *gin.Context) {
+	c.Data(http.StatusOK, gin.MIMEHTML, []byte(docsPage))
+}
+
+func mustMarshal(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Simple Todo Service</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@4/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@4/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = function () {
+      SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+    };
+  </script>
+</body>
+</html>
+`
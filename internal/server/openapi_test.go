@@ -0,0 +1,103 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+// undocumented are the routes intentionally left out of the OpenAPI document.
+var undocumented = map[string]bool{
+	"/openapi.json": true,
+	"/docs":         true,
+}
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	r := &api{conf: Config{AuthEnabled: true}, logger: log.NewNopLogger()}
+	router := r.routes()
+
+	var doc OpenAPI
+	require.NoError(t, json.Unmarshal(r.spec, &doc))
+
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		if undocumented[route.Path] {
+			continue
+		}
+		registered[route.Method+" "+openAPIPath(route.Path)] = true
+		require.NotNil(t, doc.Operation(route.Method, route.Path),
+			"route %s %s is not described in the OpenAPI document", route.Method, route.Path)
+	}
+
+	for path, item := range doc.Paths {
+		for method := range item {
+			require.True(t, registered[strings.ToUpper(method)+" "+path],
+				"operation %s %s has no registered route", method, path)
+		}
+	}
+}
+
+func TestOpenAPISchemas(t *testing.T) {
+	doc := NewOpenAPI((&api{}).endpoints())
+
+	auth := doc.Components.Schemas["AuthResponse"]
+	require.NotNil(t, auth)
+	require.Equal(t, "integer", auth.Properties["expires"].Type)
+	require.Equal(t, "string", auth.Properties["token"].Type)
+
+	// embedded structs are flattened
+	update := doc.Components.Schemas["UpdateTodo"]
+	require.NotNil(t, update)
+	require.Equal(t, "boolean", update.Properties["done"].Type)
+	require.Equal(t, "date", update.Properties["due_date"].Format)
+	require.Equal(t, "object", update.Properties["fields"].Type)
+	require.Equal(t, 2047, *update.Properties["title"].MaxLength)
+	require.Equal(t, 65535, *update.Properties["description"].MaxLength)
+
+	list := doc.Operation(http.MethodGet, "/api/v1/todos")
+	require.NotNil(t, list)
+	require.Len(t, list.Parameters, 8)
+	require.Equal(t, "limit", list.Parameters[0].Name)
+	require.Equal(t, float64(1000), *list.Parameters[0].Schema.Maximum)
+	require.Equal(t, "project_id", list.Parameters[2].Name)
+	require.Equal(t, []string{"P0", "P1", "P2", "P3", "P4"}, list.Parameters[4].Schema.Enum)
+
+	invitation := doc.Components.Schemas["NewInvitation"]
+	require.NotNil(t, invitation)
+	require.Equal(t, []string{"owner", "editor", "commenter", "viewer"}, invitation.Properties["role"].Enum)
+	require.Equal(t, []string{"role", "username"}, invitation.Required)
+
+	get := doc.Operation(http.MethodGet, "/api/v1/todos/:id")
+	require.NotNil(t, get)
+	require.Equal(t, "id", get.Parameters[0].Name)
+	require.Equal(t, "path", get.Parameters[0].In)
+	require.Equal(t, "render", get.Parameters[1].Name)
+	require.Equal(t, []string{"html"}, get.Parameters[1].Schema.Enum)
+	require.NotEmpty(t, get.Security)
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	r := &api{conf: Config{}, logger: log.NewNopLogger()}
+	router := r.routes()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	require.Equal(t, openAPIVersion, doc["openapi"])
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/docs", nil)
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "/openapi.json")
+}
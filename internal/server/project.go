@@ -0,0 +1,144 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opencensus.io/trace"
+
+	"github.com/Neurostep/todo/pkg/services/todo"
+	"github.com/Neurostep/todo/pkg/tools/logging"
+)
+
+func (r *api) createProject(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "create_project")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	req := requestBody(c).(*NewProject)
+
+	// the user creating the project owns it
+	p, err := r.conf.TodoService.CreateProject(ctx, todo.CreateProject{
+		Name:  req.Name,
+		Owner: c.GetString(usernameKey),
+	})
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("project", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, ProjectResponse{ID: p.ID, Name: p.Name})
+}
+
+func (r *api) getProjects(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "list_projects")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	projects, err := r.conf.TodoService.GetProjects(ctx, c.GetString(usernameKey))
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("project", err.Error()))
+		return
+	}
+
+	response := make([]ProjectResponse, len(projects))
+	for i, p := range projects {
+		response[i] = ProjectResponse{ID: p.ID, Name: p.Name}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (r *api) getProject(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "get_project")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	p, err := r.conf.TodoService.GetProject(ctx, pathID(c, "projectId"))
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("project", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, ProjectResponse{ID: p.ID, Name: p.Name})
+}
+
+func (r *api) updateProject(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "update_project")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	req := requestBody(c).(*NewProject)
+
+	p, err := r.conf.TodoService.UpdateProject(ctx, todo.UpdateProject{
+		Id:   pathID(c, "projectId"),
+		Name: req.Name,
+	})
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("project", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, ProjectResponse{ID: p.ID, Name: p.Name})
+}
+
+func (r *api) getMembers(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "list_members")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	members, err := r.conf.TodoService.GetMembers(ctx, pathID(c, "projectId"))
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("project.member", err.Error()))
+		return
+	}
+
+	response := make([]MemberResponse, len(members))
+	for i, m := range members {
+		response[i] = memberResponse(&m)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (r *api) updateMember(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "update_member")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	req := requestBody(c).(*UpdateMember)
+
+	m, err := r.conf.TodoService.UpdateMember(ctx, todo.UpdateMember{
+		ProjectId: pathID(c, "projectId"),
+		Id:        pathID(c, "memberId"),
+		Role:      todo.Role(req.Role),
+	})
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("project.member", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, memberResponse(m))
+}
+
+func (r *api) removeMember(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "remove_member")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	err := r.conf.TodoService.RemoveMember(ctx, pathID(c, "projectId"), pathID(c, "memberId"))
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("project.member", err.Error()))
+		return
+	}
+
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+func memberResponse(m *todo.Membership) MemberResponse {
+	return MemberResponse{
+		ID:       m.ID,
+		Username: m.Username,
+		Role:     string(m.Role),
+	}
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Neurostep/todo/pkg/tools/logging"
+)
+
+// Groups of routes sharing rate limits.
+const (
+	limitAPI  = "api"
+	limitAuth = "auth"
+)
+
+// rateLimit rejects the requests of clients exceeding the limit of the group
+// with 429. The state of the bucket is reported in the RateLimit-* headers
+// of the IETF draft, Retry-After tells rejected clients when to come back.
+func (r *api) rateLimit(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res := r.conf.RateLimiter.Allow(c.Request.Context(), group, clientID(c))
+		if res == nil {
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", ceilSeconds(res.Reset))
+
+		if !res.Allowed {
+			c.Header("Retry-After", ceilSeconds(res.RetryAfter))
+			logger := logging.FromContext(c.Request.Context(), r.logger)
+			respondErrors(c, logger, http.StatusTooManyRequests, newError("rate_limit", "too many requests, retry later"))
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
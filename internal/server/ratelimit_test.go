@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Neurostep/todo/pkg/ratelimit"
+	"github.com/Neurostep/todo/pkg/services/todo"
+)
+
+func TestRateLimit(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemory(), map[string]ratelimit.Limit{
+		limitAuth: {Requests: 2, Period: time.Minute},
+		limitAPI:  {Requests: 100, Period: time.Minute},
+	}, log.NewNopLogger())
+	r := &api{
+		conf: Config{
+			TodoService: todo.New(todo.Config{Repository: todo.NewMemoryRepository(), Logger: log.NewNopLogger()}),
+			RateLimiter: limiter,
+		},
+		logger: log.NewNopLogger(),
+	}
+	router := r.routes()
+
+	for i := 1; i >= 0; i-- {
+		rec := serve(router, http.MethodPost, "/signin", `{"username":"user","password":"wrong"}`)
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+		require.Equal(t, string(rune('0'+i)), rec.Header().Get("RateLimit-Remaining"))
+	}
+
+	rec := serve(router, http.MethodPost, "/signin", `{"username":"user","password":"password"}`)
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "30", rec.Header().Get("Retry-After"))
+	require.Equal(t, []string{"rate_limit"}, errorLabels(t, rec))
+
+	rec = serve(router, http.MethodGet, "/api/v1/todos", "")
+	require.Equal(t, http.StatusOK, rec.Code, "groups are limited separately")
+	require.Equal(t, "99", rec.Header().Get("RateLimit-Remaining"))
+
+	rec = serve(router, http.MethodGet, "/openapi.json", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Header().Get("RateLimit-Limit"), "the document is not limited")
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opencensus.io/trace"
+
+	"github.com/Neurostep/todo/pkg/services/todo"
+	"github.com/Neurostep/todo/pkg/tools/logging"
+)
+
+func (r *api) getReferences(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "get_references")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	refs, err := r.conf.TodoService.GetReferences(ctx, pathID(c, "id"))
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("todo.reference", err.Error()))
+		return
+	}
+
+	visible, err := r.visibleProjects(ctx, c)
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("todo.reference", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, ReferencesResponse{
+		Mentions:     refs.Mentions,
+		Todos:        todoReferences(refs.Todos, visible),
+		ReferencedBy: todoReferences(refs.ReferencedBy, visible),
+	})
+}
+
+// visibleProjects returns the projects whose todos the user may view, the
+// todos outside projects included, nil when all of them are.
+func (r *api) visibleProjects(ctx context.Context, c *gin.Context) (map[uint]bool, error) {
+	if !r.conf.AuthEnabled {
+		return nil, nil
+	}
+	projects, err := r.conf.TodoService.GetProjects(ctx, c.GetString(usernameKey))
+	if err != nil {
+		return nil, err
+	}
+	visible := map[uint]bool{0: true}
+	for _, p := range projects {
+		visible[p.ID] = true
+	}
+	return visible, nil
+}
+
+// todoReferences lists the todos of the visible projects, see
+// visibleProjects.
+func todoReferences(todos []todo.Todo, visible map[uint]bool) []TodoReference {
+	refs := []TodoReference{}
+	for _, td := range todos {
+		if visible == nil || visible[td.ProjectId] {
+			refs = append(refs, TodoReference{ID: td.ID, Title: td.Title})
+		}
+	}
+	return refs
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescriptionsAndReferences(t *testing.T) {
+	as := newAuthorizedAPI(t, "alice", "bob")
+
+	var project ProjectResponse
+	require.Equal(t, http.StatusCreated, as("alice", http.MethodPost, "/api/v1/projects", `{"name":"home"}`, &project))
+	var private, shared TodoResponse
+	require.Equal(t, http.StatusCreated, as("alice", http.MethodPost, "/api/v1/todos",
+		fmt.Sprintf(`{"title":"private","due_date":"2022-09-01","project_id":%d}`, project.ID), &private))
+	description := fmt.Sprintf("**Plan** with @bob, after #%d and #999 <script>alert(1)</script>", private.ID)
+	body, err := json.Marshal(map[string]string{"title": "shared", "due_date": "2022-09-01", "description": description})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, as("alice", http.MethodPost, "/api/v1/todos", string(body), &shared))
+	require.Equal(t, description, shared.Description)
+	require.Empty(t, shared.DescriptionHTML)
+
+	var res TodoResponse
+	todoPath := fmt.Sprintf("/api/v1/todos/%d", shared.ID)
+	require.Equal(t, http.StatusOK, as("bob", http.MethodGet, todoPath, "", &res))
+	require.Empty(t, res.DescriptionHTML, "rendered on request")
+	require.Equal(t, http.StatusOK, as("bob", http.MethodGet, todoPath+"?render=html", "", &res))
+	require.Contains(t, res.DescriptionHTML, "<strong>Plan</strong>")
+	require.NotContains(t, res.DescriptionHTML, "<script")
+	require.Equal(t, http.StatusBadRequest, as("bob", http.MethodGet, todoPath+"?render=pdf", "", nil))
+
+	var todos TodosResponse
+	require.Equal(t, http.StatusOK, as("bob", http.MethodGet, "/api/v1/todos?render=html", "", &todos))
+	require.Len(t, todos.Data, 1)
+	require.Equal(t, res.DescriptionHTML, todos.Data[0].DescriptionHTML)
+
+	var refs ReferencesResponse
+	refsPath := todoPath + "/references"
+	require.Equal(t, http.StatusOK, as("alice", http.MethodGet, refsPath, "", &refs))
+	require.Equal(t, ReferencesResponse{
+		Mentions:     []string{"bob"},
+		Todos:        []TodoReference{{ID: private.ID, Title: "private"}},
+		ReferencedBy: []TodoReference{},
+	}, refs, "todos which do not exist are left out")
+	require.Equal(t, http.StatusOK, as("bob", http.MethodGet, refsPath, "", &refs))
+	require.Empty(t, refs.Todos, "todos of projects of others are left out")
+
+	update := fmt.Sprintf(`{"title":"private","due_date":"2022-09-01","description":"blocks #%d"}`, shared.ID)
+	require.Equal(t, http.StatusOK, as("alice", http.MethodPut, fmt.Sprintf("/api/v1/todos/%d", private.ID), update, nil))
+	require.Equal(t, http.StatusOK, as("alice", http.MethodGet, refsPath, "", &refs))
+	require.Equal(t, []TodoReference{{ID: private.ID, Title: "private"}}, refs.ReferencedBy)
+	require.Equal(t, http.StatusOK, as("bob", http.MethodGet, refsPath, "", &refs))
+	require.Empty(t, refs.ReferencedBy)
+
+	require.Equal(t, http.StatusNoContent, as("alice", http.MethodDelete, fmt.Sprintf("/api/v1/todos/%d", private.ID), "", nil))
+	require.Equal(t, http.StatusOK, as("alice", http.MethodGet, refsPath, "", &refs))
+	require.Empty(t, refs.Todos)
+	require.Empty(t, refs.ReferencedBy)
+	require.Equal(t, http.StatusNotFound, as("alice", http.MethodGet, "/api/v1/todos/999/references", "", nil))
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Neurostep/todo/pkg/tools/logging"
+)
+
+const (
+	requestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+// requestID identifies the request by the X-Request-ID it carries, or by a
+// new one, so the entries of its log can be found. The id is returned in the
+// same header.
+func requestID(c *gin.Context) {
+	id := c.GetHeader(requestIDHeader)
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+	c.Header(requestIDHeader, id)
+	c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+	c.Next()
+}
+
+// validRequestID accepts ids of a safe alphabet only, they end up in logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':', r == '/', r == '+', r == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// logRequests logs a line per request once it is served, correlated with
+// its trace by logging.FromContext.
+func (r *api) logRequests(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	kv := []interface{}{
+		"event", "http_request",
+		"method", c.Request.Method,
+		"route", c.FullPath(),
+		"path", c.Request.URL.Path,
+		"status", c.Writer.Status(),
+		"duration", time.Since(start),
+		"bytes", c.Writer.Size(),
+		"client_ip", c.ClientIP(),
+		"user_agent", c.Request.UserAgent(),
+	}
+	if username := c.GetString(usernameKey); username != "" {
+		kv = append(kv, "user", username)
+	}
+	logging.FromContext(c.Request.Context(), r.logger).Log(kv...)
+}
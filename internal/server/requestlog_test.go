@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Neurostep/todo/pkg/services/todo"
+)
+
+func TestRequestLog(t *testing.T) {
+	var entries []map[string]interface{}
+	logger := log.LoggerFunc(func(kv ...interface{}) error {
+		entry := map[string]interface{}{}
+		for i := 0; i < len(kv); i += 2 {
+			entry[kv[i].(string)] = kv[i+1]
+		}
+		if entry["event"] == "http_request" {
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	r := &api{
+		conf:   Config{TodoService: todo.New(todo.Config{Repository: todo.NewMemoryRepository(), Logger: log.NewNopLogger()})},
+		logger: logger,
+	}
+	router := r.routes()
+
+	for id, propagated := range map[string]bool{"abc-123": true, "two words": false, "": false} {
+		entries = nil
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/todos/42", nil)
+		req.Header.Set(requestIDHeader, id)
+		router.ServeHTTP(rec, req)
+
+		returned := rec.Header().Get(requestIDHeader)
+		if propagated {
+			require.Equal(t, id, returned)
+		} else {
+			require.Len(t, returned, 32, "an id is generated")
+		}
+
+		require.Len(t, entries, 1)
+		require.Equal(t, returned, entries[0]["request_id"])
+		require.Equal(t, "/api/v1/todos/:id", entries[0]["route"])
+		require.Equal(t, "/api/v1/todos/42", entries[0]["path"])
+		require.Equal(t, http.StatusNotFound, entries[0]["status"])
+	}
+}
@@ -4,40 +4,115 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"contrib.go.opencensus.io/exporter/prometheus"
 	"github.com/gin-gonic/gin"
 	"github.com/go-kit/kit/log"
-	"github.com/jinzhu/gorm"
 	"go.opencensus.io/plugin/ochttp"
-	"go.opencensus.io/stats/view"
-	"go.opencensus.io/tag"
+	"go.opencensus.io/plugin/ochttp/propagation/tracecontext"
 
+	"github.com/Neurostep/todo/pkg/idempotency"
+	"github.com/Neurostep/todo/pkg/ratelimit"
 	"github.com/Neurostep/todo/pkg/services/todo"
+	"github.com/Neurostep/todo/pkg/tools/health"
 	"github.com/Neurostep/todo/pkg/tools/metrics"
 )
 
-const timeout = 2 // seconds
+const apiPrefix = "/api/v1"
+
+var (
+	// apiErrors are the error codes every /api/v1 endpoint may answer with.
+	apiErrors = []int{
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusServiceUnavailable,
+	}
+	// todoErrors are the ones of endpoints addressing a todo or a project,
+	// which the user may not be allowed to.
+	todoErrors = append(append([]int{}, apiErrors...), http.StatusForbidden, http.StatusNotFound)
+	// memberErrors are the ones of endpoints adding what a project or todo
+	// may not have twice, members, watchers or custom fields.
+	memberErrors = append(append([]int{}, apiErrors...), http.StatusForbidden, http.StatusNotFound, http.StatusConflict)
+)
 
 type (
 	Config struct {
 		Debug       bool
 		AuthEnabled bool
 		Port        int `validate:"required"`
-		TodoService *todo.Service
-		DB          *gorm.DB
+		TodoService todo.ServiceProvider
 		Logger      log.Logger
+		// TrustTraceParent continues the traces of the W3C traceparent
+		// header of requests, which start traces of their own otherwise
+		TrustTraceParent bool
 
-		PrometheusExporter *prometheus.Exporter
+		// ReadTimeout, WriteTimeout and IdleTimeout configure connections,
+		// see http.Server. WriteTimeout defaults to the longest request
+		// timeout and a second.
+		ReadTimeout  time.Duration
+		WriteTimeout time.Duration
+		IdleTimeout  time.Duration
+		// RequestTimeout is the deadline of requests, RouteTimeouts
+		// override it by endpoint id, such as listTodos
+		RequestTimeout time.Duration
+		RouteTimeouts  map[string]time.Duration
+
+		// CORS is the policy for cross-origin requests of browsers, see
+		// SetCORS
+		CORS CORSConfig
+		// JWTKeys sign and verify the tokens, see SetJWTKeys
+		JWTKeys []JWTKey
+		// RateLimiter limits the requests per route group, nothing is
+		// limited when nil
+		RateLimiter *ratelimit.Limiter
+		// Idempotency stores the responses of requests with an
+		// Idempotency-Key for IdempotencyTTL, the header is ignored when nil
+		Idempotency    idempotency.Store
+		IdempotencyTTL time.Duration
+		// Health is the registry served by the admin listener, readiness
+		// fails from the start of the shutdown
+		Health *health.Registry
+		// ShutdownDelay keeps serving after readiness started failing on
+		// shutdown, so load balancers stop routing first
+		ShutdownDelay time.Duration
 	}
 
 	api struct {
 		*http.Server
 		conf   Config
 		logger log.Logger
-		pe     *prometheus.Exporter
+		health *health.Registry
+		spec   []byte
+		// cors is the *cors policy and jwtKeys the []JWTKey in use, they
+		// are replaced while serving
+		cors    atomic.Value
+		jwtKeys atomic.Value
+	}
+
+	// endpoint is a single entry of the route table.
+	endpoint struct {
+		id      string
+		method  string
+		path    string
+		tag     string
+		summary string
+		// auth marks endpoints that expect the Authorization header
+		auth bool
+		// idempotent endpoints accept the Idempotency-Key header
+		idempotent bool
+		// permission is required in the project of the request, given by
+		// project, see authorize
+		permission permission
+		project    projectOf
+		// rateLimit is the group of rate limits applying, /api/v1 endpoints
+		// default to limitAPI
+		rateLimit string
+		query     interface{}
+		body      interface{}
+		responses map[int]interface{}
+		handler   gin.HandlerFunc
 	}
 )
 
@@ -50,99 +125,373 @@ func New(c Config) *api {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	r := &api{conf: c, logger: c.Logger}
+	r := &api{conf: c, logger: c.Logger, health: c.Health}
+	if r.health == nil {
+		r.health = health.New()
+	}
 	handler := &ochttp.Handler{
 		Handler: r.routes(),
 		FormatSpanName: func(req *http.Request) string {
 			return fmt.Sprintf("recv.todo.http:%s", req.URL.Path)
 		},
-		IsPublicEndpoint: true,
+		Propagation:      &tracecontext.HTTPFormat{},
+		IsPublicEndpoint: !c.TrustTraceParent,
+	}
+	if !c.TrustTraceParent {
+		// the parent would only be linked, which the OpenTelemetry bridge
+		// cannot do
+		handler.Propagation = ignoreTraceParent{}
 	}
 	r.Server = &http.Server{
 		Addr:    fmt.Sprintf(":%d", c.Port),
-		Handler: http.TimeoutHandler(handler, time.Second, ""),
+		Handler: handler,
 
-		ReadTimeout:  time.Second,
-		WriteTimeout: time.Second * 2,
+		ReadTimeout:  durationOr(c.ReadTimeout, defaultReadTimeout),
+		WriteTimeout: c.writeTimeout(),
+		IdleTimeout:  durationOr(c.IdleTimeout, defaultIdleTimeout),
 	}
 
 	return r
 }
 
+// endpoints is the route table of the service. It is the single source for
+// both the gin router and the OpenAPI document served at /openapi.json.
+func (r *api) endpoints() []endpoint {
+	return []endpoint{
+		{
+			id: "signin", method: http.MethodPost, path: "/signin", tag: "auth",
+			summary:   "Sign in by the pair username & password",
+			body:      Credentials{},
+			rateLimit: limitAuth,
+			responses: withErrors(http.StatusOK, AuthResponse{}, apiErrors...),
+			handler:   r.signin,
+		},
+		{
+			id: "refresh", method: http.MethodGet, path: "/refresh", tag: "auth", auth: true,
+			summary:   "Refresh authorization token",
+			rateLimit: limitAuth,
+			responses: withErrors(http.StatusOK, AuthResponse{}, apiErrors...),
+			handler:   r.refresh,
+		},
+		{
+			id: "listTodos", method: http.MethodGet, path: apiPrefix + "/todos", tag: "todos", auth: true,
+			summary:    "List todos",
+			query:      TodosQuery{},
+			responses:  withErrors(http.StatusOK, TodosResponse{}, todoErrors...),
+			permission: permView,
+			project:    r.queryProject,
+			handler:    r.getTodos,
+		},
+		{
+			id: "createTodo", method: http.MethodPost, path: apiPrefix + "/todos", tag: "todos", auth: true,
+			summary:    "Create a todo",
+			body:       NewTodo{},
+			responses:  withErrors(http.StatusCreated, TodoResponse{}, todoErrors...),
+			idempotent: true,
+			permission: permEdit,
+			project:    r.bodyProject,
+			handler:    r.createTodo,
+		},
+		{
+			id: "getTodo", method: http.MethodGet, path: apiPrefix + "/todos/:id", tag: "todos", auth: true,
+			summary:    "Get a todo by id",
+			query:      TodoQuery{},
+			responses:  withErrors(http.StatusOK, TodoResponse{}, todoErrors...),
+			permission: permView,
+			project:    r.todoProject,
+			handler:    r.getTodo,
+		},
+		{
+			id: "updateTodo", method: http.MethodPut, path: apiPrefix + "/todos/:id", tag: "todos", auth: true,
+			summary:    "Update a todo",
+			body:       UpdateTodo{},
+			responses:  withErrors(http.StatusOK, TodoResponse{}, todoErrors...),
+			permission: permEdit,
+			project:    r.todoProject,
+			handler:    r.updateTodo,
+		},
+		{
+			id: "deleteTodo", method: http.MethodDelete, path: apiPrefix + "/todos/:id", tag: "todos", auth: true,
+			summary:    "Delete a todo",
+			responses:  withErrors(http.StatusNoContent, nil, todoErrors...),
+			permission: permEdit,
+			project:    r.todoProject,
+			handler:    r.deleteTodo,
+		},
+		{
+			id: "addComment", method: http.MethodPost, path: apiPrefix + "/todos/:id/comments", tag: "comments", auth: true,
+			summary:    "Add a comment to a todo",
+			body:       NewComment{},
+			responses:  withErrors(http.StatusCreated, CommentResponse{}, todoErrors...),
+			idempotent: true,
+			permission: permComment,
+			project:    r.todoProject,
+			handler:    r.addCommentToTodo,
+		},
+		{
+			id: "listComments", method: http.MethodGet, path: apiPrefix + "/todos/:id/comments", tag: "comments", auth: true,
+			summary:    "List comments of a todo",
+			responses:  withErrors(http.StatusOK, []CommentResponse{}, todoErrors...),
+			permission: permView,
+			project:    r.todoProject,
+			handler:    r.getComments,
+		},
+		{
+			id: "removeComment", method: http.MethodDelete, path: apiPrefix + "/todos/:id/comments/:commentId", tag: "comments", auth: true,
+			summary:    "Remove a comment from a todo",
+			responses:  withErrors(http.StatusNoContent, nil, todoErrors...),
+			permission: permEdit,
+			project:    r.todoProject,
+			handler:    r.removeCommentFromTodo,
+		},
+		{
+			id: "addLabel", method: http.MethodPost, path: apiPrefix + "/todos/:id/labels", tag: "labels", auth: true,
+			summary:    "Add a label to a todo",
+			body:       NewLabel{},
+			responses:  withErrors(http.StatusCreated, LabelResponse{}, todoErrors...),
+			idempotent: true,
+			permission: permEdit,
+			project:    r.todoProject,
+			handler:    r.addLabelToTodo,
+		},
+		{
+			id: "listLabels", method: http.MethodGet, path: apiPrefix + "/todos/:id/labels", tag: "labels", auth: true,
+			summary:    "List labels of a todo",
+			responses:  withErrors(http.StatusOK, []LabelResponse{}, todoErrors...),
+			permission: permView,
+			project:    r.todoProject,
+			handler:    r.getLabels,
+		},
+		{
+			id: "removeLabel", method: http.MethodDelete, path: apiPrefix + "/todos/:id/labels/:labelId", tag: "labels", auth: true,
+			summary:    "Remove a label from a todo",
+			responses:  withErrors(http.StatusNoContent, nil, todoErrors...),
+			permission: permEdit,
+			project:    r.todoProject,
+			handler:    r.removeLabelFromTodo,
+		},
+		{
+			id: "addWatcher", method: http.MethodPost, path: apiPrefix + "/todos/:id/watchers", tag: "watchers", auth: true,
+			summary:    "Subscribe a user to the changes of a todo",
+			body:       NewWatcher{},
+			responses:  withErrors(http.StatusCreated, WatcherResponse{}, memberErrors...),
+			idempotent: true,
+			permission: permEdit,
+			project:    r.todoProject,
+			handler:    r.addWatcherToTodo,
+		},
+		{
+			id: "listWatchers", method: http.MethodGet, path: apiPrefix + "/todos/:id/watchers", tag: "watchers", auth: true,
+			summary:    "List watchers of a todo",
+			responses:  withErrors(http.StatusOK, []WatcherResponse{}, todoErrors...),
+			permission: permView,
+			project:    r.todoProject,
+			handler:    r.getWatchers,
+		},
+		{
+			id: "removeWatcher", method: http.MethodDelete, path: apiPrefix + "/todos/:id/watchers/:watcherId", tag: "watchers", auth: true,
+			summary:    "Unsubscribe a user from the changes of a todo",
+			responses:  withErrors(http.StatusNoContent, nil, todoErrors...),
+			permission: permEdit,
+			project:    r.todoProject,
+			handler:    r.removeWatcherFromTodo,
+		},
+		{
+			id: "listReferences", method: http.MethodGet, path: apiPrefix + "/todos/:id/references", tag: "todos", auth: true,
+			summary:    "List the references of the description of a todo and the todos referring to it",
+			responses:  withErrors(http.StatusOK, ReferencesResponse{}, todoErrors...),
+			permission: permView,
+			project:    r.todoProject,
+			handler:    r.getReferences,
+		},
+		{
+			id: "listProjects", method: http.MethodGet, path: apiPrefix + "/projects", tag: "projects", auth: true,
+			summary:   "List the projects of the user",
+			responses: withErrors(http.StatusOK, []ProjectResponse{}, apiErrors...),
+			handler:   r.getProjects,
+		},
+		{
+			id: "createProject", method: http.MethodPost, path: apiPrefix + "/projects", tag: "projects", auth: true,
+			summary:    "Create a project owned by the user",
+			body:       NewProject{},
+			responses:  withErrors(http.StatusCreated, ProjectResponse{}, apiErrors...),
+			idempotent: true,
+			handler:    r.createProject,
+		},
+		{
+			id: "getProject", method: http.MethodGet, path: apiPrefix + "/projects/:projectId", tag: "projects", auth: true,
+			summary:    "Get a project by id",
+			responses:  withErrors(http.StatusOK, ProjectResponse{}, todoErrors...),
+			permission: permView,
+			project:    r.pathProject,
+			handler:    r.getProject,
+		},
+		{
+			id: "updateProject", method: http.MethodPut, path: apiPrefix + "/projects/:projectId", tag: "projects", auth: true,
+			summary:    "Update a project",
+			body:       NewProject{},
+			responses:  withErrors(http.StatusOK, ProjectResponse{}, todoErrors...),
+			permission: permManage,
+			project:    r.pathProject,
+			handler:    r.updateProject,
+		},
+		{
+			id: "createField", method: http.MethodPost, path: apiPrefix + "/projects/:projectId/fields", tag: "fields", auth: true,
+			summary:    "Define a custom field of the todos of a project",
+			body:       NewField{},
+			responses:  withErrors(http.StatusCreated, FieldResponse{}, memberErrors...),
+			idempotent: true,
+			permission: permManage,
+			project:    r.pathProject,
+			handler:    r.createField,
+		},
+		{
+			id: "listFields", method: http.MethodGet, path: apiPrefix + "/projects/:projectId/fields", tag: "fields", auth: true,
+			summary:    "List the custom fields of a project",
+			responses:  withErrors(http.StatusOK, []FieldResponse{}, todoErrors...),
+			permission: permView,
+			project:    r.pathProject,
+			handler:    r.getFields,
+		},
+		{
+			id: "deleteField", method: http.MethodDelete, path: apiPrefix + "/projects/:projectId/fields/:fieldId", tag: "fields", auth: true,
+			summary:    "Delete a custom field of a project along with its values",
+			responses:  withErrors(http.StatusNoContent, nil, todoErrors...),
+			permission: permManage,
+			project:    r.pathProject,
+			handler:    r.deleteField,
+		},
+		{
+			id: "listMembers", method: http.MethodGet, path: apiPrefix + "/projects/:projectId/members", tag: "members", auth: true,
+			summary:    "List members of a project",
+			responses:  withErrors(http.StatusOK, []MemberResponse{}, todoErrors...),
+			permission: permView,
+			project:    r.pathProject,
+			handler:    r.getMembers,
+		},
+		{
+			id: "updateMember", method: http.MethodPut, path: apiPrefix + "/projects/:projectId/members/:memberId", tag: "members", auth: true,
+			summary:    "Change the role of a member",
+			body:       UpdateMember{},
+			responses:  withErrors(http.StatusOK, MemberResponse{}, memberErrors...),
+			permission: permManage,
+			project:    r.pathProject,
+			handler:    r.updateMember,
+		},
+		{
+			id: "removeMember", method: http.MethodDelete, path: apiPrefix + "/projects/:projectId/members/:memberId", tag: "members", auth: true,
+			summary:    "Remove a member from a project",
+			responses:  withErrors(http.StatusNoContent, nil, memberErrors...),
+			permission: permManage,
+			project:    r.pathProject,
+			handler:    r.removeMember,
+		},
+		{
+			id: "inviteMember", method: http.MethodPost, path: apiPrefix + "/projects/:projectId/invitations", tag: "invitations", auth: true,
+			summary:    "Invite a user to a project",
+			body:       NewInvitation{},
+			responses:  withErrors(http.StatusCreated, InvitationResponse{}, memberErrors...),
+			idempotent: true,
+			permission: permManage,
+			project:    r.pathProject,
+			handler:    r.inviteMember,
+		},
+		{
+			id: "listProjectInvitations", method: http.MethodGet, path: apiPrefix + "/projects/:projectId/invitations", tag: "invitations", auth: true,
+			summary:    "List pending invitations to a project",
+			responses:  withErrors(http.StatusOK, []InvitationResponse{}, todoErrors...),
+			permission: permManage,
+			project:    r.pathProject,
+			handler:    r.getProjectInvitations,
+		},
+		{
+			id: "revokeInvitation", method: http.MethodDelete, path: apiPrefix + "/projects/:projectId/invitations/:invitationId", tag: "invitations", auth: true,
+			summary:    "Revoke an invitation to a project",
+			responses:  withErrors(http.StatusNoContent, nil, todoErrors...),
+			permission: permManage,
+			project:    r.pathProject,
+			handler:    r.revokeInvitation,
+		},
+		{
+			id: "listInvitations", method: http.MethodGet, path: apiPrefix + "/invitations", tag: "invitations", auth: true,
+			summary:   "List the invitations of the user",
+			responses: withErrors(http.StatusOK, []InvitationResponse{}, apiErrors...),
+			handler:   r.getInvitations,
+		},
+		{
+			id: "acceptInvitation", method: http.MethodPost, path: apiPrefix + "/invitations/:id/accept", tag: "invitations", auth: true,
+			summary:   "Accept an invitation of the user, joining the project",
+			responses: withErrors(http.StatusCreated, MemberResponse{}, memberErrors...),
+			handler:   r.acceptInvitation,
+		},
+		{
+			id: "declineInvitation", method: http.MethodDelete, path: apiPrefix + "/invitations/:id", tag: "invitations", auth: true,
+			summary:   "Decline an invitation of the user",
+			responses: withErrors(http.StatusNoContent, nil, todoErrors...),
+			handler:   r.declineInvitation,
+		},
+	}
+}
+
 func (r *api) routes() *gin.Engine {
+	endpoints := r.endpoints()
+
+	r.SetCORS(r.conf.CORS)
+	r.SetJWTKeys(r.conf.JWTKeys)
+
 	router := gin.New()
-	router.Use(CORS)
+	router.Use(requestID, r.logRequests, r.handleCORS, r.timeouts(endpoints))
 	monitoredRouter := metrics.WrapGinRouter(router)
-	router.GET("/healthz", r.healthz)
-	router.GET("/readyz", r.readyz)
-
-	// auth endpoints
-	router.POST("/signin", r.signin)
-	router.GET("/refresh", r.refresh)
 
 	// API endpoints
 	var apiGroup *gin.RouterGroup
 	if r.conf.AuthEnabled {
-		apiGroup = router.Group("/api/v1", authMiddleware)
+		apiGroup = router.Group(apiPrefix, r.authenticate)
 	} else {
-		apiGroup = router.Group("/api/v1")
+		apiGroup = router.Group(apiPrefix)
 	}
 
 	monitoredAPIGroup := metrics.WrapGinRouter(apiGroup)
-	monitoredAPIGroup.Use(requireContentType(r.logger, "application/json"))
-
-	todosGroup := metrics.WrapGinRouter(apiGroup)
-	{
-		todosGroup.GET("/todos", r.getTodos)
-		todosGroup.POST("/todos", r.createTodo)
-		todosGroup.GET("/todos/:id", r.getTodo)
-		todosGroup.PUT("/todos/:id", r.updateTodo)
-		todosGroup.DELETE("/todos/:id", r.deleteTodo)
-
-		todoGroup := apiGroup.Group("todos/:id")
-		todoComments := metrics.WrapGinRouter(todoGroup)
-		{
-			todoComments.POST("comments", r.addCommentToTodo)
-			todoComments.GET("comments", r.getComments)
-			todoComments.DELETE("comments/:commentId", r.removeCommentFromTodo)
+	monitoredAPIGroup.Use(requireContentType(r.logger, "application/json"), clientContext, userContext)
+
+	doc := NewOpenAPI(endpoints)
+	for _, e := range endpoints {
+		handlers := []gin.HandlerFunc{validateRequest(doc, e, r.logger)}
+		if e.permission != "" {
+			handlers = append(handlers, r.authorize(e))
 		}
-		todoLabels := metrics.WrapGinRouter(todoGroup)
-		{
-			todoLabels.POST("labels", r.addLabelToTodo)
-			todoLabels.GET("labels", r.getLabels)
-			todoLabels.DELETE("labels/:labelId", r.removeLabelFromTodo)
+		if e.idempotent && r.conf.Idempotency != nil {
+			handlers = append(handlers, r.idempotent)
+		}
+		handlers = append(handlers, e.handler)
+		isAPI := strings.HasPrefix(e.path, apiPrefix+"/")
+		if e.rateLimit == "" && isAPI {
+			e.rateLimit = limitAPI
+		}
+		if e.rateLimit != "" && r.conf.RateLimiter != nil {
+			handlers = append([]gin.HandlerFunc{r.rateLimit(e.rateLimit)}, handlers...)
+		}
+		if isAPI {
+			monitoredAPIGroup.Handle(e.method, strings.TrimPrefix(e.path, apiPrefix), handlers...)
+		} else {
+			monitoredRouter.Handle(e.method, e.path, handlers...)
 		}
 	}
 
-	if r.conf.PrometheusExporter != nil && r.setupPrometheusMetrics() == nil {
-		monitoredRouter.GET("/metrics", gin.HandlerFunc(func(c *gin.Context) {
-			ochttp.SetRoute(c.Request.Context(), "/metrics")
-			r.conf.PrometheusExporter.ServeHTTP(c.Writer, c.Request)
-		}))
-	}
+	r.spec = mustMarshal(doc)
+	monitoredRouter.GET("/openapi.json", r.openAPISpec)
+	monitoredRouter.GET("/docs", r.openAPIDocs)
 	return router
 }
 
-func (r *api) setupPrometheusMetrics() error {
-	err := view.Register(
-		ochttp.ServerRequestCountView,
-		ochttp.ServerRequestBytesView,
-		ochttp.ServerResponseBytesView,
-		ochttp.ServerLatencyView,
-		ochttp.ServerRequestCountByMethod,
-		ochttp.ServerResponseCountByStatusCode,
-		&view.View{
-			Name:        "opencensus.io/http/server/route_latency",
-			Description: "Latency distribution",
-			TagKeys:     []tag.Key{ochttp.KeyServerRoute, ochttp.StatusCode},
-			Measure:     ochttp.ServerLatency,
-			Aggregation: ochttp.DefaultLatencyDistribution,
-		},
-	)
-	if err != nil {
-		r.logger.Log("event", "http_monitoring_view_register_failed", "error", err)
+// withErrors builds the responses of an endpoint: the successful one and
+// the error codes answered with the Errors payload.
+func withErrors(code int, body interface{}, errorCodes ...int) map[int]interface{} {
+	res := map[int]interface{}{code: body}
+	for _, c := range errorCodes {
+		res[c] = Errors{}
 	}
-	return err
+	return res
 }
 
 func (r *api) Start(ctx context.Context) error {
@@ -161,7 +510,12 @@ func (r *api) Start(ctx context.Context) error {
 		return err
 	case <-ctx.Done():
 		r.logger.Log("event", "shutting down...")
-		c, cancel := context.WithTimeout(context.Background(), time.Second*timeout)
+		r.health.Shutdown()
+		if r.conf.ShutdownDelay > 0 {
+			time.Sleep(r.conf.ShutdownDelay)
+		}
+		// requests in flight get as long as the longest may take
+		c, cancel := context.WithTimeout(context.Background(), r.conf.writeTimeout())
 		defer cancel()
 		err := r.Shutdown(c)
 
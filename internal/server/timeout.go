@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Neurostep/todo/pkg/tools/logging"
+)
+
+const (
+	defaultReadTimeout    = 5 * time.Second
+	defaultIdleTimeout    = 2 * time.Minute
+	defaultRequestTimeout = 5 * time.Second
+	// writeTimeoutMargin leaves handlers time to answer their timeouts
+	// before the connection is closed
+	writeTimeoutMargin = time.Second
+)
+
+func durationOr(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
+
+// longestTimeout is the longest time a request may be handled.
+func (c Config) longestTimeout() time.Duration {
+	longest := durationOr(c.RequestTimeout, defaultRequestTimeout)
+	for _, d := range c.RouteTimeouts {
+		if d > longest {
+			longest = d
+		}
+	}
+	return longest
+}
+
+// writeTimeout is the configured one or the longest request timeout with a
+// margin.
+func (c Config) writeTimeout() time.Duration {
+	return durationOr(c.WriteTimeout, c.longestTimeout()+writeTimeoutMargin)
+}
+
+// timeouts gives requests the deadline of their route, RequestTimeout
+// unless overridden by the id of the endpoint. The deadline is carried by
+// the request context down to the database, handlers failing past it answer
+// 503, see respondErrors. Responses are not buffered, so they can be
+// streamed.
+func (r *api) timeouts(endpoints []endpoint) gin.HandlerFunc {
+	byRoute := map[string]time.Duration{}
+	known := map[string]bool{}
+	for _, e := range endpoints {
+		known[e.id] = true
+		if d := r.conf.RouteTimeouts[e.id]; d > 0 {
+			byRoute[e.method+" "+e.path] = d
+		}
+	}
+	for id := range r.conf.RouteTimeouts {
+		if !known[id] {
+			r.logger.Log("event", "unknown_route_timeout", "route", id)
+		}
+	}
+	requestTimeout := durationOr(r.conf.RequestTimeout, defaultRequestTimeout)
+
+	return func(c *gin.Context) {
+		d, ok := byRoute[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			d = requestTimeout
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if !c.Writer.Written() && ctx.Err() == context.DeadlineExceeded {
+			respondErrors(c, logging.FromContext(ctx, r.logger), http.StatusServiceUnavailable)
+		}
+	}
+}
+
+// timedOut tells whether the deadline of the request passed.
+func timedOut(c *gin.Context) bool {
+	return c.Request.Context().Err() == context.DeadlineExceeded
+}
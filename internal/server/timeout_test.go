@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Neurostep/todo/pkg/services/todo"
+)
+
+// slowService takes delay to list todos, unless the context is done first.
+type slowService struct {
+	todo.ServiceProvider
+	delay time.Duration
+}
+
+func (s slowService) GetTodos(ctx context.Context, pg todo.PaginateTodos) (*todo.PaginatedTodos, error) {
+	select {
+	case <-time.After(s.delay):
+		return &todo.PaginatedTodos{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	newRouter := func(c Config) *gin.Engine {
+		c.TodoService = slowService{delay: 50 * time.Millisecond}
+		r := &api{conf: c, logger: log.NewNopLogger()}
+		return r.routes()
+	}
+
+	rec := serve(newRouter(Config{RequestTimeout: 10 * time.Millisecond}), http.MethodGet, "/api/v1/todos", "")
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Equal(t, []string{"timeout"}, errorLabels(t, rec))
+
+	rec = serve(newRouter(Config{
+		RequestTimeout: 10 * time.Millisecond,
+		RouteTimeouts:  map[string]time.Duration{"listTodos": time.Second},
+	}), http.MethodGet, "/api/v1/todos", "")
+	require.Equal(t, http.StatusOK, rec.Code, "the route overrides the timeout")
+
+	require.Equal(t, 6*time.Second, Config{}.writeTimeout())
+	require.Equal(t, 31*time.Second, Config{RouteTimeouts: map[string]time.Duration{"listTodos": 30 * time.Second}}.writeTimeout())
+	require.Equal(t, time.Second, Config{WriteTimeout: time.Second}.writeTimeout())
+}
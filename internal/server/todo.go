@@ -1,15 +1,17 @@
 package server
 
 import (
-	"github.com/Neurostep/todo/pkg/types"
+	"context"
 	"net/http"
-	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.opencensus.io/trace"
 
+	"github.com/Neurostep/todo/pkg/markdown"
 	"github.com/Neurostep/todo/pkg/services/todo"
 	"github.com/Neurostep/todo/pkg/tools/logging"
+	"github.com/Neurostep/todo/pkg/types"
 )
 
 func (r *api) getTodos(c *gin.Context) {
@@ -17,20 +19,34 @@ func (r *api) getTodos(c *gin.Context) {
 	defer span.End()
 	logger := logging.FromContext(ctx, r.logger)
 
-	query := TodosQuery{}
-	if err := c.ShouldBindQuery(&query); err != nil {
-		errs := extractBindErrors(err)
-		respondErrors(c, logger, http.StatusBadRequest, errs...)
+	query := requestQuery(c).(*TodosQuery)
+
+	if query.Assignee == "me" && c.GetString(usernameKey) == "" {
+		respondErrors(c, logger, http.StatusBadRequest, newError("validation", "assignee=me requires authorization"))
+		return
+	}
+
+	order, err := todo.ParseOrder(query.Sort)
+	if err != nil {
+		respondErrors(c, logger, http.StatusBadRequest, serviceError("sort", err))
 		return
 	}
 
-	results, err := r.conf.TodoService.GetTodos(ctx, r.conf.DB, todo.PaginateTodos{
-		Limit:  query.Limit,
-		Offset: query.Offset,
+	filter, err := r.todoFilter(ctx, c, query)
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), serviceError("todo", err))
+		return
+	}
+
+	results, err := r.conf.TodoService.GetTodos(ctx, todo.PaginateTodos{
+		TodoFilter: filter,
+		Order:      order,
+		Limit:      query.Limit,
+		Offset:     query.Offset,
 	})
 
 	if err != nil {
-		respondErrors(c, logger, http.StatusInternalServerError)
+		respondErrors(c, logger, serviceErrorCode(err), serviceError("todo", err))
 		return
 	}
 
@@ -41,45 +57,75 @@ func (r *api) getTodos(c *gin.Context) {
 	}
 
 	for _, e := range results.Items {
-		res.Data = append(res.Data, TodoResponse{
-			ID:      e.ID,
-			Title:   e.Title,
-			Done:    e.Done,
-			DueDate: e.DueDate.Format(types.DueDateFormat),
-		})
+		td := todoResponse(&e)
+		if err := renderTodo(&td, query.Render); err != nil {
+			respondErrors(c, logger, http.StatusInternalServerError, newError("todo.description", err.Error()))
+			return
+		}
+		res.Data = append(res.Data, td)
 	}
 	c.JSON(http.StatusOK, res)
 }
 
+// todoFilter selects the todos of the query. Todos of an assignee are
+// listed from the todos outside projects and the projects of the user,
+// which are all of them when the API does not require authorization.
+func (r *api) todoFilter(ctx context.Context, c *gin.Context, query *TodosQuery) (todo.TodoFilter, error) {
+	filter := todo.TodoFilter{Assignee: query.Assignee}
+	if query.Assignee == "me" {
+		filter.Assignee = c.GetString(usernameKey)
+	}
+	if query.Priority != "" {
+		p, err := todo.ParsePriority(query.Priority)
+		if err != nil {
+			return filter, err
+		}
+		filter.Priorities = []todo.Priority{p}
+	}
+	if query.Field != "" {
+		i := strings.Index(query.Field, ":")
+		if i < 0 {
+			return filter, &todo.ValidationError{Field: "field", Message: "must be name:value"}
+		}
+		filter.Fields = []todo.FieldValue{{Name: query.Field[:i], Value: query.Field[i+1:]}}
+	}
+
+	switch {
+	case query.ProjectID != 0:
+		filter.Projects = []uint{query.ProjectID}
+	case filter.Assignee != "":
+		projects, err := r.conf.TodoService.GetProjects(ctx, c.GetString(usernameKey))
+		if err != nil {
+			return filter, err
+		}
+		filter.Projects = []uint{0}
+		for _, p := range projects {
+			filter.Projects = append(filter.Projects, p.ID)
+		}
+	}
+	return filter, nil
+}
+
 func (r *api) getTodo(c *gin.Context) {
 	ctx, span := trace.StartSpan(c.Request.Context(), "get_todo")
 	defer span.End()
 	logger := logging.FromContext(ctx, r.logger)
 
-	idStr := c.Param("id")
-	if idStr == "" {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo", "id is empty"))
-		return
-	}
+	id := pathID(c, "id")
+	query := requestQuery(c).(*TodoQuery)
 
-	id, err := strconv.Atoi(idStr)
+	td, err := r.conf.TodoService.GetTodo(ctx, id)
 	if err != nil {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo", "id is not numeric"))
+		respondErrors(c, logger, serviceErrorCode(err), newError("todo", err.Error()))
 		return
 	}
 
-	td, err := r.conf.TodoService.GetTodo(ctx, r.conf.DB, uint(id))
-	if err != nil {
-		respondErrors(c, logger, http.StatusInternalServerError, newError("todo", err.Error()))
+	res := todoResponse(td)
+	if err := renderTodo(&res, query.Render); err != nil {
+		respondErrors(c, logger, http.StatusInternalServerError, newError("todo.description", err.Error()))
 		return
 	}
-
-	c.JSON(http.StatusOK, TodoResponse{
-		ID:      td.ID,
-		Title:   td.Title,
-		Done:    td.Done,
-		DueDate: td.DueDate.Format(types.DueDateFormat),
-	})
+	c.JSON(http.StatusOK, res)
 }
 
 func (r *api) createTodo(c *gin.Context) {
@@ -87,28 +133,35 @@ func (r *api) createTodo(c *gin.Context) {
 	defer span.End()
 	logger := logging.FromContext(ctx, r.logger)
 
-	var req NewTodo
-	if err := c.ShouldBindJSON(&req); err != nil {
-		errs := extractBindErrors(err)
-		respondErrors(c, logger, http.StatusBadRequest, errs...)
+	req := requestBody(c).(*NewTodo)
+
+	err := r.checkMember(ctx, c, req.Assignee)
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("todo.assignee", err.Error()))
+		return
+	}
+	priority, err := todoPriority(req.Priority)
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), serviceError("priority", err))
 		return
 	}
 
-	td, err := r.conf.TodoService.CreateTodo(ctx, r.conf.DB, &todo.CreateTodo{
-		Title:   req.Title,
-		DueDate: req.DueDate,
+	td, err := r.conf.TodoService.CreateTodo(ctx, &todo.CreateTodo{
+		Title:           req.Title,
+		Description:     req.Description,
+		DueDate:         req.DueDate,
+		ProjectId:       req.ProjectID,
+		Assignee:        req.Assignee,
+		Priority:        priority,
+		EstimateMinutes: req.EstimateMinutes,
+		Fields:          req.Fields,
 	})
 	if err != nil {
-		respondErrors(c, logger, http.StatusInternalServerError, newError("todo", err.Error()))
+		respondErrors(c, logger, serviceErrorCode(err), serviceError("todo", err))
 		return
 	}
 
-	c.JSON(http.StatusCreated, TodoResponse{
-		ID:      td.ID,
-		Title:   td.Title,
-		Done:    td.Done,
-		DueDate: td.DueDate.Format(types.DueDateFormat),
-	})
+	c.JSON(http.StatusCreated, todoResponse(td))
 }
 
 func (r *api) updateTodo(c *gin.Context) {
@@ -116,43 +169,38 @@ func (r *api) updateTodo(c *gin.Context) {
 	defer span.End()
 	logger := logging.FromContext(ctx, r.logger)
 
-	idStr := c.Param("id")
-	if idStr == "" {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo", "id is empty"))
-		return
-	}
+	id := pathID(c, "id")
 
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo", "id is not numeric"))
+	req := requestBody(c).(*UpdateTodo)
+
+	if err := r.checkMember(ctx, c, req.Assignee); err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("todo.assignee", err.Error()))
 		return
 	}
-
-	var req UpdateTodo
-	if err := c.ShouldBindJSON(&req); err != nil {
-		errs := extractBindErrors(err)
-		respondErrors(c, logger, http.StatusBadRequest, errs...)
+	priority, err := todoPriority(req.Priority)
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), serviceError("priority", err))
 		return
 	}
 
-	td, err := r.conf.TodoService.UpdateTodo(c, r.conf.DB, &todo.UpdateTodo{
-		Id:      uint(id),
-		Title:   req.Title,
-		DueDate: req.DueDate,
-		Done:    req.Done,
+	td, err := r.conf.TodoService.UpdateTodo(ctx, &todo.UpdateTodo{
+		Id:              id,
+		Title:           req.Title,
+		Description:     req.Description,
+		DueDate:         req.DueDate,
+		Done:            req.Done,
+		Assignee:        req.Assignee,
+		Priority:        priority,
+		EstimateMinutes: req.EstimateMinutes,
+		Fields:          req.Fields,
 	})
 
 	if err != nil {
-		respondErrors(c, logger, http.StatusInternalServerError, newError("todo", err.Error()))
+		respondErrors(c, logger, serviceErrorCode(err), serviceError("todo", err))
 		return
 	}
 
-	c.JSON(http.StatusOK, TodoResponse{
-		ID:      td.ID,
-		Title:   td.Title,
-		Done:    td.Done,
-		DueDate: td.DueDate.Format(types.DueDateFormat),
-	})
+	c.JSON(http.StatusOK, todoResponse(td))
 }
 
 func (r *api) deleteTodo(c *gin.Context) {
@@ -160,24 +208,55 @@ func (r *api) deleteTodo(c *gin.Context) {
 	defer span.End()
 	logger := logging.FromContext(ctx, r.logger)
 
-	idStr := c.Param("id")
-	if idStr == "" {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo", "id is empty"))
-		return
-	}
+	id := pathID(c, "id")
+
+	err := r.conf.TodoService.DeleteTodo(ctx, id)
 
-	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		respondErrors(c, logger, http.StatusBadRequest, newError("todo", "id is not numeric"))
+		respondErrors(c, logger, serviceErrorCode(err), newError("todo", err.Error()))
 		return
 	}
 
-	err = r.conf.TodoService.DeleteTodo(c, r.conf.DB, uint(id))
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
 
+func todoResponse(td *todo.Todo) TodoResponse {
+	return TodoResponse{
+		ID:              td.ID,
+		Title:           td.Title,
+		Description:     td.Description,
+		Done:            td.Done,
+		DueDate:         td.DueDate.Format(types.DueDateFormat),
+		ProjectID:       td.ProjectId,
+		Assignee:        td.Assignee,
+		Priority:        td.Priority.String(),
+		EstimateMinutes: td.EstimateMinutes,
+		Fields:          td.Fields,
+	}
+}
+
+// renderTodo renders the description of the todo to sanitized HTML when
+// render is html.
+func renderTodo(res *TodoResponse, render string) error {
+	if render != "html" || res.Description == "" {
+		return nil
+	}
+	html, err := markdown.HTML(res.Description)
 	if err != nil {
-		respondErrors(c, logger, http.StatusInternalServerError, newError("todo", err.Error()))
-		return
+		return err
 	}
+	res.DescriptionHTML = html
+	return nil
+}
 
-	c.Writer.WriteHeader(http.StatusNoContent)
+// todoPriority parses the priority of a request, nil when it is omitted.
+func todoPriority(s string) (*todo.Priority, error) {
+	if s == "" {
+		return nil, nil
+	}
+	p, err := todo.ParsePriority(s)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
 }
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/trace"
+
+	"github.com/Neurostep/todo/pkg/services/todo"
+)
+
+type spanRecorder []*trace.SpanData
+
+func (r *spanRecorder) ExportSpan(s *trace.SpanData) { *r = append(*r, s) }
+
+func TestTraceParent(t *testing.T) {
+	spans := &spanRecorder{}
+	trace.RegisterExporter(spans)
+	defer trace.UnregisterExporter(spans)
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+	defer trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(1e-4)})
+
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	for trust, continued := range map[bool]bool{true: true, false: false} {
+		*spans = nil
+		srv := New(Config{Port: 9000, Logger: log.NewNopLogger(), TrustTraceParent: trust})
+
+		req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+		req.Header.Set("traceparent", traceParent)
+		srv.Handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		require.Len(t, *spans, 1)
+		traceID := (*spans)[0].TraceID.String()
+		require.Equal(t, continued, traceID == "4bf92f3577b34da6a3ce929d0e0e4736", "trust %v", trust)
+	}
+}
+
+func TestAuthorizeSpans(t *testing.T) {
+	spans := &spanRecorder{}
+	trace.RegisterExporter(spans)
+	defer trace.UnregisterExporter(spans)
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+	defer trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(1e-4)})
+
+	service := todo.New(todo.Config{Repository: todo.NewMemoryRepository(), Logger: log.NewNopLogger()})
+	td, err := service.CreateTodo(context.Background(), &todo.CreateTodo{Title: "groceries"})
+	require.NoError(t, err)
+	r := &api{conf: Config{TodoService: service}, logger: log.NewNopLogger()}
+	router := r.routes()
+
+	*spans = nil
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/todos/%d", td.ID), nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var authorize *trace.SpanData
+	for _, s := range *spans {
+		if s.Name == "authorize" {
+			authorize = s
+		}
+	}
+	require.NotNil(t, authorize)
+	lookups := 0
+	for _, s := range *spans {
+		if s.Name == "todo.get" && s.ParentSpanID == authorize.SpanID {
+			lookups++
+		}
+	}
+	require.Equal(t, 1, lookups, "the project of the todo is looked up under the authorize span")
+}
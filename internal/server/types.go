@@ -4,14 +4,36 @@ import (
 	"github.com/Neurostep/todo/pkg/types"
 )
 
+// The `binding` tags below are not evaluated by gin: they feed the constraints
+// of the OpenAPI document, which validateRequest enforces.
 type (
+	// TodoFields are the fields of todos set on creation and on updates.
+	TodoFields struct {
+		Title string `json:"title" binding:"max=2047"`
+		// Description is the Markdown description of the todo, @username
+		// mentions users and #id refers to todos
+		Description string        `json:"description,omitempty" binding:"max=65535"`
+		DueDate     types.DueDate `json:"due_date"`
+		// Assignee is the username of the user the todo is assigned to,
+		// a member of its project.
+		Assignee string `json:"assignee,omitempty" binding:"max=255"`
+		// Priority defaults to P2
+		Priority        string `json:"priority,omitempty" binding:"omitempty,oneof=P0 P1 P2 P3 P4"`
+		EstimateMinutes uint   `json:"estimate_minutes,omitempty"`
+		// Fields are the values of the custom fields of the project by
+		// their names
+		Fields map[string]interface{} `json:"fields,omitempty"`
+	}
+
+	// NewTodo creates a todo in the project, outside projects when it is
+	// omitted.
 	NewTodo struct {
-		Title   string        `json:"title" binding:"max=2047"`
-		DueDate types.DueDate `json:"due_date"`
+		TodoFields
+		ProjectID uint `json:"project_id,omitempty"`
 	}
 
 	UpdateTodo struct {
-		NewTodo
+		TodoFields
 		Done bool `json:"done"`
 	}
 
@@ -35,11 +57,55 @@ type (
 		Color string `json:"color"`
 	}
 
+	NewField struct {
+		Name     string   `json:"name" binding:"required,min=1,max=63"`
+		Type     string   `json:"type" binding:"required,oneof=text number enum date"`
+		Options  []string `json:"options,omitempty"`
+		Required bool     `json:"required"`
+	}
+
+	FieldResponse struct {
+		ID       uint     `json:"id"`
+		Name     string   `json:"name"`
+		Type     string   `json:"type"`
+		Options  []string `json:"options,omitempty"`
+		Required bool     `json:"required"`
+	}
+
+	NewWatcher struct {
+		Username string `json:"username" binding:"required,min=1,max=255"`
+	}
+
+	WatcherResponse struct {
+		ID       uint   `json:"id"`
+		Username string `json:"username"`
+	}
+
+	// ReferencesResponse lists the users and todos the description of a
+	// todo refers to, and the todos referring to it.
+	ReferencesResponse struct {
+		Mentions     []string        `json:"mentions"`
+		Todos        []TodoReference `json:"todos"`
+		ReferencedBy []TodoReference `json:"referenced_by"`
+	}
+
+	TodoReference struct {
+		ID    uint   `json:"id"`
+		Title string `json:"title"`
+	}
+
 	TodoResponse struct {
-		ID      uint   `json:"id"`
-		Title   string `json:"title"`
-		DueDate string `json:"due_date"`
-		Done    bool   `json:"done"`
+		ID              uint                   `json:"id"`
+		Title           string                 `json:"title"`
+		Description     string                 `json:"description,omitempty"`
+		DescriptionHTML string                 `json:"description_html,omitempty"`
+		DueDate         string                 `json:"due_date"`
+		Done            bool                   `json:"done"`
+		ProjectID       uint                   `json:"project_id,omitempty"`
+		Assignee        string                 `json:"assignee,omitempty"`
+		Priority        string                 `json:"priority"`
+		EstimateMinutes uint                   `json:"estimate_minutes,omitempty"`
+		Fields          map[string]interface{} `json:"fields,omitempty"`
 	}
 
 	TodosResponse struct {
@@ -48,8 +114,56 @@ type (
 		Data       []TodoResponse `json:"data"`
 	}
 
+	// TodosQuery lists the todos of the project, the todos outside
+	// projects when ProjectID is omitted. Todos assigned to Assignee are
+	// listed from all the projects of the user, "me" standing for the user.
+	// Field selects the todos of the project by the value of a custom field,
+	// as name:value, see todo.ParseOrder for Sort. Render=html renders the
+	// descriptions of the todos, as does it for a single todo in TodoQuery.
 	TodosQuery struct {
-		Limit  uint32 `form:"limit" binding:"lte=1000"`
-		Offset uint32 `form:"offset"`
+		Limit     uint32 `form:"limit" binding:"lte=1000"`
+		Offset    uint32 `form:"offset"`
+		ProjectID uint   `form:"project_id"`
+		Assignee  string `form:"assignee" binding:"max=255"`
+		Priority  string `form:"priority" binding:"omitempty,oneof=P0 P1 P2 P3 P4"`
+		Field     string `form:"field" binding:"max=2047"`
+		Sort      string `form:"sort" binding:"max=255"`
+		Render    string `form:"render" binding:"omitempty,oneof=html"`
+	}
+
+	TodoQuery struct {
+		Render string `form:"render" binding:"omitempty,oneof=html"`
+	}
+
+	NewProject struct {
+		Name string `json:"name" binding:"required,min=1,max=255"`
+	}
+
+	ProjectResponse struct {
+		ID   uint   `json:"id"`
+		Name string `json:"name"`
+	}
+
+	MemberResponse struct {
+		ID       uint   `json:"id"`
+		Username string `json:"username"`
+		Role     string `json:"role"`
+	}
+
+	UpdateMember struct {
+		Role string `json:"role" binding:"required,oneof=owner editor commenter viewer"`
+	}
+
+	NewInvitation struct {
+		Username string `json:"username" binding:"required,min=1,max=255"`
+		Role     string `json:"role" binding:"required,oneof=owner editor commenter viewer"`
+	}
+
+	InvitationResponse struct {
+		ID        uint   `json:"id"`
+		ProjectID uint   `json:"project_id"`
+		Username  string `json:"username"`
+		Role      string `json:"role"`
+		InvitedBy string `json:"invited_by"`
 	}
 )
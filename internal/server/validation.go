@@ -0,0 +1,284 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-kit/kit/log"
+
+	"github.com/Neurostep/todo/pkg/tools/logging"
+	"github.com/Neurostep/todo/pkg/types"
+)
+
+const (
+	requestBodyKey  = "todo.request.body"
+	requestQueryKey = "todo.request.query"
+	pathParamPrefix = "todo.request.param."
+)
+
+// validateRequest checks path parameters, query parameters and the JSON body
+// of a request against the operation from the OpenAPI document before the
+// handler runs. Parsed values are stored in the gin context, see pathID,
+// requestBody and requestQuery.
+func validateRequest(doc *OpenAPI, e endpoint, logger log.Logger) gin.HandlerFunc {
+	op := doc.Operation(e.method, e.path)
+
+	return func(c *gin.Context) {
+		logger := logging.FromContext(c.Request.Context(), logger)
+		var errs []*Error
+
+		query := c.Request.URL.Query()
+		for _, p := range op.Parameters {
+			var (
+				value string
+				ok    bool
+			)
+			switch p.In {
+			case "path":
+				value = c.Param(p.Name)
+				ok = value != ""
+			case "query":
+				value = query.Get(p.Name)
+				ok = value != ""
+			case "header":
+				value = c.GetHeader(p.Name)
+				ok = value != ""
+			}
+
+			if !ok {
+				if p.Required {
+					errs = append(errs, newError(p.Name, "is required"))
+				}
+				continue
+			}
+
+			v, err := parseParam(p.Schema, value)
+			if err == nil {
+				err = validateValue(doc, p.Schema, v, p.Name, &errs)
+			}
+			if err != nil {
+				errs = append(errs, newError(p.Name, err.Error()))
+				continue
+			}
+			if p.In == "path" {
+				if n, ok := v.(json.Number); ok {
+					id, _ := strconv.ParseUint(n.String(), 10, 64)
+					c.Set(pathParamPrefix+p.Name, uint(id))
+				}
+			}
+		}
+
+		if e.query != nil && len(errs) == 0 {
+			q := reflect.New(reflect.TypeOf(e.query))
+			if err := binding.MapFormWithTag(q.Interface(), query, "form"); err != nil {
+				errs = append(errs, newError("query", err.Error()))
+			} else {
+				c.Set(requestQueryKey, q.Interface())
+			}
+		}
+
+		if op.RequestBody != nil && len(errs) == 0 {
+			errs = append(errs, bindBody(c, doc, op.RequestBody, reflect.TypeOf(e.body))...)
+		}
+
+		if len(errs) != 0 {
+			respondErrors(c, logger, http.StatusBadRequest, errs...)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// bindBody validates the JSON body against the schema and decodes it into a
+// new value of type t.
+func bindBody(c *gin.Context, doc *OpenAPI, rb *RequestBody, t reflect.Type) []*Error {
+	raw, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return []*Error{newError("body", err.Error())}
+	}
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(raw))
+
+	if len(bytes.TrimSpace(raw)) == 0 {
+		if rb.Required {
+			return []*Error{newError("body", "request body is required")}
+		}
+		return nil
+	}
+
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return []*Error{newError("bind_error", err.Error())}
+	}
+
+	var errs []*Error
+	if err := validateValue(doc, rb.Content[gin.MIMEJSON].Schema, v, "", &errs); err != nil {
+		errs = append(errs, newError("body", err.Error()))
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+
+	body := reflect.New(t)
+	if err := json.Unmarshal(raw, body.Interface()); err != nil {
+		return []*Error{newError("bind_error", err.Error())}
+	}
+	c.Set(requestBodyKey, body.Interface())
+
+	return nil
+}
+
+// validateValue checks a decoded JSON value against the schema. Violations
+// of nested properties are appended to errs labeled with their path, a
+// violation of the value itself is returned.
+func validateValue(doc *OpenAPI, s *Schema, v interface{}, path string, errs *[]*Error) error {
+	s = doc.Resolve(s)
+	if s == nil || v == nil {
+		return nil
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("must be an object")
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				*errs = append(*errs, newError(join(path, name), "is required"))
+			}
+		}
+		for name, prop := range s.Properties {
+			value, ok := obj[name]
+			if !ok {
+				continue
+			}
+			if err := validateValue(doc, prop, value, join(path, name), errs); err != nil {
+				*errs = append(*errs, newError(join(path, name), err.Error()))
+			}
+		}
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("must be an array")
+		}
+		for i, item := range items {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			if err := validateValue(doc, s.Items, item, p, errs); err != nil {
+				*errs = append(*errs, newError(p, err.Error()))
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("must be a string")
+		}
+		if s.MaxLength != nil && len([]rune(str)) > *s.MaxLength {
+			return fmt.Errorf("must be at most %d characters long", *s.MaxLength)
+		}
+		if s.MinLength != nil && len([]rune(str)) < *s.MinLength {
+			return fmt.Errorf("must be at least %d characters long", *s.MinLength)
+		}
+		if len(s.Enum) != 0 && !contains(s.Enum, str) {
+			return fmt.Errorf("must be one of %s", strings.Join(s.Enum, ", "))
+		}
+		switch s.Format {
+		case "date":
+			if _, err := time.Parse(types.DueDateFormat, str); err != nil {
+				return fmt.Errorf("must be a date in %s format", types.DueDateFormat)
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				return fmt.Errorf("must be a date-time in RFC 3339 format")
+			}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("must be a boolean")
+		}
+	case "integer", "number":
+		n, ok := v.(json.Number)
+		if !ok {
+			return fmt.Errorf("must be a %s", s.Type)
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return fmt.Errorf("must be a %s", s.Type)
+		}
+		if s.Type == "integer" {
+			if _, err := n.Int64(); err != nil {
+				return fmt.Errorf("must be an integer")
+			}
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			return fmt.Errorf("must be greater than or equal to %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			return fmt.Errorf("must be less than or equal to %v", *s.Maximum)
+		}
+	}
+
+	return nil
+}
+
+// parseParam converts a raw path or query value into the representation
+// validateValue expects for the schema type.
+func parseParam(s *Schema, value string) (interface{}, error) {
+	switch s.Type {
+	case "integer", "number":
+		n := json.Number(value)
+		if _, err := n.Float64(); err != nil {
+			return nil, fmt.Errorf("must be a %s", s.Type)
+		}
+		return n, nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("must be a boolean")
+		}
+		return b, nil
+	}
+	return value, nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return strings.Join([]string{path, name}, ".")
+}
+
+// pathID returns the numeric path parameter validated by validateRequest.
+func pathID(c *gin.Context, name string) uint {
+	return c.GetUint(pathParamPrefix + name)
+}
+
+// requestBody returns the JSON body decoded by validateRequest.
+func requestBody(c *gin.Context) interface{} {
+	return c.MustGet(requestBodyKey)
+}
+
+// requestQuery returns the query parameters decoded by validateRequest.
+func requestQuery(c *gin.Context) interface{} {
+	return c.MustGet(requestQueryKey)
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func newValidatedRouter(e endpoint) *gin.Engine {
+	router := gin.New()
+	router.Handle(e.method, e.path, validateRequest(NewOpenAPI([]endpoint{e}), e, log.NewNopLogger()), e.handler)
+	return router
+}
+
+func serve(router *gin.Engine, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func errorLabels(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	var errs Errors
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errs))
+	labels := []string{}
+	for _, e := range errs.Errors {
+		labels = append(labels, e.Label)
+	}
+	return labels
+}
+
+func TestValidatePathParams(t *testing.T) {
+	var id, commentID uint
+	router := newValidatedRouter(endpoint{
+		method: http.MethodDelete, path: "/todos/:id/comments/:commentId",
+		handler: func(c *gin.Context) {
+			id, commentID = pathID(c, "id"), pathID(c, "commentId")
+			c.Status(http.StatusNoContent)
+		},
+	})
+
+	rec := serve(router, http.MethodDelete, "/todos/abc/comments/0", "")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.ElementsMatch(t, []string{"id", "commentId"}, errorLabels(t, rec))
+
+	rec = serve(router, http.MethodDelete, "/todos/12/comments/3", "")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, uint(12), id)
+	require.Equal(t, uint(3), commentID)
+}
+
+func TestValidateQuery(t *testing.T) {
+	var query *TodosQuery
+	router := newValidatedRouter(endpoint{
+		method: http.MethodGet, path: "/todos", query: TodosQuery{},
+		handler: func(c *gin.Context) {
+			query = requestQuery(c).(*TodosQuery)
+			c.Status(http.StatusOK)
+		},
+	})
+
+	rec := serve(router, http.MethodGet, "/todos?limit=1001&offset=-1", "")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.ElementsMatch(t, []string{"limit", "offset"}, errorLabels(t, rec))
+
+	rec = serve(router, http.MethodGet, "/todos?limit=10&offset=5", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, uint32(10), query.Limit)
+	require.Equal(t, uint32(5), query.Offset)
+}
+
+func TestValidateBody(t *testing.T) {
+	var req *UpdateTodo
+	router := newValidatedRouter(endpoint{
+		method: http.MethodPut, path: "/todos/:id", body: UpdateTodo{},
+		handler: func(c *gin.Context) {
+			req = requestBody(c).(*UpdateTodo)
+			c.Status(http.StatusOK)
+		},
+	})
+
+	rec := serve(router, http.MethodPut, "/todos/1", "")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, []string{"body"}, errorLabels(t, rec))
+
+	rec = serve(router, http.MethodPut, "/todos/1", "{")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, []string{"bind_error"}, errorLabels(t, rec))
+
+	body := `{"title":"` + strings.Repeat("a", 2048) + `","due_date":"tomorrow","done":"yes"}`
+	rec = serve(router, http.MethodPut, "/todos/1", body)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.ElementsMatch(t, []string{"title", "due_date", "done"}, errorLabels(t, rec))
+
+	rec = serve(router, http.MethodPut, "/todos/1", `{"title":"write tests","due_date":"2022-08-01","done":true}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "write tests", req.Title)
+	require.True(t, req.Done)
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opencensus.io/trace"
+
+	"github.com/Neurostep/todo/pkg/services/todo"
+	"github.com/Neurostep/todo/pkg/tools/logging"
+)
+
+func (r *api) addWatcherToTodo(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "add_watcher")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	req := requestBody(c).(*NewWatcher)
+
+	err := r.checkMember(ctx, c, req.Username)
+	var w *todo.Watcher
+	if err == nil {
+		w, err = r.conf.TodoService.AddWatcher(ctx, todo.AddWatcher{
+			TodoId:   pathID(c, "id"),
+			Username: req.Username,
+		})
+	}
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("todo.watcher", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, WatcherResponse{ID: w.ID, Username: w.Username})
+}
+
+func (r *api) removeWatcherFromTodo(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "remove_watcher")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	err := r.conf.TodoService.RemoveWatcher(ctx, pathID(c, "id"), pathID(c, "watcherId"))
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("todo.watcher", err.Error()))
+		return
+	}
+
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+func (r *api) getWatchers(c *gin.Context) {
+	ctx, span := trace.StartSpan(c.Request.Context(), "get_watchers")
+	defer span.End()
+	logger := logging.FromContext(ctx, r.logger)
+
+	watchers, err := r.conf.TodoService.GetWatchers(ctx, pathID(c, "id"))
+	if err != nil {
+		respondErrors(c, logger, serviceErrorCode(err), newError("todo.watcher", err.Error()))
+		return
+	}
+
+	response := make([]WatcherResponse, len(watchers))
+	for i, w := range watchers {
+		response[i] = WatcherResponse{ID: w.ID, Username: w.Username}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
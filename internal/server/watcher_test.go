@@ -0,0 +1,53 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssigneesAndWatchers(t *testing.T) {
+	as := newAuthorizedAPI(t, "alice", "bob")
+
+	var project ProjectResponse
+	require.Equal(t, http.StatusCreated, as("alice", http.MethodPost, "/api/v1/projects", `{"name":"home"}`, &project))
+	require.Equal(t, http.StatusBadRequest, as("alice", http.MethodPost, "/api/v1/todos",
+		fmt.Sprintf(`{"title":"groceries","due_date":"2022-09-01","project_id":%d,"assignee":"bob"}`, project.ID), nil),
+		"assignees are members")
+
+	var inProject, shared TodoResponse
+	require.Equal(t, http.StatusCreated, as("alice", http.MethodPost, "/api/v1/todos",
+		fmt.Sprintf(`{"title":"groceries","due_date":"2022-09-01","project_id":%d,"assignee":"alice"}`, project.ID), &inProject))
+	require.Equal(t, "alice", inProject.Assignee)
+	require.Equal(t, http.StatusCreated, as("alice", http.MethodPost, "/api/v1/todos",
+		`{"title":"shared","due_date":"2022-09-01","assignee":"alice"}`, &shared))
+	require.Equal(t, http.StatusCreated, as("alice", http.MethodPost, "/api/v1/todos", `{"title":"unassigned","due_date":"2022-09-01"}`, nil))
+
+	var todos TodosResponse
+	require.Equal(t, http.StatusOK, as("alice", http.MethodGet, "/api/v1/todos?assignee=me", "", &todos))
+	require.Equal(t, []TodoResponse{inProject, shared}, todos.Data, "across the projects of the user")
+	todos = TodosResponse{}
+	require.Equal(t, http.StatusOK, as("bob", http.MethodGet, "/api/v1/todos?assignee=alice", "", &todos))
+	require.Equal(t, []TodoResponse{shared}, todos.Data, "projects of others are left out")
+	require.Equal(t, http.StatusOK, as("bob", http.MethodGet, "/api/v1/todos?assignee=me", "", &todos))
+	require.Empty(t, todos.Data)
+
+	watchersPath := fmt.Sprintf("/api/v1/todos/%d/watchers", shared.ID)
+	require.Equal(t, http.StatusCreated, as("bob", http.MethodPost, fmt.Sprintf("/api/v1/todos/%d/comments", shared.ID), `{"text":"milk"}`, nil))
+	var watchers []WatcherResponse
+	require.Equal(t, http.StatusOK, as("alice", http.MethodGet, watchersPath, "", &watchers))
+	require.Len(t, watchers, 2)
+	require.Equal(t, "alice", watchers[0].Username, "assignees watch")
+	require.Equal(t, "bob", watchers[1].Username, "commenters watch")
+
+	require.Equal(t, http.StatusConflict, as("alice", http.MethodPost, watchersPath, `{"username":"bob"}`, nil))
+	var carol WatcherResponse
+	require.Equal(t, http.StatusCreated, as("alice", http.MethodPost, watchersPath, `{"username":"carol"}`, &carol))
+	require.Equal(t, http.StatusNoContent, as("alice", http.MethodDelete, fmt.Sprintf("%s/%d", watchersPath, carol.ID), "", nil))
+	require.Equal(t, http.StatusNotFound, as("alice", http.MethodDelete, fmt.Sprintf("%s/%d", watchersPath, carol.ID), "", nil))
+
+	require.Equal(t, http.StatusBadRequest, as("alice", http.MethodPost, fmt.Sprintf("/api/v1/todos/%d/watchers", inProject.ID), `{"username":"bob"}`, nil),
+		"watchers are members")
+}
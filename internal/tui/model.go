@@ -0,0 +1,473 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Neurostep/todo/pkg/client"
+	"github.com/Neurostep/todo/pkg/types"
+)
+
+const (
+	listView view = iota
+	detailView
+)
+
+const (
+	filterAll doneFilter = iota
+	filterPending
+	filterDone
+)
+
+const (
+	reverseVideo = "\x1b[7m"
+	bold         = "\x1b[1m"
+	resetStyle   = "\x1b[0m"
+)
+
+type (
+	view       int
+	doneFilter int
+
+	// input is the line editor shown at the bottom of the screen.
+	input struct {
+		prompt string
+		value  []rune
+		submit func(value string) error
+	}
+
+	model struct {
+		ctx context.Context
+		api API
+
+		todos  []client.Todo
+		labels map[uint][]client.Label
+
+		view        view
+		cursor      int
+		offset      int
+		doneFilter  doneFilter
+		labelFilter string
+
+		comments []client.Comment
+
+		input  *input
+		status string
+		quit   bool
+	}
+)
+
+func (f doneFilter) String() string {
+	switch f {
+	case filterPending:
+		return "pending"
+	case filterDone:
+		return "done"
+	}
+	return "all"
+}
+
+func newModel(ctx context.Context, api API) *model {
+	return &model{
+		ctx:    ctx,
+		api:    api,
+		labels: map[uint][]client.Label{},
+	}
+}
+
+// reload fetches all todos from the server.
+func (m *model) reload() {
+	todos := []client.Todo{}
+	opts := client.ListTodosOptions{Limit: 100}
+	for {
+		page, err := m.api.ListTodos(m.ctx, opts)
+		if err != nil {
+			m.status = err.Error()
+			return
+		}
+		todos = append(todos, page.Data...)
+		if !page.HasMore || len(page.Data) == 0 {
+			break
+		}
+		opts.Offset += uint32(len(page.Data))
+	}
+
+	m.todos = todos
+	m.labels = map[uint][]client.Label{}
+	if m.labelFilter != "" {
+		m.loadLabels()
+	}
+	m.status = fmt.Sprintf("loaded %d todos", len(todos))
+	m.clampCursor()
+}
+
+func (m *model) loadLabels() {
+	for _, td := range m.todos {
+		if _, ok := m.labels[td.ID]; ok {
+			continue
+		}
+		labels, err := m.api.ListLabels(m.ctx, td.ID)
+		if err != nil {
+			m.status = err.Error()
+			return
+		}
+		m.labels[td.ID] = labels
+	}
+}
+
+// visible returns the todos passing the filters.
+func (m *model) visible() []client.Todo {
+	res := []client.Todo{}
+	for _, td := range m.todos {
+		if m.doneFilter == filterPending && td.Done || m.doneFilter == filterDone && !td.Done {
+			continue
+		}
+		if m.labelFilter != "" && !m.hasLabel(td.ID) {
+			continue
+		}
+		res = append(res, td)
+	}
+	return res
+}
+
+func (m *model) hasLabel(id uint) bool {
+	for _, l := range m.labels[id] {
+		if strings.EqualFold(l.Text, m.labelFilter) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *model) selected() (client.Todo, bool) {
+	todos := m.visible()
+	if m.cursor < 0 || m.cursor >= len(todos) {
+		return client.Todo{}, false
+	}
+	return todos[m.cursor], true
+}
+
+func (m *model) clampCursor() {
+	n := len(m.visible())
+	if m.cursor >= n {
+		m.cursor = n - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+func (m *model) handleKey(k key) {
+	if k.name == keyCtrlC {
+		m.quit = true
+		return
+	}
+	if m.input != nil {
+		m.handleInput(k)
+		return
+	}
+	if m.view == detailView {
+		m.handleDetailKey(k)
+		return
+	}
+
+	switch {
+	case k.name == keyUp || k.r == 'k':
+		m.cursor--
+	case k.name == keyDown || k.r == 'j':
+		m.cursor++
+	case k.name == keyPgUp:
+		m.cursor -= 10
+	case k.name == keyPgDown:
+		m.cursor += 10
+	case k.name == keyHome || k.r == 'g':
+		m.cursor = 0
+	case k.name == keyEnd || k.r == 'G':
+		m.cursor = len(m.visible()) - 1
+	case k.name == keyEnter:
+		m.openDetail()
+	case k.r == 'q':
+		m.quit = true
+	case k.r == 'r':
+		m.reload()
+	case k.r == 'f':
+		m.doneFilter = (m.doneFilter + 1) % 3
+		m.cursor = 0
+	case k.r == 'l':
+		m.ask("filter by label (empty clears): ", m.labelFilter, func(v string) error {
+			m.labelFilter = strings.TrimSpace(v)
+			if m.labelFilter != "" {
+				m.loadLabels()
+			}
+			m.cursor = 0
+			return nil
+		})
+	case k.r == ' ' || k.r == 'x':
+		if td, ok := m.selected(); ok {
+			m.update(td, func(u *client.UpdateTodo) error {
+				u.Done = !u.Done
+				return nil
+			})
+		}
+	case k.r == 'e':
+		if td, ok := m.selected(); ok {
+			m.ask("title: ", td.Title, func(v string) error {
+				return m.update(td, func(u *client.UpdateTodo) error {
+					u.Title = v
+					return nil
+				})
+			})
+		}
+	case k.r == 'd':
+		if td, ok := m.selected(); ok {
+			m.ask("due date ("+types.DueDateFormat+"): ", formatDate(td.DueDate), func(v string) error {
+				return m.update(td, func(u *client.UpdateTodo) error {
+					d, err := time.Parse(types.DueDateFormat, strings.TrimSpace(v))
+					if err != nil {
+						return fmt.Errorf("invalid date %q", v)
+					}
+					u.DueDate = types.DueDate(d)
+					return nil
+				})
+			})
+		}
+	case k.r == 'n':
+		m.ask("new todo: ", "", func(v string) error {
+			td, err := m.api.CreateTodo(m.ctx, client.NewTodo{Title: v, DueDate: types.DueDate(time.Now())})
+			if err != nil {
+				return err
+			}
+			m.todos = append(m.todos, *td)
+			m.status = fmt.Sprintf("created #%d", td.ID)
+			return nil
+		})
+	}
+	m.clampCursor()
+}
+
+func (m *model) handleDetailKey(k key) {
+	switch {
+	case k.name == keyEsc || k.r == 'q' || k.name == keyBackspace:
+		m.view = listView
+		m.comments = nil
+	case k.r == 'c':
+		td, ok := m.selected()
+		if !ok {
+			return
+		}
+		m.ask("comment: ", "", func(v string) error {
+			cmnt, err := m.api.AddComment(m.ctx, td.ID, v)
+			if err != nil {
+				return err
+			}
+			m.comments = append(m.comments, *cmnt)
+			return nil
+		})
+	}
+}
+
+func (m *model) handleInput(k key) {
+	in := m.input
+	switch {
+	case k.name == keyEsc:
+		m.input = nil
+	case k.name == keyEnter:
+		m.input = nil
+		if err := in.submit(string(in.value)); err != nil {
+			m.status = err.Error()
+		}
+		m.clampCursor()
+	case k.name == keyBackspace:
+		if len(in.value) > 0 {
+			in.value = in.value[:len(in.value)-1]
+		}
+	case k.name == "":
+		in.value = append(in.value, k.r)
+	}
+}
+
+func (m *model) ask(prompt, value string, submit func(string) error) {
+	m.input = &input{prompt: prompt, value: []rune(value), submit: submit}
+}
+
+// update sends the todo with the change applied and replaces it in the list.
+func (m *model) update(td client.Todo, change func(u *client.UpdateTodo) error) error {
+	u := client.UpdateTodo{
+		Title:           td.Title,
+		Description:     td.Description,
+		DueDate:         td.DueDate,
+		Done:            td.Done,
+		Assignee:        td.Assignee,
+		Priority:        td.Priority,
+		EstimateMinutes: td.EstimateMinutes,
+		Fields:          td.Fields,
+	}
+	if err := change(&u); err != nil {
+		return err
+	}
+
+	updated, err := m.api.UpdateTodo(m.ctx, td.ID, u)
+	if err != nil {
+		m.status = err.Error()
+		return err
+	}
+	for i := range m.todos {
+		if m.todos[i].ID == updated.ID {
+			m.todos[i] = *updated
+		}
+	}
+	m.status = fmt.Sprintf("updated #%d", updated.ID)
+	return nil
+}
+
+func (m *model) openDetail() {
+	td, ok := m.selected()
+	if !ok {
+		return
+	}
+	comments, err := m.api.ListComments(m.ctx, td.ID)
+	if err != nil {
+		m.status = err.Error()
+		return
+	}
+	labels, err := m.api.ListLabels(m.ctx, td.ID)
+	if err != nil {
+		m.status = err.Error()
+		return
+	}
+	m.labels[td.ID] = labels
+	m.comments = comments
+	m.view = detailView
+}
+
+// render draws the whole screen. Lines are separated by \r\n as the terminal
+// is in raw mode.
+func (m *model) render(width, height int) string {
+	var lines []string
+	if m.view == detailView {
+		lines = m.renderDetail()
+	} else {
+		lines = m.renderList(height - 2)
+	}
+
+	for len(lines) < height-2 {
+		lines = append(lines, "")
+	}
+	lines = lines[:height-2]
+
+	var footer string
+	switch {
+	case m.input != nil:
+		footer = m.input.prompt + string(m.input.value) + "_"
+	case m.view == detailView:
+		footer = "c comment  esc back  ctrl+c quit"
+	default:
+		footer = "j/k move  enter open  space done  e title  d due  n new  f done filter  l label filter  r reload  q quit"
+	}
+
+	for i := range lines {
+		lines[i] = truncate(lines[i], width)
+	}
+	lines = append(lines, truncate(m.status, width), truncate(footer, width))
+
+	return strings.Join(lines, "\r\n")
+}
+
+func (m *model) renderList(rows int) []string {
+	header := fmt.Sprintf("%sTodos%s  filter: %s", bold, resetStyle, m.doneFilter)
+	if m.labelFilter != "" {
+		header += fmt.Sprintf(", label: %s", m.labelFilter)
+	}
+	lines := []string{header, ""}
+
+	todos := m.visible()
+	rows -= len(lines)
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if rows > 0 && m.cursor >= m.offset+rows {
+		m.offset = m.cursor - rows + 1
+	}
+
+	for i := m.offset; i < len(todos) && i < m.offset+rows; i++ {
+		td := todos[i]
+		done := " "
+		if td.Done {
+			done = "x"
+		}
+		line := fmt.Sprintf("[%s] %5d  %s  %s", done, td.ID, formatDate(td.DueDate), td.Title)
+		if i == m.cursor {
+			line = reverseVideo + line + resetStyle
+		}
+		lines = append(lines, line)
+	}
+	if len(todos) == 0 {
+		lines = append(lines, "no todos")
+	}
+
+	return lines
+}
+
+func (m *model) renderDetail() []string {
+	td, ok := m.selected()
+	if !ok {
+		return nil
+	}
+
+	status := "pending"
+	if td.Done {
+		status = "done"
+	}
+	lines := []string{
+		fmt.Sprintf("%s#%d %s%s", bold, td.ID, td.Title, resetStyle),
+		fmt.Sprintf("due %s, %s", formatDate(td.DueDate), status),
+	}
+
+	labels := make([]string, 0, len(m.labels[td.ID]))
+	for _, l := range m.labels[td.ID] {
+		labels = append(labels, l.Text)
+	}
+	if len(labels) != 0 {
+		lines = append(lines, "labels: "+strings.Join(labels, ", "))
+	}
+
+	lines = append(lines, "", bold+"Comments"+resetStyle)
+	for _, c := range m.comments {
+		lines = append(lines, "  - "+c.Text)
+	}
+	if len(m.comments) == 0 {
+		lines = append(lines, "  no comments")
+	}
+
+	return lines
+}
+
+func formatDate(d types.DueDate) string {
+	return time.Time(d).Format(types.DueDateFormat)
+}
+
+// truncate cuts s to width visible runes, ignoring escape sequences.
+func truncate(s string, width int) string {
+	var b strings.Builder
+	visible, inEscape := 0, false
+	for _, r := range s {
+		switch {
+		case r == '\x1b':
+			inEscape = true
+		case inEscape:
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+				inEscape = false
+			}
+		default:
+			if visible == width {
+				b.WriteString(resetStyle)
+				return b.String()
+			}
+			visible++
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,161 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Neurostep/todo/pkg/client"
+	"github.com/Neurostep/todo/pkg/types"
+)
+
+type fakeAPI struct {
+	todos    []client.Todo
+	comments map[uint][]client.Comment
+	labels   map[uint][]client.Label
+	updates  []client.UpdateTodo
+}
+
+func (f *fakeAPI) ListTodos(_ context.Context, opts client.ListTodosOptions) (*client.TodoPage, error) {
+	end := int(opts.Offset + opts.Limit)
+	if end > len(f.todos) {
+		end = len(f.todos)
+	}
+	return &client.TodoPage{
+		HasMore:    end < len(f.todos),
+		TotalCount: len(f.todos),
+		Data:       f.todos[opts.Offset:end],
+	}, nil
+}
+
+func (f *fakeAPI) CreateTodo(_ context.Context, t client.NewTodo) (*client.Todo, error) {
+	td := client.Todo{ID: uint(len(f.todos) + 1), Title: t.Title, DueDate: t.DueDate}
+	f.todos = append(f.todos, td)
+	return &td, nil
+}
+
+func (f *fakeAPI) UpdateTodo(_ context.Context, id uint, t client.UpdateTodo) (*client.Todo, error) {
+	f.updates = append(f.updates, t)
+	return &client.Todo{ID: id, Title: t.Title, DueDate: t.DueDate, Done: t.Done}, nil
+}
+
+func (f *fakeAPI) ListComments(_ context.Context, id uint) ([]client.Comment, error) {
+	return f.comments[id], nil
+}
+
+func (f *fakeAPI) AddComment(_ context.Context, id uint, text string) (*client.Comment, error) {
+	c := client.Comment{ID: 100, Text: text}
+	f.comments[id] = append(f.comments[id], c)
+	return &c, nil
+}
+
+func (f *fakeAPI) ListLabels(_ context.Context, id uint) ([]client.Label, error) {
+	return f.labels[id], nil
+}
+
+func newFakeAPI(n int) *fakeAPI {
+	f := &fakeAPI{comments: map[uint][]client.Comment{}, labels: map[uint][]client.Label{}}
+	for i := 1; i <= n; i++ {
+		f.todos = append(f.todos, client.Todo{
+			ID:      uint(i),
+			Title:   "todo " + string(rune('a'+i-1)),
+			DueDate: types.DueDate(time.Date(2022, 8, i, 0, 0, 0, 0, time.UTC)),
+			Done:    i%2 == 0,
+		})
+	}
+	return f
+}
+
+func typeKeys(m *model, s string) {
+	for _, k := range parseKeys([]byte(s)) {
+		m.handleKey(k)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys := parseKeys([]byte("j\x1b[A\x1b[6~\r\x7fé\x03\x1b"))
+	require.Equal(t, []key{
+		{r: 'j'}, {name: keyUp}, {name: keyPgDown}, {name: keyEnter},
+		{name: keyBackspace}, {r: 'é'}, {name: keyCtrlC}, {name: keyEsc},
+	}, keys)
+}
+
+func TestNavigationAndFilters(t *testing.T) {
+	api := newFakeAPI(150)
+	m := newModel(context.Background(), api)
+	m.reload()
+	require.Len(t, m.todos, 150, "all pages are loaded")
+
+	typeKeys(m, "jjj")
+	td, _ := m.selected()
+	require.Equal(t, uint(4), td.ID)
+
+	typeKeys(m, "G")
+	td, _ = m.selected()
+	require.Equal(t, uint(150), td.ID)
+	screen := m.render(80, 24)
+	require.Contains(t, screen, "todo")
+	require.Len(t, strings.Split(screen, "\r\n"), 24)
+
+	// pending only
+	typeKeys(m, "f")
+	require.Len(t, m.visible(), 75)
+	for _, td := range m.visible() {
+		require.False(t, td.Done)
+	}
+
+	api.labels[3] = []client.Label{{ID: 1, Text: "home"}}
+	typeKeys(m, "lhome\r")
+	require.Equal(t, "home", m.labelFilter)
+	require.Len(t, m.visible(), 1)
+	require.Contains(t, m.render(80, 24), "label: home")
+}
+
+func TestInlineEditing(t *testing.T) {
+	api := newFakeAPI(3)
+	m := newModel(context.Background(), api)
+	m.reload()
+
+	// replace the title: erase the current one first
+	typeKeys(m, "e"+strings.Repeat("\x7f", 10)+"renamed\r")
+	require.Equal(t, "renamed", m.todos[0].Title)
+
+	typeKeys(m, "d\x7f\x7f15\r")
+	require.Equal(t, "2022-08-15", formatDate(m.todos[0].DueDate))
+
+	typeKeys(m, "d\x7f\x7fxx\r")
+	require.Contains(t, m.status, "invalid date")
+
+	typeKeys(m, " ")
+	require.True(t, m.todos[0].Done)
+	require.Len(t, api.updates, 3)
+
+	// escape discards the edit
+	typeKeys(m, "eoops\x1b")
+	require.Nil(t, m.input)
+	require.Equal(t, "renamed", m.todos[0].Title)
+}
+
+func TestDetailView(t *testing.T) {
+	api := newFakeAPI(2)
+	api.comments[1] = []client.Comment{{ID: 1, Text: "first!"}}
+	m := newModel(context.Background(), api)
+	m.reload()
+
+	typeKeys(m, "\r")
+	require.Equal(t, detailView, m.view)
+	require.Contains(t, m.render(80, 24), "first!")
+
+	typeKeys(m, "cnice one\r")
+	require.Contains(t, m.render(80, 24), "nice one")
+
+	typeKeys(m, "q")
+	require.Equal(t, listView, m.view)
+	require.False(t, m.quit)
+
+	typeKeys(m, "q")
+	require.True(t, m.quit)
+}
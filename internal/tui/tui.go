@@ -0,0 +1,144 @@
+// Package tui implements a full-screen terminal UI for browsing and editing
+// todos. It only needs a terminal, so it also works over SSH sessions.
+package tui
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+
+	"github.com/Neurostep/todo/pkg/client"
+)
+
+const (
+	enterAltScreen = "\x1b[?1049h\x1b[?25l"
+	leaveAltScreen = "\x1b[?25h\x1b[?1049l"
+	clearScreen    = "\x1b[H\x1b[2J"
+)
+
+// API is the part of the todo client the UI relies on.
+type API interface {
+	ListTodos(ctx context.Context, opts client.ListTodosOptions) (*client.TodoPage, error)
+	CreateTodo(ctx context.Context, todo client.NewTodo) (*client.Todo, error)
+	UpdateTodo(ctx context.Context, id uint, todo client.UpdateTodo) (*client.Todo, error)
+	ListComments(ctx context.Context, todoID uint) ([]client.Comment, error)
+	AddComment(ctx context.Context, todoID uint, text string) (*client.Comment, error)
+	ListLabels(ctx context.Context, todoID uint) ([]client.Label, error)
+}
+
+var _ API = (*client.Client)(nil)
+
+// Run takes over the terminal until the user quits.
+func Run(ctx context.Context, api API, in *os.File, out io.Writer) error {
+	fd := int(in.Fd())
+	if !term.IsTerminal(fd) {
+		return errors.New("the terminal UI requires an interactive terminal")
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return errors.Wrap(err, "failed to switch the terminal to raw mode")
+	}
+	defer term.Restore(fd, state)
+
+	io.WriteString(out, enterAltScreen)
+	defer io.WriteString(out, leaveAltScreen)
+
+	m := newModel(ctx, api)
+	m.reload()
+
+	buf := make([]byte, 64)
+	for !m.quit {
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			width, height = 80, 24
+		}
+		io.WriteString(out, clearScreen+m.render(width, height))
+
+		n, err := in.Read(buf)
+		if err != nil {
+			return err
+		}
+		for _, k := range parseKeys(buf[:n]) {
+			m.handleKey(k)
+		}
+	}
+
+	return nil
+}
+
+type key struct {
+	// name is set for special keys, r for printable ones
+	name string
+	r    rune
+}
+
+const (
+	keyUp        = "up"
+	keyDown      = "down"
+	keyPgUp      = "pgup"
+	keyPgDown    = "pgdown"
+	keyHome      = "home"
+	keyEnd       = "end"
+	keyEnter     = "enter"
+	keyEsc       = "esc"
+	keyBackspace = "backspace"
+	keyCtrlC     = "ctrl+c"
+)
+
+var escapeSequences = map[string]string{
+	"\x1b[A":  keyUp,
+	"\x1b[B":  keyDown,
+	"\x1bOA":  keyUp,
+	"\x1bOB":  keyDown,
+	"\x1b[5~": keyPgUp,
+	"\x1b[6~": keyPgDown,
+	"\x1b[H":  keyHome,
+	"\x1b[F":  keyEnd,
+	"\x1b[1~": keyHome,
+	"\x1b[4~": keyEnd,
+}
+
+// parseKeys decodes the raw bytes read from the terminal.
+func parseKeys(b []byte) []key {
+	var keys []key
+	s := string(b)
+	for len(s) > 0 {
+		if s[0] == '\x1b' {
+			matched := false
+			for seq, name := range escapeSequences {
+				if len(s) >= len(seq) && s[:len(seq)] == seq {
+					keys = append(keys, key{name: name})
+					s = s[len(seq):]
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				// a lone escape, or a sequence we don't handle
+				keys = append(keys, key{name: keyEsc})
+				s = ""
+			}
+			continue
+		}
+
+		r := []rune(s)[0]
+		switch r {
+		case '\r', '\n':
+			keys = append(keys, key{name: keyEnter})
+		case 127, '\b':
+			keys = append(keys, key{name: keyBackspace})
+		case 3:
+			keys = append(keys, key{name: keyCtrlC})
+		default:
+			if r >= ' ' {
+				keys = append(keys, key{r: r})
+			}
+		}
+		s = s[len(string(r)):]
+	}
+	return keys
+}
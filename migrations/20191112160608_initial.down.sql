@@ -1,3 +1,3 @@
-DROP TABLE IF EXISTS todos;
 DROP TABLE IF EXISTS labels;
 DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS todos;
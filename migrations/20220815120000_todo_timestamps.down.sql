@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx__todos__due_date_open;
+
+ALTER TABLE todos DROP COLUMN IF EXISTS completed_at;
+ALTER TABLE todos DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,4 @@
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS created_at timestamp without time zone NOT NULL DEFAULT now();
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS completed_at timestamp without time zone;
+
+CREATE INDEX IF NOT EXISTS idx__todos__due_date_open ON todos(due_date) WHERE NOT done;
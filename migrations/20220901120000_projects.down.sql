@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx__todos__project_id;
+ALTER TABLE todos DROP COLUMN IF EXISTS project_id;
+
+DROP TABLE IF EXISTS invitations;
+DROP TABLE IF EXISTS memberships;
+DROP TABLE IF EXISTS projects;
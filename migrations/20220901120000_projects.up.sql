@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS projects
+(
+  id serial PRIMARY KEY,
+  name character varying (255) NOT NULL,
+  created_at timestamp without time zone NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS memberships
+(
+  id serial PRIMARY KEY,
+  project_id integer REFERENCES projects(id) NOT NULL,
+  username character varying (255) NOT NULL,
+  role character varying (16) NOT NULL CHECK (role IN ('owner', 'editor', 'commenter', 'viewer'))
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx__memberships__project_id_username ON memberships(project_id, username);
+CREATE INDEX IF NOT EXISTS idx__memberships__username ON memberships(username);
+
+CREATE TABLE IF NOT EXISTS invitations
+(
+  id serial PRIMARY KEY,
+  project_id integer REFERENCES projects(id) NOT NULL,
+  username character varying (255) NOT NULL,
+  role character varying (16) NOT NULL CHECK (role IN ('owner', 'editor', 'commenter', 'viewer')),
+  invited_by character varying (255) NOT NULL,
+  created_at timestamp without time zone NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx__invitations__project_id_username ON invitations(project_id, username);
+CREATE INDEX IF NOT EXISTS idx__invitations__username ON invitations(username);
+
+-- todos created before stay outside projects
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS project_id integer REFERENCES projects(id);
+CREATE INDEX IF NOT EXISTS idx__todos__project_id ON todos(project_id);
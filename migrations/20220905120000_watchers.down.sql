@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS watchers;
+
+DROP INDEX IF EXISTS idx__todos__assignee;
+ALTER TABLE todos DROP COLUMN IF EXISTS assignee;
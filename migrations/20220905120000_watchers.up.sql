@@ -0,0 +1,10 @@
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS assignee character varying (255) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx__todos__assignee ON todos(assignee);
+
+CREATE TABLE IF NOT EXISTS watchers
+(
+  id serial PRIMARY KEY,
+  todo_id integer REFERENCES todos(id) NOT NULL,
+  username character varying (255) NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx__watchers__todo_id_username ON watchers(todo_id, username);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS fields;
+
+DROP INDEX IF EXISTS idx__todos__priority;
+ALTER TABLE todos DROP COLUMN IF EXISTS fields;
+ALTER TABLE todos DROP COLUMN IF EXISTS estimate_minutes;
+ALTER TABLE todos DROP COLUMN IF EXISTS priority;
@@ -0,0 +1,15 @@
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS priority smallint NOT NULL DEFAULT 2 CHECK (priority BETWEEN 0 AND 4);
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS estimate_minutes integer NOT NULL DEFAULT 0 CHECK (estimate_minutes >= 0);
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS fields jsonb NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS idx__todos__priority ON todos(priority);
+
+CREATE TABLE IF NOT EXISTS fields
+(
+  id serial PRIMARY KEY,
+  project_id integer REFERENCES projects(id) NOT NULL,
+  name character varying (63) NOT NULL,
+  type character varying (16) NOT NULL CHECK (type IN ('text', 'number', 'enum', 'date')),
+  options jsonb NOT NULL DEFAULT '[]',
+  required boolean NOT NULL DEFAULT false
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx__fields__project_id_name ON fields(project_id, name);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS todo_references;
+
+ALTER TABLE todos DROP COLUMN IF EXISTS description;
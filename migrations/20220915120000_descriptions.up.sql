@@ -0,0 +1,12 @@
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS description text NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS todo_references
+(
+  id serial PRIMARY KEY,
+  todo_id integer REFERENCES todos(id) NOT NULL,
+  kind character varying (16) NOT NULL CHECK (kind IN ('mention', 'todo')),
+  username character varying (255) NOT NULL DEFAULT '',
+  target_id integer NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx__todo_references__todo_id ON todo_references(todo_id);
+CREATE INDEX IF NOT EXISTS idx__todo_references__target_id ON todo_references(target_id) WHERE kind = 'todo';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx__comments__todo_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx__todo_comments__todo_id ON comments(todo_id);
+
+DROP INDEX IF EXISTS idx__labels__todo_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx__todo_labels__todo_id ON labels(todo_id);
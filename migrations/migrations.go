@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL migrations of the Postgres schema, so the
+// binary can apply them itself, see `todo migrate`.
+package migrations
+
+import "embed"
+
+// FS holds the <version>_<name>.up.sql and .down.sql files.
+//
+//go:embed *.sql
+var FS embed.FS
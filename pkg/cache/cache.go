@@ -0,0 +1,74 @@
+// Package cache provides the caches the services put in front of their
+// storage: an in-process LRU and a client of Redis compatible servers.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+const (
+	DriverNone   = "none"
+	DriverMemory = "memory"
+	DriverRedis  = "redis"
+)
+
+// Cache stores values under string keys. A ttl of zero keeps the value
+// until it is deleted or evicted.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// ReadThrough serves reads from the cache, loading and storing the value on
+// a miss. The cache is never required for a read to succeed: its failures
+// are logged and the value is loaded instead.
+type ReadThrough struct {
+	Cache  Cache
+	TTL    time.Duration
+	Logger log.Logger
+}
+
+// Fetch decodes the value cached under key into dst. On a miss load fills
+// dst, which is cached afterwards. kind tags the hit and miss metrics.
+func (r *ReadThrough) Fetch(ctx context.Context, kind, key string, dst interface{}, load func() error) error {
+	raw, ok, err := r.Cache.Get(ctx, key)
+	switch {
+	case err != nil:
+		r.Logger.Log("event", "cache_get_failed", "key", key, "error", err)
+		record(ctx, kind, resultError)
+	case ok:
+		if err := json.Unmarshal(raw, dst); err == nil {
+			record(ctx, kind, resultHit)
+			return nil
+		}
+		r.Logger.Log("event", "cache_decode_failed", "key", key, "error", err)
+		record(ctx, kind, resultError)
+	default:
+		record(ctx, kind, resultMiss)
+	}
+
+	if err := load(); err != nil {
+		return err
+	}
+
+	raw, err = json.Marshal(dst)
+	if err == nil {
+		err = r.Cache.Set(ctx, key, raw, r.TTL)
+	}
+	if err != nil {
+		r.Logger.Log("event", "cache_set_failed", "key", key, "error", err)
+	}
+	return nil
+}
+
+// Delete removes the keys, logging failures.
+func (r *ReadThrough) Delete(ctx context.Context, keys ...string) {
+	if err := r.Cache.Delete(ctx, keys...); err != nil {
+		r.Logger.Log("event", "cache_delete_failed", "keys", keys, "error", err)
+	}
+}
@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+// caches returns every implementation, Redis served by miniredis.
+func caches(t *testing.T) (map[string]Cache, func(time.Duration)) {
+	lru := NewLRU(10)
+	now := time.Now()
+	lru.now = func() time.Time { return now }
+
+	mr := miniredis.RunT(t)
+	mr.RequireAuth("secret")
+	redis := NewRedis(RedisConfig{Address: mr.Addr(), Password: "secret"})
+	t.Cleanup(func() { redis.Close() })
+
+	advance := func(d time.Duration) {
+		now = now.Add(d)
+		mr.FastForward(d)
+	}
+	return map[string]Cache{"lru": lru, "redis": redis}, advance
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	all, advance := caches(t)
+
+	for name, c := range all {
+		t.Run(name, func(t *testing.T) {
+			_, ok, err := c.Get(ctx, "missing")
+			require.NoError(t, err)
+			require.False(t, ok)
+
+			require.NoError(t, c.Set(ctx, "a", []byte(`{"title":"first"}`), 0))
+			require.NoError(t, c.Set(ctx, "b", []byte("short lived"), time.Second))
+			require.NoError(t, c.Set(ctx, "c", []byte(""), 0))
+
+			v, ok, err := c.Get(ctx, "a")
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, `{"title":"first"}`, string(v))
+
+			v, ok, err = c.Get(ctx, "c")
+			require.NoError(t, err)
+			require.True(t, ok, "empty values are values")
+			require.Empty(t, v)
+
+			require.NoError(t, c.Delete(ctx, "a", "missing"))
+			_, ok, _ = c.Get(ctx, "a")
+			require.False(t, ok)
+
+			advance(2 * time.Second)
+			_, ok, _ = c.Get(ctx, "b")
+			require.False(t, ok, "expired")
+		})
+	}
+}
+
+func TestLRUEviction(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), 0))
+	require.NoError(t, c.Set(ctx, "b", []byte("2"), 0))
+	_, _, _ = c.Get(ctx, "a")
+	require.NoError(t, c.Set(ctx, "c", []byte("3"), 0))
+
+	require.Equal(t, 2, c.Len())
+	_, ok, _ := c.Get(ctx, "b")
+	require.False(t, ok, "the least recently used entry is evicted")
+	_, ok, _ = c.Get(ctx, "a")
+	require.True(t, ok)
+}
+
+func TestRedisFailures(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	c := NewRedis(RedisConfig{Address: mr.Addr(), Password: "wrong"})
+	mr.RequireAuth("secret")
+
+	_, _, err := c.Get(ctx, "a")
+	require.Error(t, err)
+
+	c = NewRedis(RedisConfig{Address: mr.Addr(), Password: "secret"})
+	require.NoError(t, c.Ping(ctx))
+	mr.Close()
+	require.Error(t, c.Ping(ctx))
+}
+
+// failingCache fails every operation, like an unreachable Redis.
+type failingCache struct{}
+
+func (failingCache) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func (failingCache) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func (failingCache) Delete(context.Context, ...string) error {
+	return errors.New("connection refused")
+}
+
+func TestReadThrough(t *testing.T) {
+	ctx := context.Background()
+	type todo struct{ Title string }
+
+	loads := 0
+	load := func(dst *todo) func() error {
+		return func() error {
+			loads++
+			*dst = todo{Title: "first"}
+			return nil
+		}
+	}
+
+	r := &ReadThrough{Cache: NewLRU(10), TTL: time.Minute, Logger: log.NewNopLogger()}
+	for i := 0; i < 2; i++ {
+		var td todo
+		require.NoError(t, r.Fetch(ctx, "todo", "todo:1", &td, load(&td)))
+		require.Equal(t, "first", td.Title)
+	}
+	require.Equal(t, 1, loads)
+
+	r.Delete(ctx, "todo:1")
+	var td todo
+	require.NoError(t, r.Fetch(ctx, "todo", "todo:1", &td, load(&td)))
+	require.Equal(t, 2, loads)
+
+	r = &ReadThrough{Cache: failingCache{}, Logger: log.NewNopLogger()}
+	require.NoError(t, r.Fetch(ctx, "todo", "todo:1", &td, load(&td)), "cache failures do not fail reads")
+	require.Equal(t, 3, loads)
+
+	loadErr := errors.New("not found")
+	require.Equal(t, loadErr, r.Fetch(ctx, "todo", "todo:2", &td, func() error { return loadErr }))
+}
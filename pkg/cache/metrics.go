@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"context"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const (
+	resultHit   = "hit"
+	resultMiss  = "miss"
+	resultError = "error"
+)
+
+var (
+	// KeyKind is the kind of the cached value, KeyResult is hit, miss or
+	// error.
+	KeyKind   = tag.MustNewKey("kind")
+	KeyResult = tag.MustNewKey("result")
+
+	lookups = stats.Int64("todo/cache/lookups", "Cache lookups", stats.UnitDimensionless)
+
+	// Views count the lookups by kind and result, the hit ratio is the
+	// share of hits.
+	Views = []*view.View{
+		{
+			Name:        "todo/cache/lookups",
+			Description: "Cache lookups by kind and result",
+			TagKeys:     []tag.Key{KeyKind, KeyResult},
+			Measure:     lookups,
+			Aggregation: view.Count(),
+		},
+	}
+)
+
+func record(ctx context.Context, kind, result string) {
+	stats.RecordWithTags(ctx, []tag.Mutator{
+		tag.Upsert(KeyKind, kind),
+		tag.Upsert(KeyResult, result),
+	}, lookups.M(1))
+}
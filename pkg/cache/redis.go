@@ -0,0 +1,233 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultRedisPoolSize = 10
+	defaultRedisTimeout  = time.Second
+)
+
+type (
+	RedisConfig struct {
+		Address  string
+		Password string
+		DB       int
+		// PoolSize is the number of idle connections kept open
+		PoolSize int
+		// Timeout bounds dialing and every command without a deadline of
+		// its own
+		Timeout time.Duration
+	}
+
+	// Redis is a cache backed by a server speaking the Redis protocol
+	// (Redis, KeyDB, Dragonfly and the like), shared by every instance of
+	// the service.
+	Redis struct {
+		cfg  RedisConfig
+		idle chan *redisConn
+	}
+
+	redisConn struct {
+		net.Conn
+		r *bufio.Reader
+	}
+
+	// redisError is an error reply of the server, the connection stays
+	// usable after it.
+	redisError string
+)
+
+var _ Cache = (*Redis)(nil)
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+func NewRedis(cfg RedisConfig) *Redis {
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = defaultRedisPoolSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultRedisTimeout
+	}
+	return &Redis{cfg: cfg, idle: make(chan *redisConn, cfg.PoolSize)}
+}
+
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := c.Do(ctx, "GET", key)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, errors.Errorf("redis: unexpected reply %v to GET", reply)
+	}
+	return value, true, nil
+}
+
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(int64(ttl/time.Millisecond), 10))
+	}
+	_, err := c.Do(ctx, args...)
+	return err
+}
+
+func (c *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := c.Do(ctx, append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+// Ping checks the server is reachable, it fits a health check.
+func (c *Redis) Ping(ctx context.Context) error {
+	_, err := c.Do(ctx, "PING")
+	return err
+}
+
+// Close closes the idle connections.
+func (c *Redis) Close() error {
+	for {
+		select {
+		case conn := <-c.idle:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// Do sends a command and reads its reply: a string, an int64, []byte for
+// bulk strings, []interface{} for arrays or nil. Connections are returned to
+// the pool unless the exchange failed midway.
+func (c *Redis) Do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := c.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(c.deadline(ctx), args...)
+	if _, ok := err.(redisError); err != nil && !ok {
+		conn.Close()
+		return nil, err
+	}
+
+	select {
+	case c.idle <- conn:
+	default:
+		conn.Close()
+	}
+	return reply, err
+}
+
+func (c *Redis) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-c.idle:
+		return conn, nil
+	default:
+	}
+
+	d := net.Dialer{Timeout: c.cfg.Timeout}
+	nc, err := d.DialContext(ctx, "tcp", c.cfg.Address)
+	if err != nil {
+		return nil, errors.Wrap(err, "redis: dial")
+	}
+	conn := &redisConn{Conn: nc, r: bufio.NewReader(nc)}
+
+	if c.cfg.Password != "" {
+		if _, err := conn.do(c.deadline(ctx), "AUTH", c.cfg.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if c.cfg.DB != 0 {
+		if _, err := conn.do(c.deadline(ctx), "SELECT", strconv.Itoa(c.cfg.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (c *Redis) deadline(ctx context.Context) time.Time {
+	if d, ok := ctx.Deadline(); ok {
+		return d
+	}
+	return time.Now().Add(c.cfg.Timeout)
+}
+
+func (conn *redisConn) do(deadline time.Time, args ...string) (interface{}, error) {
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return conn.reply()
+}
+
+// reply reads a RESP2 reply: nil bulk strings and arrays are returned as
+// nil, bulk strings as []byte.
+func (conn *redisConn) reply() (interface{}, error) {
+	line, err := conn.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.Errorf("redis: malformed reply %q", line)
+	}
+	kind, line := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return line, nil
+	case '-':
+		return nil, redisError(line)
+	case ':':
+		return strconv.ParseInt(line, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(conn.r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = conn.reply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+
+	return nil, errors.Errorf("redis: unknown reply type %q", kind)
+}
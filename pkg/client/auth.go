@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Token is an authorization token issued by the API.
+type Token struct {
+	Token   string `json:"token"`
+	Expires int64  `json:"expires"`
+}
+
+// ExpiresAt returns the moment the token stops being accepted.
+func (t Token) ExpiresAt() time.Time {
+	return time.Unix(t.Expires, 0)
+}
+
+// Valid reports whether the token is set and not expired yet.
+func (t Token) Valid() bool {
+	return t.Token != "" && time.Now().Before(t.ExpiresAt())
+}
+
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Signin exchanges the username and password for a token, which is then
+// used for the following requests.
+func (c *Client) Signin(ctx context.Context, username, password string) (Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.signin(ctx, username, password)
+}
+
+// Refresh renews the current token. The API only allows it during the last
+// 30 seconds of the token's life.
+func (c *Client) Refresh(ctx context.Context) (Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.refresh(ctx)
+}
+
+// Token returns the token the client currently uses.
+func (c *Client) Token() Token {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.token
+}
+
+// authorize returns the token for the next request, renewing it first when
+// it is about to expire.
+func (c *Client) authorize(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+
+	if token.Valid() && time.Until(token.ExpiresAt()) > refreshWindow {
+		return token.Token, nil
+	}
+	if !token.Valid() && c.username == "" {
+		// nothing to renew with, let the server decide
+		return token.Token, nil
+	}
+
+	return c.renew(ctx, token.Token, false)
+}
+
+// renew refreshes the token, or signs in again when refreshing isn't
+// possible. stale is the token the caller saw; if another goroutine already
+// replaced it, the new one is returned as is.
+func (c *Client) renew(ctx context.Context, stale string, rejected bool) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token.Token != stale && c.token.Valid() {
+		return c.token.Token, nil
+	}
+
+	if !rejected && c.token.Valid() {
+		if token, err := c.refresh(ctx); err == nil || c.username == "" {
+			return token.Token, err
+		}
+	}
+
+	token, err := c.signin(ctx, c.username, c.password)
+	return token.Token, err
+}
+
+func (c *Client) signin(ctx context.Context, username, password string) (Token, error) {
+	body, err := jsonBody(credentials{Username: username, Password: password})
+	if err != nil {
+		return Token{}, err
+	}
+
+	res, err := c.send(ctx, http.MethodPost, "/signin", url.Values{}, body, "")
+	if err != nil {
+		return Token{}, err
+	}
+	defer res.Body.Close()
+
+	var token Token
+	if err := decodeResponse(res, &token); err != nil {
+		return Token{}, err
+	}
+	c.token = token
+
+	return token, nil
+}
+
+func (c *Client) refresh(ctx context.Context) (Token, error) {
+	res, err := c.send(ctx, http.MethodGet, "/refresh", url.Values{}, nil, c.token.Token)
+	if err != nil {
+		return Token{}, err
+	}
+	defer res.Body.Close()
+
+	var token Token
+	if err := decodeResponse(res, &token); err != nil {
+		return Token{}, err
+	}
+	c.token = token
+
+	return token, nil
+}
@@ -0,0 +1,175 @@
+// Package client provides a typed Go client for the todo HTTP API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	apiPrefix = "/api/v1"
+
+	// refreshWindow mirrors the server: a token can only be refreshed during
+	// the last 30 seconds of its life.
+	refreshWindow = 30 * time.Second
+)
+
+type (
+	// Client talks to the todo API. It is safe for concurrent use.
+	Client struct {
+		baseURL    *url.URL
+		httpClient *http.Client
+
+		username string
+		password string
+
+		mu    sync.Mutex
+		token Token
+	}
+
+	// Option configures a Client.
+	Option func(*Client)
+)
+
+// WithHTTPClient sets the HTTP client used to send requests.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithCredentials makes the client sign in on its own whenever it has no
+// valid token, including after the server rejected the current one.
+func WithCredentials(username, password string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithToken sets the token obtained earlier, e.g. by a previous Signin.
+func WithToken(token Token) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// New creates a client for the API served at baseURL, e.g. http://localhost:19000.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid base url")
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.Errorf("invalid base url %q: scheme and host are required", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// do sends an authorized API request. in is encoded as the JSON body and the
+// response body is decoded into out, unless they are nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = jsonBody(in); err != nil {
+			return err
+		}
+	}
+
+	token, err := c.authorize(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.send(ctx, method, path, query, body, token)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode == http.StatusUnauthorized && c.username != "" {
+		res.Body.Close()
+		// the token was rejected, e.g. the server key changed: sign in again
+		if token, err = c.renew(ctx, token, true); err != nil {
+			return err
+		}
+		if res, err = c.send(ctx, method, path, query, body, token); err != nil {
+			return err
+		}
+	}
+	defer res.Body.Close()
+
+	return decodeResponse(res, out)
+}
+
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body []byte, token string) (*http.Response, error) {
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s %s failed", method, path)
+	}
+	return res, nil
+}
+
+func decodeResponse(res *http.Response, out interface{}) error {
+	if res.StatusCode >= http.StatusBadRequest {
+		return decodeError(res)
+	}
+	if out == nil || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return errors.Wrap(err, "failed to decode response")
+	}
+	return nil
+}
+
+func jsonBody(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode request")
+	}
+	return b, nil
+}
+
+func todoPath(id uint, parts ...string) string {
+	p := apiPrefix + "/todos/" + strconv.FormatUint(uint64(id), 10)
+	for _, part := range parts {
+		p += "/" + part
+	}
+	return p
+}
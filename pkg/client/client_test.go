@@ -0,0 +1,306 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Neurostep/todo/internal/server"
+	"github.com/Neurostep/todo/pkg/services/todo"
+	"github.com/Neurostep/todo/pkg/types"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	s := server.New(server.Config{
+		AuthEnabled: true,
+		Port:        9000,
+		TodoService: todo.New(todo.Config{
+			Repository: todo.NewMemoryRepository(),
+			Logger:     log.NewNopLogger(),
+		}),
+		Logger: log.NewNopLogger(),
+	})
+	ts := httptest.NewServer(s.Handler)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func dueDate(t *testing.T, s string) types.DueDate {
+	d, err := time.Parse(types.DueDateFormat, s)
+	require.NoError(t, err)
+	return types.DueDate(d)
+}
+
+func TestClient(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+
+	c, err := New(ts.URL, WithCredentials("user", "password"))
+	require.NoError(t, err)
+
+	created, err := c.CreateTodo(ctx, NewTodo{Title: "write client", DueDate: dueDate(t, "2022-08-01")})
+	require.NoError(t, err)
+	require.NotZero(t, created.ID)
+	require.True(t, c.Token().Valid(), "client signs in on its own")
+
+	got, err := c.GetTodo(ctx, created.ID)
+	require.NoError(t, err)
+	require.Equal(t, "write client", got.Title)
+	require.Equal(t, "2022-08-01", time.Time(got.DueDate).Format(types.DueDateFormat))
+
+	updated, err := c.UpdateTodo(ctx, created.ID, UpdateTodo{Title: "write client", DueDate: got.DueDate, Done: true})
+	require.NoError(t, err)
+	require.True(t, updated.Done)
+
+	cmnt, err := c.AddComment(ctx, created.ID, "looks good")
+	require.NoError(t, err)
+	comments, err := c.ListComments(ctx, created.ID)
+	require.NoError(t, err)
+	require.Equal(t, []Comment{*cmnt}, comments)
+	require.NoError(t, c.RemoveComment(ctx, created.ID, cmnt.ID))
+
+	lbl, err := c.AddLabel(ctx, created.ID, NewLabel{Text: "sdk", Color: "#00ff00"})
+	require.NoError(t, err)
+	labels, err := c.ListLabels(ctx, created.ID)
+	require.NoError(t, err)
+	require.Equal(t, []Label{*lbl}, labels)
+	require.NoError(t, c.RemoveLabel(ctx, created.ID, lbl.ID))
+
+	require.NoError(t, c.DeleteTodo(ctx, created.ID))
+	_, err = c.GetTodo(ctx, created.ID)
+	require.True(t, IsNotFound(err), "got %v", err)
+}
+
+func TestTodoIterator(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+
+	c, err := New(ts.URL, WithCredentials("user", "password"))
+	require.NoError(t, err)
+
+	for i := 0; i < 7; i++ {
+		_, err := c.CreateTodo(ctx, NewTodo{Title: "todo", DueDate: dueDate(t, "2022-08-01")})
+		require.NoError(t, err)
+	}
+
+	page, err := c.ListTodos(ctx, ListTodosOptions{Limit: 5})
+	require.NoError(t, err)
+	require.True(t, page.HasMore)
+	require.Equal(t, 7, page.TotalCount)
+	require.Len(t, page.Data, 5)
+
+	it := c.Todos(ListTodosOptions{Limit: 3})
+	var ids []uint
+	for it.Next(ctx) {
+		ids = append(ids, it.Todo().ID)
+	}
+	require.NoError(t, it.Err())
+	require.Len(t, ids, 7)
+}
+
+func TestClientErrors(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+
+	anonymous, err := New(ts.URL)
+	require.NoError(t, err)
+	_, err = anonymous.ListTodos(ctx, ListTodosOptions{})
+	require.True(t, IsUnauthorized(err), "got %v", err)
+
+	_, err = anonymous.Signin(ctx, "user", "wrong")
+	require.True(t, IsUnauthorized(err), "got %v", err)
+
+	c, err := New(ts.URL, WithCredentials("user", "password"))
+	require.NoError(t, err)
+	_, err = c.CreateTodo(ctx, NewTodo{Title: strings.Repeat("a", 2048), DueDate: dueDate(t, "2022-08-01")})
+	require.True(t, IsBadRequest(err), "got %v", err)
+
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	require.Equal(t, "title", apiErr.Errors[0].Label)
+
+	_, err = New("localhost")
+	require.Error(t, err)
+}
+
+func TestTokenRenewal(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+
+	c, err := New(ts.URL, WithCredentials("user", "password"), WithToken(Token{Token: "garbage", Expires: time.Now().Add(time.Hour).Unix()}))
+	require.NoError(t, err)
+
+	// the server rejects the token, the client signs in and retries
+	_, err = c.ListTodos(ctx, ListTodosOptions{})
+	require.NoError(t, err)
+	require.NotEqual(t, "garbage", c.Token().Token)
+
+	expired, err := New(ts.URL, WithCredentials("user", "password"), WithToken(Token{Token: "old", Expires: time.Now().Add(-time.Minute).Unix()}))
+	require.NoError(t, err)
+	_, err = expired.ListTodos(ctx, ListTodosOptions{})
+	require.NoError(t, err)
+	require.True(t, expired.Token().Valid())
+
+	manual, err := New(ts.URL)
+	require.NoError(t, err)
+	token, err := manual.Signin(ctx, "user", "password")
+	require.NoError(t, err)
+	require.True(t, token.Valid())
+	require.Equal(t, token, manual.Token())
+
+	// refreshing is only allowed close to the expiration
+	_, err = manual.Refresh(ctx)
+	require.True(t, IsBadRequest(err), "got %v", err)
+}
+
+func TestProjects(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+
+	c, err := New(ts.URL, WithCredentials("user", "password"))
+	require.NoError(t, err)
+
+	p, err := c.CreateProject(ctx, "home")
+	require.NoError(t, err)
+	p, err = c.RenameProject(ctx, p.ID, "family")
+	require.NoError(t, err)
+	projects, err := c.ListProjects(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []Project{*p}, projects)
+
+	td, err := c.CreateTodo(ctx, NewTodo{Title: "groceries", DueDate: dueDate(t, "2022-08-01"), ProjectID: p.ID})
+	require.NoError(t, err)
+	require.Equal(t, p.ID, td.ProjectID)
+	page, err := c.ListTodos(ctx, ListTodosOptions{ProjectID: p.ID})
+	require.NoError(t, err)
+	require.Equal(t, []Todo{*td}, page.Data)
+	page, err = c.ListTodos(ctx, ListTodosOptions{})
+	require.NoError(t, err)
+	require.Empty(t, page.Data, "todos outside projects")
+
+	members, err := c.ListMembers(ctx, p.ID)
+	require.NoError(t, err)
+	require.Equal(t, []Member{{ID: members[0].ID, Username: "user", Role: RoleOwner}}, members)
+	_, err = c.UpdateMember(ctx, p.ID, members[0].ID, RoleViewer)
+	require.True(t, IsConflict(err), "got %v", err)
+
+	inv, err := c.Invite(ctx, p.ID, "guest", RoleViewer)
+	require.NoError(t, err)
+	require.Equal(t, "user", inv.InvitedBy)
+	invitations, err := c.ListProjectInvitations(ctx, p.ID)
+	require.NoError(t, err)
+	require.Equal(t, []Invitation{*inv}, invitations)
+	_, err = c.AcceptInvitation(ctx, inv.ID)
+	require.True(t, IsNotFound(err), "invitations of others, got %v", err)
+	require.NoError(t, c.RevokeInvitation(ctx, p.ID, inv.ID))
+
+	invitations, err = c.ListInvitations(ctx)
+	require.NoError(t, err)
+	require.Empty(t, invitations)
+	_, err = c.GetProject(ctx, 1000)
+	require.True(t, IsNotFound(err), "got %v", err)
+}
+
+func TestWatchers(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+
+	c, err := New(ts.URL, WithCredentials("user", "password"))
+	require.NoError(t, err)
+
+	td, err := c.CreateTodo(ctx, NewTodo{Title: "groceries", DueDate: dueDate(t, "2022-08-01"), Assignee: "user"})
+	require.NoError(t, err)
+	require.Equal(t, "user", td.Assignee)
+	_, err = c.CreateTodo(ctx, NewTodo{Title: "laundry", DueDate: dueDate(t, "2022-08-01")})
+	require.NoError(t, err)
+	page, err := c.ListTodos(ctx, ListTodosOptions{Assignee: "me"})
+	require.NoError(t, err)
+	require.Equal(t, []Todo{*td}, page.Data)
+
+	w, err := c.AddWatcher(ctx, td.ID, "guest")
+	require.NoError(t, err)
+	_, err = c.AddWatcher(ctx, td.ID, "guest")
+	require.True(t, IsConflict(err), "got %v", err)
+	watchers, err := c.ListWatchers(ctx, td.ID)
+	require.NoError(t, err)
+	require.Equal(t, []Watcher{{ID: watchers[0].ID, Username: "user"}, *w}, watchers)
+	require.NoError(t, c.RemoveWatcher(ctx, td.ID, w.ID))
+
+	td, err = c.UpdateTodo(ctx, td.ID, UpdateTodo{Title: td.Title, DueDate: td.DueDate})
+	require.NoError(t, err)
+	require.Empty(t, td.Assignee, "unassigned")
+}
+
+func TestDescriptions(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+
+	c, err := New(ts.URL, WithCredentials("user", "password"))
+	require.NoError(t, err)
+
+	target, err := c.CreateTodo(ctx, NewTodo{Title: "laundry", DueDate: dueDate(t, "2022-08-01")})
+	require.NoError(t, err)
+	description := fmt.Sprintf("_after_ #%d, ask @guest", target.ID)
+	td, err := c.CreateTodo(ctx, NewTodo{Title: "groceries", DueDate: dueDate(t, "2022-08-01"), Description: description})
+	require.NoError(t, err)
+	require.Equal(t, description, td.Description)
+	require.Empty(t, td.DescriptionHTML)
+
+	rendered, err := c.GetRenderedTodo(ctx, td.ID)
+	require.NoError(t, err)
+	require.Contains(t, rendered.DescriptionHTML, "<em>after</em>")
+	page, err := c.ListTodos(ctx, ListTodosOptions{RenderHTML: true})
+	require.NoError(t, err)
+	require.Equal(t, []Todo{*target, *rendered}, page.Data)
+
+	refs, err := c.ListReferences(ctx, td.ID)
+	require.NoError(t, err)
+	require.Equal(t, &References{
+		Mentions:     []string{"guest"},
+		Todos:        []TodoReference{{ID: target.ID, Title: "laundry"}},
+		ReferencedBy: []TodoReference{},
+	}, refs)
+}
+
+func TestFields(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+
+	c, err := New(ts.URL, WithCredentials("user", "password"))
+	require.NoError(t, err)
+
+	p, err := c.CreateProject(ctx, "home")
+	require.NoError(t, err)
+	size, err := c.CreateField(ctx, p.ID, NewField{Name: "size", Type: FieldNumber})
+	require.NoError(t, err)
+	fields, err := c.ListFields(ctx, p.ID)
+	require.NoError(t, err)
+	require.Equal(t, []Field{*size}, fields)
+
+	small, err := c.CreateTodo(ctx, NewTodo{Title: "dishes", DueDate: dueDate(t, "2022-08-01"), ProjectID: p.ID, Fields: map[string]interface{}{"size": 1}})
+	require.NoError(t, err)
+	require.Equal(t, "P2", small.Priority)
+	big, err := c.CreateTodo(ctx, NewTodo{Title: "weeds", DueDate: dueDate(t, "2022-08-01"), ProjectID: p.ID, Priority: "P0",
+		EstimateMinutes: 90, Fields: map[string]interface{}{"size": 10}})
+	require.NoError(t, err)
+	_, err = c.CreateTodo(ctx, NewTodo{Title: "attic", DueDate: dueDate(t, "2022-08-01"), ProjectID: p.ID, Fields: map[string]interface{}{"size": "big"}})
+	require.True(t, IsBadRequest(err), "got %v", err)
+
+	page, err := c.ListTodos(ctx, ListTodosOptions{ProjectID: p.ID, Sort: "-field.size"})
+	require.NoError(t, err)
+	require.Equal(t, []Todo{*big, *small}, page.Data)
+	page, err = c.ListTodos(ctx, ListTodosOptions{ProjectID: p.ID, Priority: "P0", Field: "size:10"})
+	require.NoError(t, err)
+	require.Equal(t, []Todo{*big}, page.Data)
+
+	require.NoError(t, c.DeleteField(ctx, p.ID, size.ID))
+	err = c.DeleteField(ctx, p.ID, size.ID)
+	require.True(t, IsNotFound(err), "got %v", err)
+}
@@ -0,0 +1,36 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+type Comment struct {
+	ID   uint   `json:"id"`
+	Text string `json:"text"`
+}
+
+func (c *Client) AddComment(ctx context.Context, todoID uint, text string) (*Comment, error) {
+	var cmnt Comment
+	req := struct {
+		Text string `json:"text"`
+	}{text}
+	if err := c.do(ctx, http.MethodPost, todoPath(todoID, "comments"), nil, req, &cmnt); err != nil {
+		return nil, err
+	}
+	return &cmnt, nil
+}
+
+func (c *Client) ListComments(ctx context.Context, todoID uint) ([]Comment, error) {
+	var comments []Comment
+	if err := c.do(ctx, http.MethodGet, todoPath(todoID, "comments"), nil, nil, &comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (c *Client) RemoveComment(ctx context.Context, todoID, id uint) error {
+	path := todoPath(todoID, "comments", strconv.FormatUint(uint64(id), 10))
+	return c.do(ctx, http.MethodDelete, path, nil, nil, nil)
+}
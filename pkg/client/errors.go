@@ -0,0 +1,87 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Error is a single entry of the Errors payload returned by the API.
+	Error struct {
+		Label   string `json:"label"`
+		Message string `json:"message"`
+	}
+
+	// APIError is returned when the API answers with a non-successful status.
+	APIError struct {
+		StatusCode int
+		Errors     []Error
+	}
+)
+
+func (e *APIError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", err.Label, err.Message))
+	}
+	res := fmt.Sprintf("todo api: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if len(msgs) != 0 {
+		res += ": " + strings.Join(msgs, "; ")
+	}
+	return res
+}
+
+// IsNotFound reports whether err is an APIError with 404 status.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is an APIError with 401 status.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// IsForbidden reports whether err is an APIError with 403 status, the role
+// of the user in the project does not allow the request.
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+// IsConflict reports whether err is an APIError with 409 status.
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// IsBadRequest reports whether err is an APIError with 400 status.
+func IsBadRequest(err error) bool {
+	return hasStatus(err, http.StatusBadRequest)
+}
+
+func hasStatus(err error, code int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == code
+}
+
+func decodeError(res *http.Response) error {
+	apiErr := &APIError{StatusCode: res.StatusCode}
+
+	raw, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return apiErr
+	}
+
+	var payload struct {
+		Errors []Error `json:"errors"`
+	}
+	// some responses, e.g. from the auth middleware, carry no payload
+	if json.Unmarshal(raw, &payload) == nil {
+		apiErr.Errors = payload.Errors
+	}
+
+	return apiErr
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// Types of custom fields.
+const (
+	FieldText   = "text"
+	FieldNumber = "number"
+	FieldEnum   = "enum"
+	FieldDate   = "date"
+)
+
+type (
+	// Field is a custom field of the todos of a project.
+	Field struct {
+		ID       uint     `json:"id"`
+		Name     string   `json:"name"`
+		Type     string   `json:"type"`
+		Options  []string `json:"options,omitempty"`
+		Required bool     `json:"required"`
+	}
+
+	// NewField defines a custom field, Options are the values of enum
+	// fields.
+	NewField struct {
+		Name     string   `json:"name"`
+		Type     string   `json:"type"`
+		Options  []string `json:"options,omitempty"`
+		Required bool     `json:"required"`
+	}
+)
+
+func (c *Client) CreateField(ctx context.Context, projectID uint, field NewField) (*Field, error) {
+	var f Field
+	if err := c.do(ctx, http.MethodPost, projectPath(projectID, "fields"), nil, field, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (c *Client) ListFields(ctx context.Context, projectID uint) ([]Field, error) {
+	var fields []Field
+	if err := c.do(ctx, http.MethodGet, projectPath(projectID, "fields"), nil, nil, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// DeleteField removes the custom field along with its values.
+func (c *Client) DeleteField(ctx context.Context, projectID, id uint) error {
+	return c.do(ctx, http.MethodDelete, projectPath(projectID, "fields", strconv.FormatUint(uint64(id), 10)), nil, nil, nil)
+}
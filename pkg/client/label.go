@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+type (
+	Label struct {
+		ID    uint   `json:"id"`
+		Text  string `json:"text"`
+		Color string `json:"color"`
+	}
+
+	NewLabel struct {
+		Text  string `json:"text"`
+		Color string `json:"color"`
+	}
+)
+
+func (c *Client) AddLabel(ctx context.Context, todoID uint, label NewLabel) (*Label, error) {
+	var lbl Label
+	if err := c.do(ctx, http.MethodPost, todoPath(todoID, "labels"), nil, label, &lbl); err != nil {
+		return nil, err
+	}
+	return &lbl, nil
+}
+
+func (c *Client) ListLabels(ctx context.Context, todoID uint) ([]Label, error) {
+	var labels []Label
+	if err := c.do(ctx, http.MethodGet, todoPath(todoID, "labels"), nil, nil, &labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+func (c *Client) RemoveLabel(ctx context.Context, todoID, id uint) error {
+	path := todoPath(todoID, "labels", strconv.FormatUint(uint64(id), 10))
+	return c.do(ctx, http.MethodDelete, path, nil, nil, nil)
+}
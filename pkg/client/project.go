@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// Roles of the members of projects.
+const (
+	RoleOwner     = "owner"
+	RoleEditor    = "editor"
+	RoleCommenter = "commenter"
+	RoleViewer    = "viewer"
+)
+
+type (
+	Project struct {
+		ID   uint   `json:"id"`
+		Name string `json:"name"`
+	}
+
+	Member struct {
+		ID       uint   `json:"id"`
+		Username string `json:"username"`
+		Role     string `json:"role"`
+	}
+
+	Invitation struct {
+		ID        uint   `json:"id"`
+		ProjectID uint   `json:"project_id"`
+		Username  string `json:"username"`
+		Role      string `json:"role"`
+		InvitedBy string `json:"invited_by"`
+	}
+)
+
+// CreateProject creates a project owned by the user.
+func (c *Client) CreateProject(ctx context.Context, name string) (*Project, error) {
+	var p Project
+	req := struct {
+		Name string `json:"name"`
+	}{name}
+	if err := c.do(ctx, http.MethodPost, apiPrefix+"/projects", nil, req, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListProjects returns the projects the user is a member of.
+func (c *Client) ListProjects(ctx context.Context) ([]Project, error) {
+	var projects []Project
+	if err := c.do(ctx, http.MethodGet, apiPrefix+"/projects", nil, nil, &projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+func (c *Client) GetProject(ctx context.Context, id uint) (*Project, error) {
+	var p Project
+	if err := c.do(ctx, http.MethodGet, projectPath(id), nil, nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (c *Client) RenameProject(ctx context.Context, id uint, name string) (*Project, error) {
+	var p Project
+	req := struct {
+		Name string `json:"name"`
+	}{name}
+	if err := c.do(ctx, http.MethodPut, projectPath(id), nil, req, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (c *Client) ListMembers(ctx context.Context, projectID uint) ([]Member, error) {
+	var members []Member
+	if err := c.do(ctx, http.MethodGet, projectPath(projectID, "members"), nil, nil, &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (c *Client) UpdateMember(ctx context.Context, projectID, id uint, role string) (*Member, error) {
+	var m Member
+	req := struct {
+		Role string `json:"role"`
+	}{role}
+	path := projectPath(projectID, "members", strconv.FormatUint(uint64(id), 10))
+	if err := c.do(ctx, http.MethodPut, path, nil, req, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (c *Client) RemoveMember(ctx context.Context, projectID, id uint) error {
+	path := projectPath(projectID, "members", strconv.FormatUint(uint64(id), 10))
+	return c.do(ctx, http.MethodDelete, path, nil, nil, nil)
+}
+
+// Invite offers username the role in the project, see AcceptInvitation.
+func (c *Client) Invite(ctx context.Context, projectID uint, username, role string) (*Invitation, error) {
+	var inv Invitation
+	req := struct {
+		Username string `json:"username"`
+		Role     string `json:"role"`
+	}{username, role}
+	if err := c.do(ctx, http.MethodPost, projectPath(projectID, "invitations"), nil, req, &inv); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// ListProjectInvitations returns the pending invitations to the project.
+func (c *Client) ListProjectInvitations(ctx context.Context, projectID uint) ([]Invitation, error) {
+	var invitations []Invitation
+	if err := c.do(ctx, http.MethodGet, projectPath(projectID, "invitations"), nil, nil, &invitations); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (c *Client) RevokeInvitation(ctx context.Context, projectID, id uint) error {
+	path := projectPath(projectID, "invitations", strconv.FormatUint(uint64(id), 10))
+	return c.do(ctx, http.MethodDelete, path, nil, nil, nil)
+}
+
+// ListInvitations returns the invitations of the user.
+func (c *Client) ListInvitations(ctx context.Context) ([]Invitation, error) {
+	var invitations []Invitation
+	if err := c.do(ctx, http.MethodGet, apiPrefix+"/invitations", nil, nil, &invitations); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// AcceptInvitation makes the user a member of the project of the invitation.
+func (c *Client) AcceptInvitation(ctx context.Context, id uint) (*Member, error) {
+	var m Member
+	// the API takes JSON requests only, even without a body
+	if err := c.do(ctx, http.MethodPost, invitationPath(id, "accept"), nil, struct{}{}, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (c *Client) DeclineInvitation(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, invitationPath(id), nil, nil, nil)
+}
+
+func projectPath(id uint, parts ...string) string {
+	p := apiPrefix + "/projects/" + strconv.FormatUint(uint64(id), 10)
+	for _, part := range parts {
+		p += "/" + part
+	}
+	return p
+}
+
+func invitationPath(id uint, parts ...string) string {
+	p := apiPrefix + "/invitations/" + strconv.FormatUint(uint64(id), 10)
+	for _, part := range parts {
+		p += "/" + part
+	}
+	return p
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+type (
+	// References are the users mentioned by @username in the description
+	// of a todo and the todos it refers to by #id, along with the todos
+	// referring to it. Todos the user may not view are left out.
+	References struct {
+		Mentions     []string        `json:"mentions"`
+		Todos        []TodoReference `json:"todos"`
+		ReferencedBy []TodoReference `json:"referenced_by"`
+	}
+
+	TodoReference struct {
+		ID    uint   `json:"id"`
+		Title string `json:"title"`
+	}
+)
+
+func (c *Client) ListReferences(ctx context.Context, todoID uint) (*References, error) {
+	var refs References
+	if err := c.do(ctx, http.MethodGet, todoPath(todoID, "references"), nil, nil, &refs); err != nil {
+		return nil, err
+	}
+	return &refs, nil
+}
@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Neurostep/todo/pkg/types"
+)
+
+type (
+	Todo struct {
+		ID    uint   `json:"id"`
+		Title string `json:"title"`
+		// Description is in Markdown, DescriptionHTML is the description
+		// rendered when asked for
+		Description     string        `json:"description,omitempty"`
+		DescriptionHTML string        `json:"description_html,omitempty"`
+		DueDate         types.DueDate `json:"due_date"`
+		Done            bool          `json:"done"`
+		ProjectID       uint          `json:"project_id,omitempty"`
+		Assignee        string        `json:"assignee,omitempty"`
+		// Priority is P0, the most urgent, to P4
+		Priority        string                 `json:"priority"`
+		EstimateMinutes uint                   `json:"estimate_minutes,omitempty"`
+		Fields          map[string]interface{} `json:"fields,omitempty"`
+	}
+
+	// NewTodo creates a todo in the project of ProjectID, outside projects
+	// when it is zero. Fields are the values of the custom fields of the
+	// project, the server picks P2 when Priority is empty.
+	NewTodo struct {
+		Title           string                 `json:"title"`
+		Description     string                 `json:"description,omitempty"`
+		DueDate         types.DueDate          `json:"due_date"`
+		ProjectID       uint                   `json:"project_id,omitempty"`
+		Assignee        string                 `json:"assignee,omitempty"`
+		Priority        string                 `json:"priority,omitempty"`
+		EstimateMinutes uint                   `json:"estimate_minutes,omitempty"`
+		Fields          map[string]interface{} `json:"fields,omitempty"`
+	}
+
+	// UpdateTodo replaces the fields of a todo, an empty Assignee
+	// unassigns it and omitted custom fields are cleared.
+	UpdateTodo struct {
+		Title           string                 `json:"title"`
+		Description     string                 `json:"description,omitempty"`
+		DueDate         types.DueDate          `json:"due_date"`
+		Done            bool                   `json:"done"`
+		Assignee        string                 `json:"assignee,omitempty"`
+		Priority        string                 `json:"priority,omitempty"`
+		EstimateMinutes uint                   `json:"estimate_minutes,omitempty"`
+		Fields          map[string]interface{} `json:"fields,omitempty"`
+	}
+
+	// ListTodosOptions controls pagination of ListTodos. Zero values let
+	// the server pick its defaults. The todos of the project of ProjectID
+	// are listed, the todos outside projects when it is zero. Assignee lists
+	// the todos assigned to the user from all the projects, "me" standing for
+	// the authorized user. Priority and Field, as name:value of a custom
+	// field of the project, select todos too. Sort is priority, due_date,
+	// estimate or field.<name>, descending when prefixed by a minus sign.
+	// RenderHTML renders the descriptions of the todos to sanitized HTML.
+	ListTodosOptions struct {
+		Limit      uint32
+		Offset     uint32
+		ProjectID  uint
+		Assignee   string
+		Priority   string
+		Field      string
+		Sort       string
+		RenderHTML bool
+	}
+
+	TodoPage struct {
+		HasMore    bool   `json:"has_more"`
+		TotalCount int    `json:"total_count"`
+		Data       []Todo `json:"data"`
+	}
+
+	// TodoIterator walks through all todos page by page:
+	//
+	//	it := c.Todos(ListTodosOptions{Limit: 100})
+	//	for it.Next(ctx) {
+	//		td := it.Todo()
+	//	}
+	//	if err := it.Err(); err != nil {
+	//	}
+	TodoIterator struct {
+		c    *Client
+		opts ListTodosOptions
+		page []Todo
+		cur  Todo
+		last bool
+		err  error
+	}
+)
+
+func (o ListTodosOptions) values() url.Values {
+	v := url.Values{}
+	if o.Limit != 0 {
+		v.Set("limit", strconv.FormatUint(uint64(o.Limit), 10))
+	}
+	if o.Offset != 0 {
+		v.Set("offset", strconv.FormatUint(uint64(o.Offset), 10))
+	}
+	if o.ProjectID != 0 {
+		v.Set("project_id", strconv.FormatUint(uint64(o.ProjectID), 10))
+	}
+	if o.Assignee != "" {
+		v.Set("assignee", o.Assignee)
+	}
+	if o.Priority != "" {
+		v.Set("priority", o.Priority)
+	}
+	if o.Field != "" {
+		v.Set("field", o.Field)
+	}
+	if o.Sort != "" {
+		v.Set("sort", o.Sort)
+	}
+	if o.RenderHTML {
+		v.Set("render", "html")
+	}
+	return v
+}
+
+// ListTodos returns a single page of todos.
+func (c *Client) ListTodos(ctx context.Context, opts ListTodosOptions) (*TodoPage, error) {
+	var page TodoPage
+	if err := c.do(ctx, http.MethodGet, apiPrefix+"/todos", opts.values(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Todos returns an iterator over all todos starting at opts.Offset, fetched
+// opts.Limit at a time.
+func (c *Client) Todos(opts ListTodosOptions) *TodoIterator {
+	return &TodoIterator{c: c, opts: opts}
+}
+
+// Next advances to the next todo, fetching the next page when needed. It
+// returns false when there are no more todos or an error occurred.
+func (it *TodoIterator) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if len(it.page) == 0 {
+		if it.last {
+			return false
+		}
+		page, err := it.c.ListTodos(ctx, it.opts)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page = page.Data
+		it.last = !page.HasMore || len(page.Data) == 0
+		it.opts.Offset += uint32(len(page.Data))
+		if len(it.page) == 0 {
+			return false
+		}
+	}
+
+	it.cur, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Todo returns the current todo.
+func (it *TodoIterator) Todo() Todo {
+	return it.cur
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *TodoIterator) Err() error {
+	return it.err
+}
+
+func (c *Client) GetTodo(ctx context.Context, id uint) (*Todo, error) {
+	var td Todo
+	if err := c.do(ctx, http.MethodGet, todoPath(id), nil, nil, &td); err != nil {
+		return nil, err
+	}
+	return &td, nil
+}
+
+// GetRenderedTodo returns the todo with its description rendered to
+// sanitized HTML.
+func (c *Client) GetRenderedTodo(ctx context.Context, id uint) (*Todo, error) {
+	var td Todo
+	if err := c.do(ctx, http.MethodGet, todoPath(id), url.Values{"render": {"html"}}, nil, &td); err != nil {
+		return nil, err
+	}
+	return &td, nil
+}
+
+func (c *Client) CreateTodo(ctx context.Context, todo NewTodo) (*Todo, error) {
+	var td Todo
+	if err := c.do(ctx, http.MethodPost, apiPrefix+"/todos", nil, todo, &td); err != nil {
+		return nil, err
+	}
+	return &td, nil
+}
+
+func (c *Client) UpdateTodo(ctx context.Context, id uint, todo UpdateTodo) (*Todo, error) {
+	var td Todo
+	if err := c.do(ctx, http.MethodPut, todoPath(id), nil, todo, &td); err != nil {
+		return nil, err
+	}
+	return &td, nil
+}
+
+func (c *Client) DeleteTodo(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, todoPath(id), nil, nil, nil)
+}
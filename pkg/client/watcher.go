@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// Watcher is a user notified of the changes of a todo.
+type Watcher struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+func (c *Client) AddWatcher(ctx context.Context, todoID uint, username string) (*Watcher, error) {
+	var w Watcher
+	req := struct {
+		Username string `json:"username"`
+	}{username}
+	if err := c.do(ctx, http.MethodPost, todoPath(todoID, "watchers"), nil, req, &w); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (c *Client) ListWatchers(ctx context.Context, todoID uint) ([]Watcher, error) {
+	var watchers []Watcher
+	if err := c.do(ctx, http.MethodGet, todoPath(todoID, "watchers"), nil, nil, &watchers); err != nil {
+		return nil, err
+	}
+	return watchers, nil
+}
+
+func (c *Client) RemoveWatcher(ctx context.Context, todoID, id uint) error {
+	path := todoPath(todoID, "watchers", strconv.FormatUint(uint64(id), 10))
+	return c.do(ctx, http.MethodDelete, path, nil, nil, nil)
+}
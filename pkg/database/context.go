@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+)
+
+// contextKey keeps the context of the handles of WithContext.
+const contextKey = "todo:context"
+
+// WithContext returns a handle of db running its statements under ctx, so
+// they are traced as spans of ctx and, row queries aside, not started once
+// ctx is done. The handle shares the connection pool, the settings and the
+// callbacks of db. gorm v1 knows nothing of contexts, statements already
+// running are left to the statement timeout. Transactions begun with Begin
+// are rolled back when ctx is done.
+func WithContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if ctx.Done() == nil {
+		return db
+	}
+	return db.Set(contextKey, ctx)
+}
+
+// Begin begins a transaction on db under the context of WithContext, if
+// any, which rolls it back when done.
+func Begin(db *gorm.DB) *gorm.DB {
+	if v, ok := db.Get(contextKey); ok {
+		return db.BeginTx(v.(context.Context), nil)
+	}
+	return db.Begin()
+}
+
+// checkContext fails the statements of handles whose context is done
+// before they run.
+func checkContext(scope *gorm.Scope) {
+	if v, ok := scope.Get(contextKey); ok {
+		if err := v.(context.Context).Err(); err != nil {
+			scope.Err(err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+//go:build cgo
+// +build cgo
+
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/trace"
+)
+
+func TestWithContext(t *testing.T) {
+	var logged int
+	logger := log.LoggerFunc(func(kv ...interface{}) error {
+		logged++
+		return nil
+	})
+	db, err := New(context.Background(), Config{Driver: DriverSQLite, Address: ":memory:", LogQueries: true}, logger)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.True(t, db == WithContext(context.Background(), db), "contexts never done need no handle")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	conn := WithContext(ctx, db)
+	require.NoError(t, conn.Exec("CREATE TABLE items (id INTEGER)").Error)
+	require.Equal(t, 1, logged, "the logging settings are kept")
+
+	cancel()
+	err = conn.Table("items").Create(&struct{ ID int }{ID: 1}).Error
+	require.True(t, errors.Is(err, context.Canceled), err)
+	require.Error(t, Begin(conn).Error)
+
+	var count int
+	require.NoError(t, db.Table("items").Count(&count).Error)
+	require.Equal(t, 0, count)
+}
+
+type spanRecorder []*trace.SpanData
+
+func (r *spanRecorder) ExportSpan(s *trace.SpanData) { *r = append(*r, s) }
+
+func TestTraceStatements(t *testing.T) {
+	db, err := New(context.Background(), Config{Driver: DriverSQLite, Address: ":memory:"}, log.NewNopLogger())
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Exec("CREATE TABLE items (id INTEGER)").Error)
+
+	spans := &spanRecorder{}
+	trace.RegisterExporter(spans)
+	defer trace.UnregisterExporter(spans)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx, parent := trace.StartSpan(ctx, "request", trace.WithSampler(trace.AlwaysSample()))
+
+	var count int
+	require.NoError(t, WithContext(ctx, db).Table("items").Count(&count).Error)
+	require.NoError(t, db.Table("items").Count(&count).Error, "statements without a context are not traced")
+	other, err := gorm.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer other.Close()
+	require.NoError(t, WithContext(ctx, other).Table("sqlite_master").Count(&count).Error, "nor the ones of other databases")
+	parent.End()
+
+	require.Len(t, *spans, 2)
+	statement := (*spans)[0]
+	require.Equal(t, "sql:row_query", statement.Name)
+	require.Equal(t, parent.SpanContext().SpanID, statement.ParentSpanID)
+	require.Equal(t, "SELECT count(*) FROM \"items\"  ", statement.Attributes["db.statement"])
+	require.Equal(t, "sqlite3", statement.Attributes["db.system"])
+}
@@ -1,21 +1,155 @@
 package database
 
 import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/go-kit/kit/log"
 	"github.com/jinzhu/gorm"
 	_ "github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+const (
+	defaultMaxOpenConns    = 20
+	defaultMaxIdleConns    = 10
+	defaultConnMaxLifetime = 30 * time.Minute
+	defaultConnectBackoff  = time.Second
+	maxConnectBackoff      = 30 * time.Second
 )
 
 type Config struct {
+	// Driver is DriverPostgres (default) or DriverSQLite. DriverMemory
+	// needs no connection at all, see todo.NewMemoryRepository.
+	Driver  string
 	Address string
+
+	// MaxOpenConns, MaxIdleConns and ConnMaxLifetime configure the
+	// connection pool, zero values fall back to the package defaults.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// StatementTimeout aborts Postgres statements running longer, zero
+	// disables it.
+	StatementTimeout time.Duration
+
+	// ConnectRetries is how many times connecting is retried on start,
+	// waiting ConnectBackoff before the first retry and doubling it after.
+	ConnectRetries int
+	ConnectBackoff time.Duration
+
+	// LogQueries logs every statement, SlowQueryThreshold only the ones
+	// taking at least that long. Errors are always logged.
+	LogQueries         bool
+	SlowQueryThreshold time.Duration
 }
 
 type Scope = func(*gorm.DB) *gorm.DB
 
-func New(cfg Config, logger log.Logger) (*gorm.DB, error) {
-	db, err := gorm.Open("postgres", cfg.Address)
-	if err != nil {
-		return nil, err
+// New connects to the configured database, retrying on failure as
+// configured, and routes the gorm log into logger.
+func New(ctx context.Context, cfg Config, logger log.Logger) (*gorm.DB, error) {
+	var (
+		db  *gorm.DB
+		err error
+	)
+	switch cfg.Driver {
+	case "", DriverPostgres:
+		address := withStatementTimeout(cfg.Address, cfg.StatementTimeout)
+		db, err = connect(ctx, cfg, logger, func() (*gorm.DB, error) {
+			return gorm.Open("postgres", address)
+		})
+		if err != nil {
+			return nil, err
+		}
+		db.DB().SetMaxOpenConns(orDefault(cfg.MaxOpenConns, defaultMaxOpenConns))
+		db.DB().SetMaxIdleConns(orDefault(cfg.MaxIdleConns, defaultMaxIdleConns))
+		lifetime := cfg.ConnMaxLifetime
+		if lifetime == 0 {
+			lifetime = defaultConnMaxLifetime
+		}
+		db.DB().SetConnMaxLifetime(lifetime)
+	case DriverSQLite:
+		if db, err = openSQLite(cfg.Address); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+
+	db.SetLogger(&gormLogger{logger: logger, all: cfg.LogQueries, slow: cfg.SlowQueryThreshold})
+	// the detailed mode is the only one passing statements to the logger
+	if cfg.LogQueries || cfg.SlowQueryThreshold > 0 {
+		db.LogMode(true)
+	}
+	traceStatements(db)
+	return db, nil
+}
+
+// connect calls open until it succeeds, the retries are exhausted or ctx is
+// done, backing off exponentially between the attempts.
+func connect(ctx context.Context, cfg Config, logger log.Logger, open func() (*gorm.DB, error)) (*gorm.DB, error) {
+	backoff := cfg.ConnectBackoff
+	if backoff <= 0 {
+		backoff = defaultConnectBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		db, err := open()
+		if err == nil {
+			return db, nil
+		}
+		if attempt >= cfg.ConnectRetries {
+			return nil, errors.Wrapf(err, "failed to connect after %d attempt(s)", attempt+1)
+		}
+
+		logger.Log("event", "database_connect_failed", "attempt", attempt+1, "retryIn", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "connecting to the database")
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+}
+
+// withStatementTimeout adds the statement_timeout run-time parameter to a
+// Postgres DSN, both in URL and in key=value form.
+func withStatementTimeout(dsn string, timeout time.Duration) string {
+	if timeout <= 0 {
+		return dsn
+	}
+	ms := strconv.FormatInt(int64(timeout/time.Millisecond), 10)
+
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			// leave reporting the malformed DSN to the driver
+			return dsn
+		}
+		q := u.Query()
+		q.Set("statement_timeout", ms)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+
+	return strings.TrimSpace(dsn + " statement_timeout=" + ms)
+}
+
+func orDefault(v, def int) int {
+	if v == 0 {
+		return def
 	}
-	return db.LogMode(true), nil
+	return v
 }
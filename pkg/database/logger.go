@@ -0,0 +1,49 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// gormLogger routes the gorm log into a go-kit logger. Statements are only
+// logged when all is set or they took at least slow, their arguments are
+// never logged as they may carry user data.
+type gormLogger struct {
+	logger log.Logger
+	all    bool
+	slow   time.Duration
+}
+
+// Print implements the gorm logger. gorm passes the kind of the entry and
+// the caller first, statements follow as duration, SQL, arguments and the
+// number of affected rows.
+func (l *gormLogger) Print(values ...interface{}) {
+	if len(values) < 2 {
+		return
+	}
+	kind, source := values[0], values[1]
+
+	if kind == "sql" && len(values) >= 6 {
+		duration, _ := values[2].(time.Duration)
+		slow := l.slow > 0 && duration >= l.slow
+		if !l.all && !slow {
+			return
+		}
+		event := "sql_query"
+		if slow {
+			event = "slow_sql_query"
+		}
+		l.logger.Log(
+			"event", event,
+			"query", values[3],
+			"duration", duration,
+			"rows", values[5],
+			"source", source,
+		)
+		return
+	}
+
+	l.logger.Log("event", "gorm_"+fmt.Sprint(kind), "message", fmt.Sprint(values[2:]...), "source", source)
+}
@@ -0,0 +1,308 @@
+// Package migrate applies the embedded SQL migrations to Postgres. The
+// bookkeeping table is the one of github.com/golang-migrate/migrate, so
+// databases migrated by its CLI are picked up as is.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	table = "schema_migrations"
+
+	versionFormat = "20060102150405"
+)
+
+var (
+	fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+	// lockID is the key of the advisory lock serializing migrators
+	lockID = int64(crc32.ChecksumIEEE([]byte("todo." + table)))
+
+	ErrDirty = errors.New("database is dirty, a previous migration failed halfway and needs fixing by hand")
+)
+
+type (
+	Migration struct {
+		Version uint64
+		Name    string
+		Up      string
+		Down    string
+	}
+
+	Migrator struct {
+		db         *sql.DB
+		migrations []Migration
+		logger     log.Logger
+	}
+
+	// Status describes the schema of the database compared to the embedded
+	// migrations.
+	Status struct {
+		// Version is the last applied migration, 0 for an empty database.
+		Version uint64
+		Dirty   bool
+		// Latest is the version the code expects.
+		Latest  uint64
+		Applied []Migration
+		Pending []Migration
+	}
+)
+
+// Load reads the migrations from fsys, sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list migrations")
+	}
+
+	byVersion := map[uint64]*Migration{}
+	for _, f := range files {
+		m := fileName.FindStringSubmatch(f.Name())
+		if m == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid migration version in %s", f.Name())
+		}
+		raw, err := fs.ReadFile(fsys, f.Name())
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s", f.Name())
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if m[3] == "up" {
+			mig.Up = string(raw)
+		} else {
+			mig.Down = string(raw)
+		}
+	}
+
+	res := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, errors.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		res = append(res, *m)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Version < res[j].Version })
+
+	return res, nil
+}
+
+// Create writes empty up and down files for a new migration into dir and
+// returns their paths.
+func Create(dir, name string, now time.Time) (string, string, error) {
+	if !regexp.MustCompile(`^[a-z0-9_]+$`).MatchString(name) {
+		return "", "", errors.Errorf("invalid migration name %q, use lowercase letters, digits and underscores", name)
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%s_%s", now.UTC().Format(versionFormat), name))
+	up, down := base+".up.sql", base+".down.sql"
+	for _, p := range []string{up, down} {
+		if _, err := os.Stat(p); err == nil {
+			return "", "", errors.Errorf("%s already exists", p)
+		}
+		if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+			return "", "", errors.Wrapf(err, "failed to create %s", p)
+		}
+	}
+
+	return up, down, nil
+}
+
+func New(db *sql.DB, fsys fs.FS, logger log.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations, logger: logger}, nil
+}
+
+// Latest returns the version of the newest embedded migration.
+func (m *Migrator) Latest() uint64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status reports the applied and pending migrations.
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	version, dirty, err := readVersion(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.status(version, dirty), nil
+}
+
+func (m *Migrator) status(version uint64, dirty bool) *Status {
+	s := &Status{Version: version, Dirty: dirty, Latest: m.Latest()}
+	for _, mig := range m.migrations {
+		if mig.Version <= version {
+			s.Applied = append(s.Applied, mig)
+		} else {
+			s.Pending = append(s.Pending, mig)
+		}
+	}
+	return s
+}
+
+// Check returns an error when the schema is dirty or older than the code
+// expects. A newer schema is accepted, so that an older binary keeps working
+// during a rolling deployment.
+func (m *Migrator) Check(ctx context.Context) error {
+	s, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if s.Dirty {
+		return errors.Wrapf(ErrDirty, "version %d", s.Version)
+	}
+	if s.Version < s.Latest {
+		return errors.Errorf("schema version %d is older than %d expected by the code, run `todo migrate up`", s.Version, s.Latest)
+	}
+	return nil
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.locked(ctx, func(conn *sql.Conn, version uint64) error {
+		for _, mig := range m.status(version, false).Pending {
+			m.logger.Log("event", "applying migration", "version", mig.Version, "name", mig.Name)
+			if err := apply(ctx, conn, mig.Up, mig.Version); err != nil {
+				return errors.Wrapf(err, "migration %d_%s failed", mig.Version, mig.Name)
+			}
+		}
+		return nil
+	})
+}
+
+// Down reverts the last steps applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.locked(ctx, func(conn *sql.Conn, version uint64) error {
+		applied := m.status(version, false).Applied
+		for i := len(applied) - 1; i >= 0 && steps > 0; i, steps = i-1, steps-1 {
+			mig := applied[i]
+			if mig.Down == "" {
+				return errors.Errorf("migration %d_%s can't be reverted, it has no down file", mig.Version, mig.Name)
+			}
+
+			previous := uint64(0)
+			if i > 0 {
+				previous = applied[i-1].Version
+			}
+			m.logger.Log("event", "reverting migration", "version", mig.Version, "name", mig.Name)
+			if err := apply(ctx, conn, mig.Down, previous); err != nil {
+				return errors.Wrapf(err, "reverting migration %d_%s failed", mig.Version, mig.Name)
+			}
+		}
+		return nil
+	})
+}
+
+// locked runs fn on a single connection holding the advisory lock, so that
+// concurrently starting replicas don't migrate the same database at once.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn, version uint64) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get a connection")
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return errors.Wrap(err, "failed to acquire the migration lock")
+	}
+	defer func() {
+		// the lock dies with the session anyway, so a failure here is harmless
+		conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+	}()
+
+	if _, err := conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+table+" (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)"); err != nil {
+		return errors.Wrap(err, "failed to create the migrations table")
+	}
+
+	// read the version only now: another migrator may have just finished
+	version, dirty, err := readVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return errors.Wrapf(ErrDirty, "version %d", version)
+	}
+
+	return fn(conn, version)
+}
+
+// apply runs the statements and records the new version in one transaction.
+func apply(ctx context.Context, conn *sql.Conn, statements string, version uint64) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, statements); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+		return err
+	}
+	if version != 0 {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO "+table+" (version, dirty) VALUES ($1, false)", version); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func readVersion(ctx context.Context, q queryer) (uint64, bool, error) {
+	var exists bool
+	err := q.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists)
+	if err != nil {
+		return 0, false, errors.Wrap(err, "failed to look up the migrations table")
+	}
+	if !exists {
+		return 0, false, nil
+	}
+
+	var (
+		version int64
+		dirty   bool
+	)
+	err = q.QueryRowContext(ctx, "SELECT version, dirty FROM "+table+" LIMIT 1").Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, errors.Wrap(err, "failed to read the schema version")
+	}
+
+	return uint64(version), dirty, nil
+}
@@ -0,0 +1,102 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Neurostep/todo/migrations"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"2_second.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"2_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"1_first.up.sql":    {Data: []byte("CREATE TABLE a ();")},
+		"README.md":         {Data: []byte("ignored")},
+	}
+
+	ms, err := Load(fsys)
+	require.NoError(t, err)
+	require.Len(t, ms, 2)
+	require.Equal(t, Migration{Version: 1, Name: "first", Up: "CREATE TABLE a ();"}, ms[0])
+	require.Equal(t, uint64(2), ms[1].Version)
+	require.Equal(t, "DROP TABLE b;", ms[1].Down)
+
+	_, err = Load(fstest.MapFS{"3_broken.down.sql": {Data: []byte("")}})
+	require.Error(t, err)
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	ms, err := Load(migrations.FS)
+	require.NoError(t, err)
+	require.NotEmpty(t, ms)
+	for _, m := range ms {
+		require.NotEmpty(t, m.Down, "migration %d_%s should be revertible", m.Version, m.Name)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	m := &Migrator{migrations: []Migration{{Version: 1}, {Version: 2}, {Version: 3}}}
+
+	s := m.status(2, false)
+	require.Equal(t, uint64(3), s.Latest)
+	require.Len(t, s.Applied, 2)
+	require.Len(t, s.Pending, 1)
+
+	s = m.status(0, false)
+	require.Len(t, s.Pending, 3)
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2022, 8, 1, 10, 30, 0, 0, time.UTC)
+
+	up, down, err := Create(dir, "add_projects", now)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "20220801103000_add_projects.up.sql"), up)
+	require.FileExists(t, down)
+
+	_, _, err = Create(dir, "add_projects", now)
+	require.Error(t, err)
+
+	_, _, err = Create(dir, "Add Projects", now)
+	require.Error(t, err)
+}
+
+// TestPostgres runs against the database from TODO_TEST_POSTGRES_DSN, e.g.
+// the one of docker-compose, and is skipped without it.
+func TestPostgres(t *testing.T) {
+	dsn := os.Getenv("TODO_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TODO_TEST_POSTGRES_DSN is not set")
+	}
+	ctx := context.Background()
+
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := New(db, migrations.FS, log.NewNopLogger())
+	require.NoError(t, err)
+
+	require.NoError(t, m.Up(ctx))
+	require.NoError(t, m.Check(ctx))
+
+	require.NoError(t, m.Down(ctx, len(m.migrations)))
+	require.Error(t, m.Check(ctx))
+
+	require.NoError(t, m.Up(ctx))
+	s, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Equal(t, m.Latest(), s.Version)
+	require.Empty(t, s.Pending)
+}
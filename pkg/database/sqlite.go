@@ -0,0 +1,21 @@
+//go:build cgo
+// +build cgo
+
+package database
+
+import (
+	"github.com/jinzhu/gorm"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openSQLite(address string) (*gorm.DB, error) {
+	db, err := gorm.Open("sqlite3", address)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer, and every connection to an in-memory
+	// database would see a database of its own
+	db.DB().SetMaxOpenConns(1)
+
+	return db, nil
+}
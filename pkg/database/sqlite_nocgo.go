@@ -0,0 +1,13 @@
+//go:build !cgo
+// +build !cgo
+
+package database
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+func openSQLite(address string) (*gorm.DB, error) {
+	return nil, errors.New("sqlite support requires a binary built with cgo enabled")
+}
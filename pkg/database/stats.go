@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// KeyPool tags the pool statistics with the name of the connection pool.
+var KeyPool = tag.MustNewKey("pool")
+
+var (
+	poolOpen              = stats.Int64("todo/db/pool/open_connections", "Established connections, in use and idle", stats.UnitDimensionless)
+	poolInUse             = stats.Int64("todo/db/pool/in_use_connections", "Connections currently in use", stats.UnitDimensionless)
+	poolIdle              = stats.Int64("todo/db/pool/idle_connections", "Idle connections", stats.UnitDimensionless)
+	poolWaitCount         = stats.Int64("todo/db/pool/wait_count", "Connections waited for in total", stats.UnitDimensionless)
+	poolWaitDuration      = stats.Int64("todo/db/pool/wait_duration", "Time blocked waiting for a connection in total", stats.UnitMilliseconds)
+	poolMaxIdleClosed     = stats.Int64("todo/db/pool/max_idle_closed", "Connections closed due to the idle limit in total", stats.UnitDimensionless)
+	poolMaxLifetimeClosed = stats.Int64("todo/db/pool/max_lifetime_closed", "Connections closed due to the lifetime limit in total", stats.UnitDimensionless)
+)
+
+// PoolViews are the views of the connection pool statistics recorded by
+// RecordPoolStats.
+var PoolViews = poolViews(
+	poolOpen,
+	poolInUse,
+	poolIdle,
+	poolWaitCount,
+	poolWaitDuration,
+	poolMaxIdleClosed,
+	poolMaxLifetimeClosed,
+)
+
+func poolViews(measures ...*stats.Int64Measure) []*view.View {
+	views := make([]*view.View, 0, len(measures))
+	for _, m := range measures {
+		views = append(views, &view.View{
+			Name:        m.Name(),
+			Description: m.Description(),
+			TagKeys:     []tag.Key{KeyPool},
+			Measure:     m,
+			Aggregation: view.LastValue(),
+		})
+	}
+	return views
+}
+
+// RecordPoolStats records the statistics of the pool every interval until
+// ctx is done.
+func RecordPoolStats(ctx context.Context, name string, db *sql.DB, interval time.Duration) {
+	ctx, _ = tag.New(ctx, tag.Upsert(KeyPool, name))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		recordPoolStats(ctx, db.Stats())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func recordPoolStats(ctx context.Context, s sql.DBStats) {
+	stats.Record(ctx,
+		poolOpen.M(int64(s.OpenConnections)),
+		poolInUse.M(int64(s.InUse)),
+		poolIdle.M(int64(s.Idle)),
+		poolWaitCount.M(s.WaitCount),
+		poolWaitDuration.M(int64(s.WaitDuration/time.Millisecond)),
+		poolMaxIdleClosed.M(s.MaxIdleClosed),
+		poolMaxLifetimeClosed.M(s.MaxLifetimeClosed),
+	)
+}
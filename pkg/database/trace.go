@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	"go.opencensus.io/trace"
+)
+
+const spanKey = "todo:span"
+
+// traceStatements checks the context of the statements run on the handles
+// of WithContext and makes every statement a span of the request running
+// it. The callbacks are the ones of db and the handles derived from it.
+func traceStatements(db *gorm.DB) {
+	callbacks := db.Callback()
+	for op, processor := range map[string]func() *gorm.CallbackProcessor{
+		"create":    callbacks.Create,
+		"query":     callbacks.Query,
+		"update":    callbacks.Update,
+		"delete":    callbacks.Delete,
+		"row_query": callbacks.RowQuery,
+	} {
+		processor().Before("gorm:"+op).Register("todo:check_context_"+op, checkContext)
+		processor().After("todo:check_context_"+op).Before("gorm:"+op).Register("todo:start_span_"+op, startSpan("sql:"+op))
+		processor().After("gorm:"+op).Register("todo:end_span_"+op, endSpan)
+	}
+}
+
+func startSpan(name string) func(*gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		v, ok := scope.Get(contextKey)
+		if !ok || scope.HasError() {
+			return
+		}
+		_, span := trace.StartSpan(v.(context.Context), name, trace.WithSpanKind(trace.SpanKindClient))
+		span.AddAttributes(
+			trace.StringAttribute("db.system", scope.Dialect().GetName()),
+			trace.StringAttribute("db.sql.table", scope.TableName()),
+		)
+		scope.Set(spanKey, span)
+	}
+}
+
+// endSpan ends the span of the statement with its SQL, never its
+// arguments as they may carry user data.
+func endSpan(scope *gorm.Scope) {
+	v, ok := scope.Get(spanKey)
+	if !ok {
+		return
+	}
+	span := v.(*trace.Span)
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("db.statement", scope.SQL),
+		trace.Int64Attribute("db.rows_affected", scope.DB().RowsAffected),
+	)
+	if err := scope.DB().Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+	}
+}
@@ -0,0 +1,43 @@
+// Package idempotency stores the responses of requests carrying an
+// Idempotency-Key, so that retries get the original response instead of
+// repeating the request.
+package idempotency
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	StoreMemory = "memory"
+	StoreRedis  = "redis"
+
+	// lockTTL bounds how long a request in progress holds its key, so keys
+	// of crashed requests are released.
+	lockTTL = time.Minute
+)
+
+type (
+	// Record is the state of a key: a request in progress until Done, then
+	// its response.
+	Record struct {
+		// Fingerprint identifies the request, a retry must match it
+		Fingerprint string `json:"fingerprint"`
+		Done        bool   `json:"done"`
+		Status      int    `json:"status,omitempty"`
+		ContentType string `json:"content_type,omitempty"`
+		Body        []byte `json:"body,omitempty"`
+	}
+
+	// Store keeps the records of the keys.
+	Store interface {
+		// Start records a request in progress under key unless the key is
+		// taken, in which case its record is returned instead.
+		Start(ctx context.Context, key, fingerprint string) (*Record, error)
+		// Finish stores the response of the request, kept for ttl.
+		Finish(ctx context.Context, key string, rec Record, ttl time.Duration) error
+		// Abandon releases the key of a request which did not complete, so
+		// it can be retried.
+		Abandon(ctx context.Context, key string) error
+	}
+)
@@ -0,0 +1,64 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Neurostep/todo/pkg/cache"
+)
+
+func TestStores(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := cache.NewRedis(cache.RedisConfig{Address: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	now := time.Now()
+	memory := NewMemory()
+	memory.now = func() time.Time { return now }
+	advance := func(d time.Duration) {
+		now = now.Add(d)
+		mr.FastForward(d)
+	}
+
+	for name, store := range map[string]Store{"memory": memory, "redis": NewRedis(client)} {
+		t.Run(name, func(t *testing.T) {
+			rec, err := store.Start(ctx, "user:alice:1", "fp")
+			require.NoError(t, err)
+			require.Nil(t, rec, "the key is taken")
+
+			rec, err = store.Start(ctx, "user:alice:1", "fp")
+			require.NoError(t, err)
+			require.NotNil(t, rec)
+			require.False(t, rec.Done, "the request is in progress")
+			require.Equal(t, "fp", rec.Fingerprint)
+
+			require.NoError(t, store.Finish(ctx, "user:alice:1", Record{
+				Fingerprint: "fp",
+				Status:      201,
+				ContentType: "application/json",
+				Body:        []byte(`{"id":1}`),
+			}, time.Hour))
+
+			rec, err = store.Start(ctx, "user:alice:1", "fp")
+			require.NoError(t, err)
+			require.True(t, rec.Done)
+			require.Equal(t, 201, rec.Status)
+			require.Equal(t, `{"id":1}`, string(rec.Body))
+
+			advance(2 * time.Hour)
+			rec, err = store.Start(ctx, "user:alice:1", "other")
+			require.NoError(t, err)
+			require.Nil(t, rec, "the record expired")
+
+			require.NoError(t, store.Abandon(ctx, "user:alice:1"))
+			rec, err = store.Start(ctx, "user:alice:1", "fp")
+			require.NoError(t, err)
+			require.Nil(t, rec, "abandoned keys are free")
+		})
+	}
+}
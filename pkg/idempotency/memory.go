@@ -0,0 +1,76 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often expired records are dropped from memory.
+const sweepInterval = time.Minute
+
+type (
+	// Memory keeps the records in the process, retries must reach the same
+	// instance to be recognized.
+	Memory struct {
+		mu      sync.Mutex
+		records map[string]*entry
+		swept   time.Time
+		now     func() time.Time
+	}
+
+	entry struct {
+		Record
+		expires time.Time
+	}
+)
+
+var _ Store = (*Memory)(nil)
+
+func NewMemory() *Memory {
+	return &Memory{records: map[string]*entry{}, now: time.Now}
+}
+
+func (m *Memory) Start(ctx context.Context, key, fingerprint string) (*Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if e, ok := m.records[key]; ok && now.Before(e.expires) {
+		rec := e.Record
+		return &rec, nil
+	}
+
+	m.sweep(now)
+	m.records[key] = &entry{Record: Record{Fingerprint: fingerprint}, expires: now.Add(lockTTL)}
+	return nil, nil
+}
+
+func (m *Memory) Finish(ctx context.Context, key string, rec Record, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec.Done = true
+	m.records[key] = &entry{Record: rec, expires: m.now().Add(ttl)}
+	return nil
+}
+
+func (m *Memory) Abandon(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, key)
+	return nil
+}
+
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.swept) < sweepInterval {
+		return
+	}
+	m.swept = now
+	for key, e := range m.records {
+		if !now.Before(e.expires) {
+			delete(m.records, key)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Doer sends a command to a Redis compatible server, see cache.Redis.
+	Doer interface {
+		Do(ctx context.Context, args ...string) (interface{}, error)
+	}
+
+	// Redis keeps the records in a Redis compatible server shared by all
+	// the instances.
+	Redis struct {
+		client Doer
+		prefix string
+	}
+)
+
+var _ Store = (*Redis)(nil)
+
+func NewRedis(client Doer) *Redis {
+	return &Redis{client: client, prefix: "idempotency:"}
+}
+
+func (r *Redis) Start(ctx context.Context, key, fingerprint string) (*Record, error) {
+	raw, err := json.Marshal(Record{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	}
+
+	// the key may expire between SET and GET, try once more then
+	for attempt := 0; attempt < 2; attempt++ {
+		reply, err := r.client.Do(ctx, "SET", r.prefix+key, string(raw), "NX", "PX", millis(lockTTL))
+		if err != nil || reply != nil {
+			return nil, err
+		}
+
+		reply, err = r.client.Do(ctx, "GET", r.prefix+key)
+		if err != nil {
+			return nil, err
+		}
+		if value, ok := reply.([]byte); ok {
+			var rec Record
+			if err := json.Unmarshal(value, &rec); err != nil {
+				return nil, errors.Wrap(err, "malformed idempotency record")
+			}
+			return &rec, nil
+		}
+	}
+	return nil, errors.New("failed to take the idempotency key")
+}
+
+func (r *Redis) Finish(ctx context.Context, key string, rec Record, ttl time.Duration) error {
+	rec.Done = true
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.Do(ctx, "SET", r.prefix+key, string(raw), "PX", millis(ttl))
+	return err
+}
+
+func (r *Redis) Abandon(ctx context.Context, key string) error {
+	_, err := r.client.Do(ctx, "DEL", r.prefix+key)
+	return err
+}
+
+func millis(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Millisecond), 10)
+}
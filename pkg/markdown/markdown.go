@@ -0,0 +1,165 @@
+// Package markdown renders the Markdown descriptions of todos to HTML safe
+// to embed in pages, and extracts the users and todos they refer to.
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// MaxReferences bounds the mentions, and the todos, extracted from a text.
+const MaxReferences = 100
+
+var (
+	// md leaves raw HTML out of the rendered documents, the sanitizer takes
+	// care of the URLs.
+	md = goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithParserOptions(parser.WithASTTransformers(util.Prioritized(sanitizer{}, 0))),
+	)
+
+	mention = regexp.MustCompile(`(?:^|[^\w@])@(\w(?:[\w.-]*\w)?)`)
+	todoRef = regexp.MustCompile(`(?:^|[^\w&#])#(\d+)\b`)
+
+	// safeSchemes are the schemes of the URLs links and images may have,
+	// relative URLs are fine too.
+	safeSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+)
+
+// References are the users mentioned by @username and the todos referred
+// to by #id, in the order they first appear.
+type References struct {
+	Mentions []string
+	Todos    []uint
+}
+
+// HTML renders the Markdown source, GitHub flavored. Raw HTML is left out
+// and the URLs of links and images are limited to http, https, mailto and
+// relative ones.
+func HTML(src string) (string, error) {
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(src), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Extract finds the references in the text of the Markdown source, code
+// and the text of links aside.
+func Extract(src string) References {
+	source := []byte(src)
+	doc := md.Parser().Parse(text.NewReader(source))
+
+	var (
+		refs     References
+		mentions = map[string]bool{}
+		todos    = map[uint]bool{}
+	)
+	scan := func(s string) {
+		for _, m := range mention.FindAllStringSubmatch(s, -1) {
+			if !mentions[m[1]] && len(refs.Mentions) < MaxReferences {
+				mentions[m[1]] = true
+				refs.Mentions = append(refs.Mentions, m[1])
+			}
+		}
+		for _, m := range todoRef.FindAllStringSubmatch(s, -1) {
+			id, err := strconv.ParseUint(m[1], 10, 32)
+			if err != nil || id == 0 || todos[uint(id)] || len(refs.Todos) >= MaxReferences {
+				continue
+			}
+			todos[uint(id)] = true
+			refs.Todos = append(refs.Todos, uint(id))
+		}
+	}
+
+	// the text of a paragraph is split across nodes, it is scanned a line
+	// at a time so references are not cut
+	var line strings.Builder
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch n := n.(type) {
+		case *ast.CodeSpan, *ast.Link, *ast.AutoLink, *ast.Image:
+			if entering {
+				line.WriteString(" ")
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			if entering {
+				line.Write(n.Segment.Value(source))
+				if n.SoftLineBreak() || n.HardLineBreak() {
+					scan(line.String())
+					line.Reset()
+				}
+			}
+		default:
+			if n.Type() == ast.TypeBlock {
+				scan(line.String())
+				line.Reset()
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	scan(line.String())
+	return refs
+}
+
+// sanitizer drops the URLs of links and images of unsafe schemes, and
+// turns such autolinks back to text. URLs are checked as the renderer
+// writes them, character references resolved.
+type sanitizer struct{}
+
+func (sanitizer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var unsafe []*ast.AutoLink
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Link:
+			if !safeURL(util.URLEscape(n.Destination, true)) {
+				n.Destination = nil
+			}
+		case *ast.Image:
+			if !safeURL(util.URLEscape(n.Destination, true)) {
+				n.Destination = nil
+			}
+		case *ast.AutoLink:
+			if !safeURL(util.URLEscape(n.URL(source), false)) {
+				unsafe = append(unsafe, n)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	// nodes are replaced once the walk is over
+	for _, n := range unsafe {
+		n.Parent().ReplaceChild(n.Parent(), n, ast.NewString(n.Label(source)))
+	}
+}
+
+// safeURL tells whether the URL is relative or of a safe scheme. Browsers
+// ignore the case of schemes and the whitespace and control characters in
+// them, so does safeURL.
+func safeURL(url []byte) bool {
+	var scheme strings.Builder
+	for _, c := range url {
+		switch {
+		case c <= ' ' || c == 0x7f:
+		case c == ':':
+			return safeSchemes[strings.ToLower(scheme.String())]
+		case c == '/' || c == '?' || c == '#':
+			return true
+		default:
+			scheme.WriteByte(c)
+		}
+	}
+	return true
+}
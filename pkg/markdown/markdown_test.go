@@ -0,0 +1,98 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTML(t *testing.T) {
+	cases := []struct {
+		name, src string
+		contains  []string
+		excludes  []string
+	}{
+		{
+			name:     "markdown",
+			src:      "# Plan\n\n- **one**\n- [x] two\n\n| a | b |\n|---|---|\n| 1 | 2 |",
+			contains: []string{"<h1>Plan</h1>", "<strong>one</strong>", `type="checkbox"`, "<table>"},
+		},
+		{
+			name:     "raw html",
+			src:      "<script>alert(1)</script>\n\ntext <img src=x onerror=alert(1)> <b>bold</b>",
+			contains: []string{"text"},
+			excludes: []string{"<script", "<img", "onerror", "<b>"},
+		},
+		{
+			name:     "links",
+			src:      "[ok](https://example.com/a?b=c) [rel](/todos/1) [mail](mailto:a@example.com)",
+			contains: []string{`href="https://example.com/a?b=c"`, `href="/todos/1"`, `href="mailto:a@example.com"`},
+		},
+		{
+			name:     "unsafe links",
+			src:      "[a](javascript:alert(1)) [b](JavaScript:alert(1)) [c](<java\tscript:alert(1)>) [d](data:text/html,x) ![e](vbscript:x)",
+			excludes: []string{"script:", "data:", "alert"},
+		},
+		{
+			name:     "encoded unsafe links",
+			src:      "[a](javascript&#58;alert(1)) [b](&#x6A;avascript:alert(1)) [c](javascript&colon;alert(1)) ![d](&#106;avascript:x) [e](javascript\\:alert(1))",
+			excludes: []string{"script:", "alert"},
+		},
+		{
+			name:     "unsafe autolinks",
+			src:      "<JAVASCRIPT:alert(1)> <https://example.com>",
+			contains: []string{`href="https://example.com"`},
+			excludes: []string{`href="JAVASCRIPT`},
+		},
+		{
+			name:     "escaping",
+			src:      "a < b & \"c\"",
+			contains: []string{"a &lt; b &amp; &quot;c&quot;"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			html, err := HTML(c.src)
+			require.NoError(t, err)
+			for _, s := range c.contains {
+				require.Contains(t, html, s)
+			}
+			for _, s := range c.excludes {
+				require.NotContains(t, html, s)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	cases := []struct {
+		name, src string
+		want      References
+	}{
+		{
+			name: "references",
+			src:  "@alice see #12 and #3, cc @bob.smith.\n\n- blocked by #12\n- ask @alice",
+			want: References{Mentions: []string{"alice", "bob.smith"}, Todos: []uint{12, 3}},
+		},
+		{
+			name: "across lines and emphasis",
+			src:  "thanks *@carol*\n@dave (#7)",
+			want: References{Mentions: []string{"carol", "dave"}, Todos: []uint{7}},
+		},
+		{
+			name: "not references",
+			src:  "mail a@example.com, issue#4, &#35;5, #0, #99999999999 and `@code #1`\n\n    @block #2\n\n[@link #3](https://example.com/#6)",
+			want: References{},
+		},
+		{
+			name: "headings",
+			src:  "# 1\n\n## @erin #8",
+			want: References{Mentions: []string{"erin"}, Todos: []uint{8}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.want, Extract(c.src))
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often full buckets are dropped from memory.
+const sweepInterval = time.Minute
+
+type (
+	// Memory keeps the buckets in the process, every instance limits on
+	// its own.
+	Memory struct {
+		mu      sync.Mutex
+		buckets map[string]*bucket
+		swept   time.Time
+	}
+
+	bucket struct {
+		tokens  float64
+		updated time.Time
+		full    time.Time
+	}
+)
+
+var _ Store = (*Memory)(nil)
+
+func NewMemory() *Memory {
+	return &Memory{buckets: map[string]*bucket{}}
+}
+
+func (m *Memory) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.burst()), updated: now}
+		m.buckets[key] = b
+	}
+
+	var res Result
+	b.tokens, res = take(b.tokens, b.updated, now, limit)
+	b.updated = now
+	b.full = now.Add(res.Reset)
+
+	return res, nil
+}
+
+// sweep drops the buckets which refilled, they are the same as new ones.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.swept) < sweepInterval {
+		return
+	}
+	m.swept = now
+	for key, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package ratelimit
+
+import (
+	"context"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+var (
+	// KeyGroup is the group of routes a limit applies to.
+	KeyGroup = tag.MustNewKey("group")
+
+	rejections = stats.Int64("todo/ratelimit/rejections", "Requests rejected by the rate limits", stats.UnitDimensionless)
+
+	Views = []*view.View{
+		{
+			Name:        "todo/ratelimit/rejections",
+			Description: "Requests rejected by the rate limits by group",
+			TagKeys:     []tag.Key{KeyGroup},
+			Measure:     rejections,
+			Aggregation: view.Count(),
+		},
+	}
+)
+
+func recordRejection(ctx context.Context, group string) {
+	stats.RecordWithTags(ctx, []tag.Mutator{tag.Upsert(KeyGroup, group)}, rejections.M(1))
+}
@@ -0,0 +1,131 @@
+// Package ratelimit limits the rate of requests with token buckets kept in
+// memory or in a store shared by all the instances of the service.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+const (
+	StoreMemory = "memory"
+	StoreRedis  = "redis"
+)
+
+type (
+	// Limit allows Requests per Period on average, with bursts of up to
+	// Burst requests. Burst defaults to Requests.
+	Limit struct {
+		Requests int
+		Period   time.Duration
+		Burst    int
+	}
+
+	// Result is the outcome of taking a token from a bucket.
+	Result struct {
+		Allowed   bool
+		Limit     int
+		Remaining int
+		// Reset is when the bucket is full again, RetryAfter when the next
+		// request is allowed.
+		Reset      time.Duration
+		RetryAfter time.Duration
+	}
+
+	// Store keeps the token buckets. Take removes a token from the bucket
+	// under key, refilled at the rate of limit up to its burst.
+	Store interface {
+		Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+	}
+
+	// Limiter applies the limits of named groups of routes.
+	Limiter struct {
+		store  Store
+		limits atomic.Value // map[string]Limit
+		logger log.Logger
+		now    func() time.Time
+	}
+)
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+// rate is the number of tokens added per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+func (l Limit) valid() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+func NewLimiter(store Store, limits map[string]Limit, logger log.Logger) *Limiter {
+	l := &Limiter{store: store, logger: logger, now: time.Now}
+	l.SetLimits(limits)
+	return l
+}
+
+// SetLimits replaces the limits of all the groups at once.
+func (l *Limiter) SetLimits(limits map[string]Limit) {
+	l.limits.Store(limits)
+}
+
+// Allow takes a token for the client identified by key from the bucket of
+// the group. Groups without a limit always pass, they have a nil result. A
+// failing store lets requests pass, it is not worth an outage.
+func (l *Limiter) Allow(ctx context.Context, group, key string) *Result {
+	limit, ok := l.limits.Load().(map[string]Limit)[group]
+	if !ok || !limit.valid() {
+		return nil
+	}
+
+	res, err := l.store.Take(ctx, group+":"+key, limit, l.now())
+	if err != nil {
+		l.logger.Log("event", "rate_limit_store_failed", "group", group, "error", err)
+		return nil
+	}
+	if !res.Allowed {
+		recordRejection(ctx, group)
+	}
+	return &res
+}
+
+// take refills a bucket holding tokens since last and takes a token from it,
+// returning the tokens left.
+func take(tokens float64, last, now time.Time, limit Limit) (float64, Result) {
+	if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+		tokens = math.Min(float64(limit.burst()), tokens+elapsed*limit.rate())
+	}
+
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	return tokens, result(tokens, allowed, limit)
+}
+
+// result describes a bucket holding tokens after a take.
+func result(tokens float64, allowed bool, limit Limit) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.burst(),
+		Remaining: int(tokens),
+		Reset:     seconds((float64(limit.burst()) - tokens) / limit.rate()),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / limit.rate())
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
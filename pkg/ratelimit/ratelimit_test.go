@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Neurostep/todo/pkg/cache"
+)
+
+func TestStores(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := cache.NewRedis(cache.RedisConfig{Address: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	limit := Limit{Requests: 2, Period: time.Second, Burst: 3}
+
+	for name, store := range map[string]Store{
+		"memory": NewMemory(),
+		"redis":  NewRedis(client),
+	} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Date(2022, 8, 1, 10, 0, 0, 0, time.UTC)
+
+			for i := 2; i >= 0; i-- {
+				res, err := store.Take(ctx, "user:alice", limit, now)
+				require.NoError(t, err)
+				require.True(t, res.Allowed)
+				require.Equal(t, 3, res.Limit)
+				require.Equal(t, i, res.Remaining)
+			}
+
+			res, err := store.Take(ctx, "user:alice", limit, now)
+			require.NoError(t, err)
+			require.False(t, res.Allowed, "the burst is exhausted")
+			require.Equal(t, 500*time.Millisecond, res.RetryAfter)
+			require.Equal(t, 1500*time.Millisecond, res.Reset)
+
+			res, err = store.Take(ctx, "user:bob", limit, now)
+			require.NoError(t, err)
+			require.True(t, res.Allowed, "buckets are per key")
+
+			res, err = store.Take(ctx, "user:alice", limit, now.Add(500*time.Millisecond))
+			require.NoError(t, err)
+			require.True(t, res.Allowed, "a token was added in the meantime")
+			require.Equal(t, 0, res.Remaining)
+		})
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit, time.Time) (Result, error) {
+	return Result{}, errors.New("connection refused")
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := NewLimiter(NewMemory(), map[string]Limit{
+		"auth": {Requests: 1, Period: time.Minute},
+	}, log.NewNopLogger())
+
+	require.Nil(t, l.Allow(ctx, "api", "ip:10.0.0.1"), "groups without a limit pass")
+
+	res := l.Allow(ctx, "auth", "ip:10.0.0.1")
+	require.NotNil(t, res)
+	require.True(t, res.Allowed)
+	require.False(t, l.Allow(ctx, "auth", "ip:10.0.0.1").Allowed)
+	require.True(t, l.Allow(ctx, "auth", "ip:10.0.0.2").Allowed)
+
+	l.SetLimits(map[string]Limit{"auth": {Requests: 10, Period: time.Minute}})
+	require.True(t, l.Allow(ctx, "auth", "ip:10.0.0.3").Allowed)
+
+	l = NewLimiter(failingStore{}, map[string]Limit{"auth": {Requests: 1, Period: time.Minute}}, log.NewNopLogger())
+	require.Nil(t, l.Allow(ctx, "auth", "ip:10.0.0.1"), "a failing store lets requests pass")
+}
@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// takeScript is take running atomically in the server, the bucket is a hash
+// of its tokens and the time of the last update in milliseconds.
+const takeScript = `
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(bucket[1]) or burst
+local updated = tonumber(bucket[2]) or now
+if now > updated then
+  tokens = math.min(burst, tokens + (now - updated) * rate)
+end
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate) + 1)
+return {allowed, tostring(tokens)}
+`
+
+type (
+	// Doer sends a command to a Redis compatible server, see cache.Redis.
+	Doer interface {
+		Do(ctx context.Context, args ...string) (interface{}, error)
+	}
+
+	// Redis keeps the buckets in a Redis compatible server, so the limits
+	// apply to all the instances together.
+	Redis struct {
+		client Doer
+		prefix string
+	}
+)
+
+var _ Store = (*Redis)(nil)
+
+func NewRedis(client Doer) *Redis {
+	return &Redis{client: client, prefix: "ratelimit:"}
+}
+
+func (r *Redis) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	reply, err := r.client.Do(ctx, "EVAL", takeScript, "1", r.prefix+key,
+		strconv.Itoa(limit.burst()),
+		strconv.FormatFloat(limit.rate()/1000, 'g', -1, 64),
+		strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10),
+	)
+	if err != nil {
+		return Result{}, err
+	}
+
+	items, ok := reply.([]interface{})
+	if !ok || len(items) != 2 {
+		return Result{}, errors.Errorf("unexpected reply %v to the rate limit script", reply)
+	}
+	allowed, _ := items[0].(int64)
+	raw, _ := items[1].([]byte)
+	tokens, err := strconv.ParseFloat(string(raw), 64)
+	if err != nil {
+		return Result{}, errors.Wrap(err, "unexpected reply to the rate limit script")
+	}
+
+	return result(tokens, allowed == 1, limit), nil
+}
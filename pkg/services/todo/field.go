@@ -0,0 +1,242 @@
+package todo
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Neurostep/todo/pkg/types"
+)
+
+// Priority ranks todos from P0, the most urgent, to P4.
+type Priority uint8
+
+const (
+	PriorityP0 Priority = iota
+	PriorityP1
+	PriorityP2
+	PriorityP3
+	PriorityP4
+
+	// DefaultPriority is the priority of the todos created without one.
+	DefaultPriority = PriorityP2
+)
+
+func (p Priority) String() string {
+	return fmt.Sprintf("P%d", p)
+}
+
+// ParsePriority parses the P0 to P4 form of a priority.
+func ParsePriority(s string) (Priority, error) {
+	if len(s) == 2 && s[0] == 'P' && s[1] >= '0' && s[1] <= '4' {
+		return Priority(s[1] - '0'), nil
+	}
+	return 0, &ValidationError{Field: "priority", Message: "must be one of P0, P1, P2, P3, P4"}
+}
+
+// FieldType is the type of the values of a custom field.
+type FieldType string
+
+const (
+	FieldText   FieldType = "text"
+	FieldNumber FieldType = "number"
+	// FieldEnum values are one of the options of the field
+	FieldEnum FieldType = "enum"
+	// FieldDate values are dates in types.DueDateFormat
+	FieldDate FieldType = "date"
+)
+
+// FieldTypes lists the types of custom fields.
+var FieldTypes = []FieldType{FieldText, FieldNumber, FieldEnum, FieldDate}
+
+// maxFieldText is the length of the longest text value of a custom field.
+const maxFieldText = 2047
+
+// fieldName restricts the names of custom fields, which are inlined in the
+// JSON paths of the queries.
+var fieldName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+type (
+	// Field is a custom field of the todos of a project.
+	Field struct {
+		ID        uint `gorm:"primary_key"`
+		ProjectId uint
+		Name      string    `gorm:"name"`
+		Type      FieldType `gorm:"type"`
+		// Options are the values of enum fields
+		Options Options `gorm:"type:text"`
+		// Required fields are set on every todo created or updated
+		Required bool `gorm:"required"`
+	}
+
+	// Options are stored as a JSON array.
+	Options []string
+
+	// Fields are the values of the custom fields of a todo by their names,
+	// strings for text, enum and date fields and float64 for number ones.
+	// They are stored as a JSON object, jsonb on Postgres.
+	Fields map[string]interface{}
+
+	// ValidationError is returned when a value does not fit its field, a
+	// custom one or priority.
+	ValidationError struct {
+		Field   string
+		Message string
+	}
+)
+
+func (f Field) TableName() string {
+	return "fields"
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+func (o Options) Value() (driver.Value, error) {
+	if o == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]string(o))
+	return string(b), err
+}
+
+func (o *Options) Scan(src interface{}) error {
+	return scanJSON(src, o)
+}
+
+func (f Fields) Value() (driver.Value, error) {
+	if f == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(map[string]interface{}(f))
+	return string(b), err
+}
+
+func (f *Fields) Scan(src interface{}) error {
+	return scanJSON(src, f)
+}
+
+// scanJSON decodes a JSON column, text on SQLite and jsonb on Postgres.
+func scanJSON(src interface{}, v interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(src, v)
+	case string:
+		return json.Unmarshal([]byte(src), v)
+	}
+	return errors.Errorf("unsupported JSON column of %T", src)
+}
+
+// parse converts a value of the field, decoded from JSON or taken from a
+// query string, to its stored form.
+func (f *Field) parse(v interface{}) (interface{}, error) {
+	invalid := func(message string) error {
+		return &ValidationError{Field: "fields." + f.Name, Message: message}
+	}
+
+	if f.Type == FieldNumber {
+		switch n := v.(type) {
+		case float64:
+			if math.IsNaN(n) || math.IsInf(n, 0) {
+				return nil, invalid("must be a number")
+			}
+			return n, nil
+		case string:
+			parsed, err := strconv.ParseFloat(n, 64)
+			if err != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+				return nil, invalid("must be a number")
+			}
+			return parsed, nil
+		}
+		return nil, invalid("must be a number")
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return nil, invalid("must be a string")
+	}
+	switch f.Type {
+	case FieldText:
+		if len([]rune(s)) > maxFieldText {
+			return nil, invalid(fmt.Sprintf("must be at most %d characters long", maxFieldText))
+		}
+	case FieldEnum:
+		for _, option := range f.Options {
+			if s == option {
+				return s, nil
+			}
+		}
+		return nil, invalid("must be one of " + strings.Join(f.Options, ", "))
+	case FieldDate:
+		if _, err := time.Parse(types.DueDateFormat, s); err != nil {
+			return nil, invalid(fmt.Sprintf("must be a date in %s format", types.DueDateFormat))
+		}
+	}
+	return s, nil
+}
+
+// validate checks the definition of a custom field.
+func (f *Field) validate() error {
+	if !fieldName.MatchString(f.Name) {
+		return &ValidationError{Field: "name", Message: "must be lowercase letters, digits and underscores, starting with a letter"}
+	}
+	known := false
+	for _, t := range FieldTypes {
+		known = known || f.Type == t
+	}
+	if !known {
+		return &ValidationError{Field: "type", Message: "must be one of text, number, enum, date"}
+	}
+	if f.Type == FieldEnum && len(f.Options) == 0 {
+		return &ValidationError{Field: "options", Message: "are required by enum fields"}
+	}
+	if f.Type != FieldEnum && len(f.Options) != 0 {
+		return &ValidationError{Field: "options", Message: "are only allowed for enum fields"}
+	}
+	return nil
+}
+
+// parseFields checks the values against the custom fields of the project,
+// and returns them in their stored form.
+func parseFields(defs []Field, values map[string]interface{}) (Fields, error) {
+	fields := Fields{}
+	for name, v := range values {
+		def := findField(defs, name)
+		if def == nil {
+			return nil, &ValidationError{Field: "fields." + name, Message: "is not a field of the project"}
+		}
+		if v == nil {
+			continue
+		}
+		parsed, err := def.parse(v)
+		if err != nil {
+			return nil, err
+		}
+		fields[name] = parsed
+	}
+	for _, def := range defs {
+		if _, ok := fields[def.Name]; def.Required && !ok {
+			return nil, &ValidationError{Field: "fields." + def.Name, Message: "is required"}
+		}
+	}
+	return fields, nil
+}
+
+func findField(defs []Field, name string) *Field {
+	for i := range defs {
+		if defs[i].Name == name {
+			return &defs[i]
+		}
+	}
+	return nil
+}
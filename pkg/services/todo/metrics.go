@@ -0,0 +1,106 @@
+package todo
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const (
+	outcomeSuccess  = "success"
+	outcomeNotFound = "not_found"
+	outcomeError    = "error"
+)
+
+var (
+	// KeyOperation is the operation of the service or of the repository,
+	// KeyOutcome is success, not_found or error.
+	KeyOperation = tag.MustNewKey("operation")
+	KeyOutcome   = tag.MustNewKey("outcome")
+
+	operations     = stats.Int64("todo/service/operations", "Operations of the todo service", stats.UnitDimensionless)
+	completionTime = stats.Float64("todo/todos/completion_time", "Time from the creation of todos to their completion", stats.UnitSeconds)
+	overdue        = stats.Int64("todo/todos/overdue", "Todos not done past their due date", stats.UnitDimensionless)
+	dbLatency      = stats.Float64("todo/db/latency", "Latency of the operations of the repository", stats.UnitMilliseconds)
+
+	// Views count the operations of the service, such as the todos
+	// created, completed and deleted and the comments and labels added,
+	// and give the distributions of the completion time and of the
+	// latency of the repository. The overdue todos are recorded by
+	// RecordOverdue.
+	Views = []*view.View{
+		{
+			Name:        "todo/service/operations",
+			Description: "Operations of the todo service by operation and outcome",
+			TagKeys:     []tag.Key{KeyOperation, KeyOutcome},
+			Measure:     operations,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        "todo/todos/completion_time",
+			Description: "Distribution of the time from the creation of todos to their completion",
+			Measure:     completionTime,
+			// a minute, ten minutes, an hour, four hours, a day, three days,
+			// a week and a month
+			Aggregation: view.Distribution(60, 600, 3600, 14400, 86400, 259200, 604800, 2592000),
+		},
+		{
+			Name:        "todo/todos/overdue",
+			Description: "Todos not done past their due date",
+			Measure:     overdue,
+			Aggregation: view.LastValue(),
+		},
+		{
+			Name:        "todo/db/latency",
+			Description: "Distribution of the latency of the repository by operation and outcome",
+			TagKeys:     []tag.Key{KeyOperation, KeyOutcome},
+			Measure:     dbLatency,
+			Aggregation: view.Distribution(1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000),
+		},
+	}
+)
+
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return outcomeSuccess
+	case errors.Is(err, ErrNotFound):
+		return outcomeNotFound
+	default:
+		return outcomeError
+	}
+}
+
+func recordOperation(ctx context.Context, operation string, err error) {
+	stats.RecordWithTags(ctx, []tag.Mutator{
+		tag.Upsert(KeyOperation, operation),
+		tag.Upsert(KeyOutcome, outcome(err)),
+	}, operations.M(1))
+}
+
+func recordCompletion(ctx context.Context, d time.Duration) {
+	stats.Record(ctx, completionTime.M(d.Seconds()))
+}
+
+// RecordOverdue records the number of overdue todos every interval until
+// ctx is done.
+func RecordOverdue(ctx context.Context, repo Repository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if count, err := repo.CountOverdue(ctx, time.Now()); err == nil {
+			stats.Record(ctx, overdue.M(int64(count)))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package todo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+func operationCount(t *testing.T, operation, outcome string) int64 {
+	rows, err := view.RetrieveData("todo/service/operations")
+	require.NoError(t, err)
+	for _, row := range rows {
+		if hasTags(row.Tags, operation, outcome) {
+			return row.Data.(*view.CountData).Value
+		}
+	}
+	return 0
+}
+
+func hasTags(tags []tag.Tag, operation, outcome string) bool {
+	found := 0
+	for _, t := range tags {
+		if (t.Key == KeyOperation && t.Value == operation) || (t.Key == KeyOutcome && t.Value == outcome) {
+			found++
+		}
+	}
+	return found == 2
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, view.Register(Views...))
+	t.Cleanup(func() { view.Unregister(Views...) })
+
+	repo := NewMemoryRepository()
+	s := New(Config{Repository: NewInstrumentedRepository(repo), Logger: log.NewNopLogger()})
+
+	td, err := s.CreateTodo(ctx, &CreateTodo{Title: "first", DueDate: dueDate("2022-08-01")})
+	require.NoError(t, err)
+	require.False(t, td.CreatedAt.IsZero())
+	_, err = s.CreateTodo(ctx, &CreateTodo{Title: "second", DueDate: dueDate("2022-08-01")})
+	require.NoError(t, err)
+
+	_, err = s.UpdateTodo(ctx, &UpdateTodo{Id: td.ID, Title: "first", DueDate: dueDate("2022-08-01"), Done: true})
+	require.NoError(t, err)
+	_, err = s.UpdateTodo(ctx, &UpdateTodo{Id: td.ID, Title: "first", DueDate: dueDate("2022-08-01"), Done: true})
+	require.NoError(t, err)
+	_, err = s.GetTodo(ctx, 1000)
+	require.Equal(t, ErrNotFound, err)
+
+	require.EqualValues(t, 2, operationCount(t, "create_todo", outcomeSuccess))
+	require.EqualValues(t, 2, operationCount(t, "update_todo", outcomeSuccess))
+	require.EqualValues(t, 1, operationCount(t, "complete_todo", outcomeSuccess), "only the transition to done completes")
+	require.EqualValues(t, 1, operationCount(t, "get_todo", outcomeNotFound))
+
+	got, err := s.GetTodo(ctx, td.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.CompletedAt)
+
+	rows, err := view.RetrieveData("todo/todos/completion_time")
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.EqualValues(t, 1, rows[0].Data.(*view.DistributionData).Count)
+
+	rows, err = view.RetrieveData("todo/db/latency")
+	require.NoError(t, err)
+	require.NotEmpty(t, rows, "the repository records its latency")
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	RecordOverdue(ctx, repo, time.Hour)
+	rows, err = view.RetrieveData("todo/todos/overdue")
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.EqualValues(t, 1, rows[0].Data.(*view.LastValueData).Value)
+}
@@ -0,0 +1,62 @@
+package todo
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Events of the notifications.
+const (
+	EventTodoUpdated   = "todo_updated"
+	EventTodoCompleted = "todo_completed"
+	EventTodoAssigned  = "todo_assigned"
+	EventTodoDeleted   = "todo_deleted"
+	EventCommentAdded  = "comment_added"
+)
+
+type (
+	// Notification tells the watchers of a todo about a change made by
+	// Actor, the user who made it is not among the Recipients.
+	Notification struct {
+		Event      string
+		TodoId     uint
+		Title      string
+		Actor      string
+		Recipients []string
+	}
+
+	// Notifier delivers the notifications. It is called by the requests
+	// making the changes, so it should hand slow deliveries off.
+	Notifier interface {
+		Notify(ctx context.Context, n Notification) error
+	}
+
+	logNotifier struct {
+		logger log.Logger
+	}
+)
+
+// NewLogNotifier creates a notifier writing the notifications to logger,
+// one entry per notification.
+func NewLogNotifier(logger log.Logger) Notifier {
+	return &logNotifier{logger: logger}
+}
+
+func (n *logNotifier) Notify(ctx context.Context, notification Notification) error {
+	return n.logger.Log("event", "notification", "notification", notification.Event,
+		"todo_id", notification.TodoId, "actor", notification.Actor, "recipients", notification.Recipients)
+}
+
+type userKey struct{}
+
+// WithUser tags ctx with the user making the request, who is not notified
+// of the changes the request makes and who watches the todos it comments.
+func WithUser(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, userKey{}, username)
+}
+
+func userFromContext(ctx context.Context) string {
+	username, _ := ctx.Value(userKey{}).(string)
+	return username
+}
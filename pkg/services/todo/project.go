@@ -0,0 +1,64 @@
+package todo
+
+import (
+	"time"
+)
+
+// Role is the part a member plays in a project, it tells what the member
+// may do with it.
+type Role string
+
+const (
+	// RoleOwner manages the project and its members
+	RoleOwner Role = "owner"
+	// RoleEditor changes the todos of the project
+	RoleEditor Role = "editor"
+	// RoleCommenter comments the todos of the project
+	RoleCommenter Role = "commenter"
+	// RoleViewer reads the project
+	RoleViewer Role = "viewer"
+)
+
+// Roles lists the roles from the most to the least privileged.
+var Roles = []Role{RoleOwner, RoleEditor, RoleCommenter, RoleViewer}
+
+type (
+	// Project groups todos shared by its members.
+	Project struct {
+		ID        uint      `gorm:"primary_key"`
+		Name      string    `gorm:"name"`
+		CreatedAt time.Time `gorm:"created_at"`
+	}
+
+	// Membership grants a user a role in a project.
+	Membership struct {
+		ID        uint `gorm:"primary_key"`
+		ProjectId uint
+		Username  string `gorm:"username"`
+		Role      Role   `gorm:"role"`
+	}
+
+	// Invitation offers a user a role in a project, the user becomes a
+	// member by accepting it.
+	Invitation struct {
+		ID        uint `gorm:"primary_key"`
+		ProjectId uint
+		Username  string `gorm:"username"`
+		Role      Role   `gorm:"role"`
+		// InvitedBy is the user who invited
+		InvitedBy string    `gorm:"invited_by"`
+		CreatedAt time.Time `gorm:"created_at"`
+	}
+)
+
+func (p Project) TableName() string {
+	return "projects"
+}
+
+func (m Membership) TableName() string {
+	return "memberships"
+}
+
+func (i Invitation) TableName() string {
+	return "invitations"
+}
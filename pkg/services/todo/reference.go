@@ -0,0 +1,23 @@
+package todo
+
+// Kinds of references.
+const (
+	// ReferenceMention is a user mentioned by @username
+	ReferenceMention = "mention"
+	// ReferenceTodo is a todo referred to by #id
+	ReferenceTodo = "todo"
+)
+
+// Reference is a user or a todo the description of a todo refers to.
+type Reference struct {
+	ID     uint `gorm:"primary_key"`
+	TodoId uint
+	Kind   string `gorm:"kind"`
+	// Username is the user mentioned, TargetId the todo referred to
+	Username string `gorm:"username"`
+	TargetId uint   `gorm:"target_id"`
+}
+
+func (r Reference) TableName() string {
+	return "todo_references"
+}
@@ -0,0 +1,96 @@
+package todo
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrNotFound is returned when the todo, comment, label, project,
+	// membership, invitation, watcher or custom field doesn't exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when the user is already a member of the
+	// project, already invited to it or already watching the todo, or when
+	// the project has a custom field of the name already.
+	ErrConflict = errors.New("already exists")
+	// ErrLastOwner is returned when a change would leave a project without
+	// an owner.
+	ErrLastOwner = errors.New("the project must keep an owner")
+)
+
+// Repository persists todos along with their comments and labels, and the
+// projects sharing them with their members. There are
+// gorm based implementations for Postgres and SQLite, and a pure in-memory one.
+type Repository interface {
+	// CreateTodo stores the todo, the project of the todo must exist.
+	CreateTodo(ctx context.Context, todo *Todo) error
+	// UpdateTodo overwrites the stored todo with the same ID.
+	UpdateTodo(ctx context.Context, todo *Todo) error
+	// DeleteTodo removes the todo with its comments, labels, watchers and
+	// references, the ones to the todo included.
+	DeleteTodo(ctx context.Context, id uint) error
+	GetTodo(ctx context.Context, id uint) (*Todo, error)
+	// FindTodos and CountTodos address the todos selected by the filter,
+	// FindTodos returns them in the order of pg.Order.
+	FindTodos(ctx context.Context, pg PaginateTodos) ([]Todo, error)
+	CountTodos(ctx context.Context, f TodoFilter) (int, error)
+	// CountOverdue counts the todos not done and due before now.
+	CountOverdue(ctx context.Context, now time.Time) (int, error)
+
+	AddComment(ctx context.Context, comment *Comment) error
+	RemoveComment(ctx context.Context, todoId, id uint) error
+	FindComments(ctx context.Context, todoId uint, limit int) ([]Comment, error)
+
+	AddLabel(ctx context.Context, label *Label) error
+	RemoveLabel(ctx context.Context, todoId, id uint) error
+	FindLabels(ctx context.Context, todoId uint, limit int) ([]Label, error)
+
+	// AddWatcher returns ErrConflict when the user watches the todo already.
+	AddWatcher(ctx context.Context, watcher *Watcher) error
+	RemoveWatcher(ctx context.Context, todoId, id uint) error
+	FindWatchers(ctx context.Context, todoId uint) ([]Watcher, error)
+
+	// SetReferences replaces the references of the todo.
+	SetReferences(ctx context.Context, todoId uint, refs []Reference) error
+	FindReferences(ctx context.Context, todoId uint) ([]Reference, error)
+	// FindBackReferences returns the references to the todo.
+	FindBackReferences(ctx context.Context, todoId uint) ([]Reference, error)
+
+	// CreateField returns ErrConflict when the project has a field of the
+	// same name already.
+	CreateField(ctx context.Context, field *Field) error
+	FindFields(ctx context.Context, projectId uint) ([]Field, error)
+	// DeleteField removes the field with its values from the todos of the
+	// project.
+	DeleteField(ctx context.Context, projectId, id uint) error
+
+	// CreateProject stores the project, with owner as its owner unless it
+	// is empty.
+	CreateProject(ctx context.Context, project *Project, owner string) error
+	UpdateProject(ctx context.Context, project *Project) error
+	GetProject(ctx context.Context, id uint) (*Project, error)
+	// FindProjects returns the projects username is a member of, all of
+	// them when it is empty.
+	FindProjects(ctx context.Context, username string) ([]Project, error)
+
+	GetMembership(ctx context.Context, projectId uint, username string) (*Membership, error)
+	FindMemberships(ctx context.Context, projectId uint) ([]Membership, error)
+	// UpdateMembership changes the role of the membership with the ID and
+	// project of membership, and RemoveMembership removes one. Both return
+	// ErrLastOwner rather than leave the project without an owner.
+	UpdateMembership(ctx context.Context, membership *Membership) error
+	RemoveMembership(ctx context.Context, projectId, id uint) error
+
+	// CreateInvitation returns ErrConflict when the user is a member of the
+	// project or invited to it already.
+	CreateInvitation(ctx context.Context, invitation *Invitation) error
+	GetInvitation(ctx context.Context, id uint) (*Invitation, error)
+	// FindInvitations returns the invitations to the project, or of the
+	// user, zero values match any.
+	FindInvitations(ctx context.Context, projectId uint, username string) ([]Invitation, error)
+	// AcceptInvitation turns the invitation into a membership.
+	AcceptInvitation(ctx context.Context, id uint) (*Membership, error)
+	DeleteInvitation(ctx context.Context, id uint) error
+}
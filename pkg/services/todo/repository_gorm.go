@@ -0,0 +1,481 @@
+package todo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/Neurostep/todo/pkg/database"
+)
+
+type gormRepository struct {
+	db *gorm.DB
+}
+
+var _ Repository = (*gormRepository)(nil)
+
+// NewGormRepository creates a repository on top of a gorm connection to
+// Postgres or SQLite.
+func NewGormRepository(db *gorm.DB) Repository {
+	return &gormRepository{db: db}
+}
+
+// AutoMigrate creates the tables of the repository. Postgres schema is
+// managed by the migrations instead, this is meant for SQLite.
+func AutoMigrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Todo{}, &Comment{}, &Label{}, &Project{}, &Membership{}, &Invitation{}, &Watcher{}, &Field{}, &Reference{}).Error
+}
+
+// conn returns the connection running statements under ctx, so they are
+// not started once the request is canceled.
+func (r *gormRepository) conn(ctx context.Context) *gorm.DB {
+	return database.WithContext(ctx, r.db)
+}
+
+func (r *gormRepository) CreateTodo(ctx context.Context, todo *Todo) error {
+	if todo.ProjectId == 0 {
+		return r.conn(ctx).Create(todo).Error
+	}
+	return transaction(r.conn(ctx), func(tx *gorm.DB) error {
+		if err := projectExists(tx, todo.ProjectId); err != nil {
+			return err
+		}
+		return tx.Create(todo).Error
+	})
+}
+
+func (r *gormRepository) UpdateTodo(ctx context.Context, todo *Todo) error {
+	res := r.conn(ctx).Model(&Todo{}).Scopes(withTodoID(todo.ID)).Updates(map[string]interface{}{
+		"title":            todo.Title,
+		"description":      todo.Description,
+		"due_date":         todo.DueDate,
+		"done":             todo.Done,
+		"completed_at":     todo.CompletedAt,
+		"assignee":         todo.Assignee,
+		"priority":         todo.Priority,
+		"estimate_minutes": todo.EstimateMinutes,
+		"fields":           todo.Fields,
+	})
+	return affected(res)
+}
+
+func (r *gormRepository) DeleteTodo(ctx context.Context, id uint) error {
+	return transaction(r.conn(ctx), func(tx *gorm.DB) error {
+		if err := tx.Where("todo_id = ?", id).Delete(&Comment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("todo_id = ?", id).Delete(&Label{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("todo_id = ?", id).Delete(&Watcher{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("todo_id = ? OR (kind = ? AND target_id = ?)", id, ReferenceTodo, id).Delete(&Reference{}).Error; err != nil {
+			return err
+		}
+		return affected(tx.Scopes(withTodoID(id)).Delete(&Todo{}))
+	})
+}
+
+func (r *gormRepository) GetTodo(ctx context.Context, id uint) (*Todo, error) {
+	td := &Todo{}
+	err := r.conn(ctx).Scopes(withTodoID(id)).First(td).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return td, nil
+}
+
+func (r *gormRepository) FindTodos(ctx context.Context, pg PaginateTodos) ([]Todo, error) {
+	todos := []Todo{}
+	err := r.conn(ctx).Scopes(withFilter(pg.TodoFilter)).Scopes(buildPaginatedScope(pg)...).Scopes(withOrder(pg.Order)).Find(&todos).Error
+
+	return todos, err
+}
+
+func (r *gormRepository) CountTodos(ctx context.Context, f TodoFilter) (int, error) {
+	var count int
+	err := r.conn(ctx).Model(&Todo{}).Scopes(withFilter(f)).Count(&count).Error
+
+	return count, err
+}
+
+func (r *gormRepository) CountOverdue(ctx context.Context, now time.Time) (int, error) {
+	var count int
+	err := r.conn(ctx).Model(&Todo{}).Where("done = ? AND due_date < ?", false, now).Count(&count).Error
+
+	return count, err
+}
+
+func (r *gormRepository) AddComment(ctx context.Context, comment *Comment) error {
+	return transaction(r.conn(ctx), func(tx *gorm.DB) error {
+		if err := todoExists(tx, comment.TodoId); err != nil {
+			return err
+		}
+		return tx.Create(comment).Error
+	})
+}
+
+func (r *gormRepository) RemoveComment(ctx context.Context, todoId, id uint) error {
+	return affected(r.conn(ctx).Where("id = ? AND todo_id = ?", id, todoId).Delete(&Comment{}))
+}
+
+func (r *gormRepository) FindComments(ctx context.Context, todoId uint, limit int) ([]Comment, error) {
+	comments := []Comment{}
+	err := r.conn(ctx).Where("todo_id = ?", todoId).Order("id").Limit(limit).Find(&comments).Error
+
+	return comments, err
+}
+
+func (r *gormRepository) AddLabel(ctx context.Context, label *Label) error {
+	return transaction(r.conn(ctx), func(tx *gorm.DB) error {
+		if err := todoExists(tx, label.TodoId); err != nil {
+			return err
+		}
+		return tx.Create(label).Error
+	})
+}
+
+func (r *gormRepository) RemoveLabel(ctx context.Context, todoId, id uint) error {
+	return affected(r.conn(ctx).Where("id = ? AND todo_id = ?", id, todoId).Delete(&Label{}))
+}
+
+func (r *gormRepository) FindLabels(ctx context.Context, todoId uint, limit int) ([]Label, error) {
+	labels := []Label{}
+	err := r.conn(ctx).Where("todo_id = ?", todoId).Order("id").Limit(limit).Find(&labels).Error
+
+	return labels, err
+}
+
+func (r *gormRepository) AddWatcher(ctx context.Context, watcher *Watcher) error {
+	return transaction(r.conn(ctx), func(tx *gorm.DB) error {
+		if err := todoExists(tx, watcher.TodoId); err != nil {
+			return err
+		}
+		var count int
+		if err := tx.Model(&Watcher{}).Where("todo_id = ? AND username = ?", watcher.TodoId, watcher.Username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != 0 {
+			return ErrConflict
+		}
+		return tx.Create(watcher).Error
+	})
+}
+
+func (r *gormRepository) RemoveWatcher(ctx context.Context, todoId, id uint) error {
+	return affected(r.conn(ctx).Where("id = ? AND todo_id = ?", id, todoId).Delete(&Watcher{}))
+}
+
+func (r *gormRepository) FindWatchers(ctx context.Context, todoId uint) ([]Watcher, error) {
+	watchers := []Watcher{}
+	err := r.conn(ctx).Where("todo_id = ?", todoId).Order("id").Find(&watchers).Error
+
+	return watchers, err
+}
+
+func (r *gormRepository) SetReferences(ctx context.Context, todoId uint, refs []Reference) error {
+	return transaction(r.conn(ctx), func(tx *gorm.DB) error {
+		if err := todoExists(tx, todoId); err != nil {
+			return err
+		}
+		if err := tx.Where("todo_id = ?", todoId).Delete(&Reference{}).Error; err != nil {
+			return err
+		}
+		for i := range refs {
+			refs[i].ID = 0
+			refs[i].TodoId = todoId
+			if err := tx.Create(&refs[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *gormRepository) FindReferences(ctx context.Context, todoId uint) ([]Reference, error) {
+	refs := []Reference{}
+	err := r.conn(ctx).Where("todo_id = ?", todoId).Order("id").Find(&refs).Error
+
+	return refs, err
+}
+
+func (r *gormRepository) FindBackReferences(ctx context.Context, todoId uint) ([]Reference, error) {
+	refs := []Reference{}
+	err := r.conn(ctx).Where("kind = ? AND target_id = ?", ReferenceTodo, todoId).Order("todo_id").Find(&refs).Error
+
+	return refs, err
+}
+
+func (r *gormRepository) CreateField(ctx context.Context, field *Field) error {
+	return transaction(r.conn(ctx), func(tx *gorm.DB) error {
+		if err := projectExists(tx, field.ProjectId); err != nil {
+			return err
+		}
+		var count int
+		if err := tx.Model(&Field{}).Where("project_id = ? AND name = ?", field.ProjectId, field.Name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != 0 {
+			return ErrConflict
+		}
+		return tx.Create(field).Error
+	})
+}
+
+func (r *gormRepository) FindFields(ctx context.Context, projectId uint) ([]Field, error) {
+	fields := []Field{}
+	err := r.conn(ctx).Where("project_id = ?", projectId).Order("id").Find(&fields).Error
+
+	return fields, err
+}
+
+func (r *gormRepository) DeleteField(ctx context.Context, projectId, id uint) error {
+	return transaction(r.conn(ctx), func(tx *gorm.DB) error {
+		var field Field
+		err := tx.Where("id = ? AND project_id = ?", id, projectId).First(&field).Error
+		if gorm.IsRecordNotFoundError(err) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&field).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Todo{}).Where("project_id = ?", projectId).
+			UpdateColumn("fields", withoutField(tx, field.Name)).Error
+	})
+}
+
+func (r *gormRepository) CreateProject(ctx context.Context, project *Project, owner string) error {
+	return transaction(r.conn(ctx), func(tx *gorm.DB) error {
+		if err := tx.Create(project).Error; err != nil {
+			return err
+		}
+		if owner == "" {
+			return nil
+		}
+		return tx.Create(&Membership{ProjectId: project.ID, Username: owner, Role: RoleOwner}).Error
+	})
+}
+
+func (r *gormRepository) UpdateProject(ctx context.Context, project *Project) error {
+	return affected(r.conn(ctx).Model(&Project{}).Where("id = ?", project.ID).Update("name", project.Name))
+}
+
+func (r *gormRepository) GetProject(ctx context.Context, id uint) (*Project, error) {
+	p := &Project{}
+	err := r.conn(ctx).Where("id = ?", id).First(p).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *gormRepository) FindProjects(ctx context.Context, username string) ([]Project, error) {
+	projects := []Project{}
+	q := r.conn(ctx)
+	if username != "" {
+		q = q.Where("id IN (?)", r.conn(ctx).Model(&Membership{}).Select("project_id").Where("username = ?", username).QueryExpr())
+	}
+	err := q.Order("id").Find(&projects).Error
+
+	return projects, err
+}
+
+func (r *gormRepository) GetMembership(ctx context.Context, projectId uint, username string) (*Membership, error) {
+	m := &Membership{}
+	err := r.conn(ctx).Where("project_id = ? AND username = ?", projectId, username).First(m).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (r *gormRepository) FindMemberships(ctx context.Context, projectId uint) ([]Membership, error) {
+	members := []Membership{}
+	err := r.conn(ctx).Where("project_id = ?", projectId).Order("id").Find(&members).Error
+
+	return members, err
+}
+
+func (r *gormRepository) UpdateMembership(ctx context.Context, membership *Membership) error {
+	return transaction(r.conn(ctx), func(tx *gorm.DB) error {
+		prev := &Membership{}
+		if err := findMembership(tx, membership.ProjectId, membership.ID, prev); err != nil {
+			return err
+		}
+		if prev.Role == RoleOwner && membership.Role != RoleOwner {
+			if err := otherOwner(tx, prev); err != nil {
+				return err
+			}
+		}
+		membership.Username = prev.Username
+		return tx.Model(prev).Update("role", membership.Role).Error
+	})
+}
+
+func (r *gormRepository) RemoveMembership(ctx context.Context, projectId, id uint) error {
+	return transaction(r.conn(ctx), func(tx *gorm.DB) error {
+		m := &Membership{}
+		if err := findMembership(tx, projectId, id, m); err != nil {
+			return err
+		}
+		if m.Role == RoleOwner {
+			if err := otherOwner(tx, m); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(m).Error
+	})
+}
+
+func (r *gormRepository) CreateInvitation(ctx context.Context, invitation *Invitation) error {
+	return transaction(r.conn(ctx), func(tx *gorm.DB) error {
+		if err := projectExists(tx, invitation.ProjectId); err != nil {
+			return err
+		}
+		var members, invitations int
+		if err := tx.Model(&Membership{}).Where("project_id = ? AND username = ?", invitation.ProjectId, invitation.Username).Count(&members).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&Invitation{}).Where("project_id = ? AND username = ?", invitation.ProjectId, invitation.Username).Count(&invitations).Error; err != nil {
+			return err
+		}
+		if members+invitations != 0 {
+			return ErrConflict
+		}
+		return tx.Create(invitation).Error
+	})
+}
+
+func (r *gormRepository) GetInvitation(ctx context.Context, id uint) (*Invitation, error) {
+	inv := &Invitation{}
+	err := r.conn(ctx).Where("id = ?", id).First(inv).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (r *gormRepository) FindInvitations(ctx context.Context, projectId uint, username string) ([]Invitation, error) {
+	invitations := []Invitation{}
+	q := r.conn(ctx)
+	if projectId != 0 {
+		q = q.Where("project_id = ?", projectId)
+	}
+	if username != "" {
+		q = q.Where("username = ?", username)
+	}
+	err := q.Order("id").Find(&invitations).Error
+
+	return invitations, err
+}
+
+func (r *gormRepository) AcceptInvitation(ctx context.Context, id uint) (*Membership, error) {
+	m := &Membership{}
+	err := transaction(r.conn(ctx), func(tx *gorm.DB) error {
+		inv := &Invitation{}
+		err := tx.Where("id = ?", id).First(inv).Error
+		if gorm.IsRecordNotFoundError(err) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(inv).Error; err != nil {
+			return err
+		}
+		*m = Membership{ProjectId: inv.ProjectId, Username: inv.Username, Role: inv.Role}
+		return tx.Create(m).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (r *gormRepository) DeleteInvitation(ctx context.Context, id uint) error {
+	return affected(r.conn(ctx).Where("id = ?", id).Delete(&Invitation{}))
+}
+
+func transaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	tx := database.Begin(db)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func todoExists(tx *gorm.DB, id uint) error {
+	var count int
+	if err := tx.Model(&Todo{}).Scopes(withTodoID(id)).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func projectExists(tx *gorm.DB, id uint) error {
+	var count int
+	if err := tx.Model(&Project{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func findMembership(tx *gorm.DB, projectId, id uint, m *Membership) error {
+	err := tx.Where("id = ? AND project_id = ?", id, projectId).First(m).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// otherOwner returns ErrLastOwner unless the project of m has an owner
+// besides m.
+func otherOwner(tx *gorm.DB, m *Membership) error {
+	var count int
+	err := tx.Model(&Membership{}).Where("project_id = ? AND role = ? AND id <> ?", m.ProjectId, RoleOwner, m.ID).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// affected turns a statement which changed no rows into ErrNotFound.
+func affected(res *gorm.DB) error {
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
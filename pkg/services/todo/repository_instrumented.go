@@ -0,0 +1,203 @@
+package todo
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+type instrumentedRepository struct {
+	repo Repository
+}
+
+var _ Repository = (*instrumentedRepository)(nil)
+
+// NewInstrumentedRepository records the latency of the operations of repo
+// by operation and outcome, see Views.
+func NewInstrumentedRepository(repo Repository) Repository {
+	return &instrumentedRepository{repo: repo}
+}
+
+func (r *instrumentedRepository) observe(ctx context.Context, operation string, start time.Time, err *error) {
+	stats.RecordWithTags(ctx, []tag.Mutator{
+		tag.Upsert(KeyOperation, operation),
+		tag.Upsert(KeyOutcome, outcome(*err)),
+	}, dbLatency.M(float64(time.Since(start))/float64(time.Millisecond)))
+}
+
+func (r *instrumentedRepository) CreateTodo(ctx context.Context, todo *Todo) (err error) {
+	defer r.observe(ctx, "create_todo", time.Now(), &err)
+	return r.repo.CreateTodo(ctx, todo)
+}
+
+func (r *instrumentedRepository) UpdateTodo(ctx context.Context, todo *Todo) (err error) {
+	defer r.observe(ctx, "update_todo", time.Now(), &err)
+	return r.repo.UpdateTodo(ctx, todo)
+}
+
+func (r *instrumentedRepository) DeleteTodo(ctx context.Context, id uint) (err error) {
+	defer r.observe(ctx, "delete_todo", time.Now(), &err)
+	return r.repo.DeleteTodo(ctx, id)
+}
+
+func (r *instrumentedRepository) GetTodo(ctx context.Context, id uint) (_ *Todo, err error) {
+	defer r.observe(ctx, "get_todo", time.Now(), &err)
+	return r.repo.GetTodo(ctx, id)
+}
+
+func (r *instrumentedRepository) FindTodos(ctx context.Context, pg PaginateTodos) (_ []Todo, err error) {
+	defer r.observe(ctx, "find_todos", time.Now(), &err)
+	return r.repo.FindTodos(ctx, pg)
+}
+
+func (r *instrumentedRepository) CountTodos(ctx context.Context, f TodoFilter) (_ int, err error) {
+	defer r.observe(ctx, "count_todos", time.Now(), &err)
+	return r.repo.CountTodos(ctx, f)
+}
+
+func (r *instrumentedRepository) CountOverdue(ctx context.Context, now time.Time) (_ int, err error) {
+	defer r.observe(ctx, "count_overdue", time.Now(), &err)
+	return r.repo.CountOverdue(ctx, now)
+}
+
+func (r *instrumentedRepository) AddComment(ctx context.Context, comment *Comment) (err error) {
+	defer r.observe(ctx, "add_comment", time.Now(), &err)
+	return r.repo.AddComment(ctx, comment)
+}
+
+func (r *instrumentedRepository) RemoveComment(ctx context.Context, todoId, id uint) (err error) {
+	defer r.observe(ctx, "remove_comment", time.Now(), &err)
+	return r.repo.RemoveComment(ctx, todoId, id)
+}
+
+func (r *instrumentedRepository) FindComments(ctx context.Context, todoId uint, limit int) (_ []Comment, err error) {
+	defer r.observe(ctx, "find_comments", time.Now(), &err)
+	return r.repo.FindComments(ctx, todoId, limit)
+}
+
+func (r *instrumentedRepository) AddLabel(ctx context.Context, label *Label) (err error) {
+	defer r.observe(ctx, "add_label", time.Now(), &err)
+	return r.repo.AddLabel(ctx, label)
+}
+
+func (r *instrumentedRepository) RemoveLabel(ctx context.Context, todoId, id uint) (err error) {
+	defer r.observe(ctx, "remove_label", time.Now(), &err)
+	return r.repo.RemoveLabel(ctx, todoId, id)
+}
+
+func (r *instrumentedRepository) FindLabels(ctx context.Context, todoId uint, limit int) (_ []Label, err error) {
+	defer r.observe(ctx, "find_labels", time.Now(), &err)
+	return r.repo.FindLabels(ctx, todoId, limit)
+}
+
+func (r *instrumentedRepository) AddWatcher(ctx context.Context, watcher *Watcher) (err error) {
+	defer r.observe(ctx, "add_watcher", time.Now(), &err)
+	return r.repo.AddWatcher(ctx, watcher)
+}
+
+func (r *instrumentedRepository) RemoveWatcher(ctx context.Context, todoId, id uint) (err error) {
+	defer r.observe(ctx, "remove_watcher", time.Now(), &err)
+	return r.repo.RemoveWatcher(ctx, todoId, id)
+}
+
+func (r *instrumentedRepository) FindWatchers(ctx context.Context, todoId uint) (_ []Watcher, err error) {
+	defer r.observe(ctx, "find_watchers", time.Now(), &err)
+	return r.repo.FindWatchers(ctx, todoId)
+}
+
+func (r *instrumentedRepository) SetReferences(ctx context.Context, todoId uint, refs []Reference) (err error) {
+	defer r.observe(ctx, "set_references", time.Now(), &err)
+	return r.repo.SetReferences(ctx, todoId, refs)
+}
+
+func (r *instrumentedRepository) FindReferences(ctx context.Context, todoId uint) (_ []Reference, err error) {
+	defer r.observe(ctx, "find_references", time.Now(), &err)
+	return r.repo.FindReferences(ctx, todoId)
+}
+
+func (r *instrumentedRepository) FindBackReferences(ctx context.Context, todoId uint) (_ []Reference, err error) {
+	defer r.observe(ctx, "find_back_references", time.Now(), &err)
+	return r.repo.FindBackReferences(ctx, todoId)
+}
+
+func (r *instrumentedRepository) CreateField(ctx context.Context, field *Field) (err error) {
+	defer r.observe(ctx, "create_field", time.Now(), &err)
+	return r.repo.CreateField(ctx, field)
+}
+
+func (r *instrumentedRepository) FindFields(ctx context.Context, projectId uint) (_ []Field, err error) {
+	defer r.observe(ctx, "find_fields", time.Now(), &err)
+	return r.repo.FindFields(ctx, projectId)
+}
+
+func (r *instrumentedRepository) DeleteField(ctx context.Context, projectId, id uint) (err error) {
+	defer r.observe(ctx, "delete_field", time.Now(), &err)
+	return r.repo.DeleteField(ctx, projectId, id)
+}
+
+func (r *instrumentedRepository) CreateProject(ctx context.Context, project *Project, owner string) (err error) {
+	defer r.observe(ctx, "create_project", time.Now(), &err)
+	return r.repo.CreateProject(ctx, project, owner)
+}
+
+func (r *instrumentedRepository) UpdateProject(ctx context.Context, project *Project) (err error) {
+	defer r.observe(ctx, "update_project", time.Now(), &err)
+	return r.repo.UpdateProject(ctx, project)
+}
+
+func (r *instrumentedRepository) GetProject(ctx context.Context, id uint) (_ *Project, err error) {
+	defer r.observe(ctx, "get_project", time.Now(), &err)
+	return r.repo.GetProject(ctx, id)
+}
+
+func (r *instrumentedRepository) FindProjects(ctx context.Context, username string) (_ []Project, err error) {
+	defer r.observe(ctx, "find_projects", time.Now(), &err)
+	return r.repo.FindProjects(ctx, username)
+}
+
+func (r *instrumentedRepository) GetMembership(ctx context.Context, projectId uint, username string) (_ *Membership, err error) {
+	defer r.observe(ctx, "get_membership", time.Now(), &err)
+	return r.repo.GetMembership(ctx, projectId, username)
+}
+
+func (r *instrumentedRepository) FindMemberships(ctx context.Context, projectId uint) (_ []Membership, err error) {
+	defer r.observe(ctx, "find_memberships", time.Now(), &err)
+	return r.repo.FindMemberships(ctx, projectId)
+}
+
+func (r *instrumentedRepository) UpdateMembership(ctx context.Context, membership *Membership) (err error) {
+	defer r.observe(ctx, "update_membership", time.Now(), &err)
+	return r.repo.UpdateMembership(ctx, membership)
+}
+
+func (r *instrumentedRepository) RemoveMembership(ctx context.Context, projectId, id uint) (err error) {
+	defer r.observe(ctx, "remove_membership", time.Now(), &err)
+	return r.repo.RemoveMembership(ctx, projectId, id)
+}
+
+func (r *instrumentedRepository) CreateInvitation(ctx context.Context, invitation *Invitation) (err error) {
+	defer r.observe(ctx, "create_invitation", time.Now(), &err)
+	return r.repo.CreateInvitation(ctx, invitation)
+}
+
+func (r *instrumentedRepository) GetInvitation(ctx context.Context, id uint) (_ *Invitation, err error) {
+	defer r.observe(ctx, "get_invitation", time.Now(), &err)
+	return r.repo.GetInvitation(ctx, id)
+}
+
+func (r *instrumentedRepository) FindInvitations(ctx context.Context, projectId uint, username string) (_ []Invitation, err error) {
+	defer r.observe(ctx, "find_invitations", time.Now(), &err)
+	return r.repo.FindInvitations(ctx, projectId, username)
+}
+
+func (r *instrumentedRepository) AcceptInvitation(ctx context.Context, id uint) (_ *Membership, err error) {
+	defer r.observe(ctx, "accept_invitation", time.Now(), &err)
+	return r.repo.AcceptInvitation(ctx, id)
+}
+
+func (r *instrumentedRepository) DeleteInvitation(ctx context.Context, id uint) (err error) {
+	defer r.observe(ctx, "delete_invitation", time.Now(), &err)
+	return r.repo.DeleteInvitation(ctx, id)
+}
@@ -0,0 +1,668 @@
+package todo
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryRepository struct {
+	mu          sync.RWMutex
+	lastID      uint
+	todos       map[uint]Todo
+	comments    map[uint]Comment
+	labels      map[uint]Label
+	watchers    map[uint]Watcher
+	references  map[uint]Reference
+	fields      map[uint]Field
+	projects    map[uint]Project
+	memberships map[uint]Membership
+	invitations map[uint]Invitation
+}
+
+var _ Repository = (*memoryRepository)(nil)
+
+// NewMemoryRepository creates a repository keeping everything in memory,
+// for development and tests which shouldn't need a database.
+func NewMemoryRepository() Repository {
+	return &memoryRepository{
+		todos:       map[uint]Todo{},
+		comments:    map[uint]Comment{},
+		labels:      map[uint]Label{},
+		watchers:    map[uint]Watcher{},
+		references:  map[uint]Reference{},
+		fields:      map[uint]Field{},
+		projects:    map[uint]Project{},
+		memberships: map[uint]Membership{},
+		invitations: map[uint]Invitation{},
+	}
+}
+
+func (r *memoryRepository) nextID() uint {
+	r.lastID++
+	return r.lastID
+}
+
+func (r *memoryRepository) CreateTodo(ctx context.Context, todo *Todo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.projects[todo.ProjectId]; todo.ProjectId != 0 && !ok {
+		return ErrNotFound
+	}
+	todo.ID = r.nextID()
+	r.todos[todo.ID] = *todo
+	return nil
+}
+
+func (r *memoryRepository) UpdateTodo(ctx context.Context, todo *Todo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.todos[todo.ID]; !ok {
+		return ErrNotFound
+	}
+	r.todos[todo.ID] = *todo
+	return nil
+}
+
+func (r *memoryRepository) DeleteTodo(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.todos[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.todos, id)
+	for cid, c := range r.comments {
+		if c.TodoId == id {
+			delete(r.comments, cid)
+		}
+	}
+	for lid, l := range r.labels {
+		if l.TodoId == id {
+			delete(r.labels, lid)
+		}
+	}
+	for wid, w := range r.watchers {
+		if w.TodoId == id {
+			delete(r.watchers, wid)
+		}
+	}
+	for rid, ref := range r.references {
+		if ref.TodoId == id || ref.Kind == ReferenceTodo && ref.TargetId == id {
+			delete(r.references, rid)
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) GetTodo(ctx context.Context, id uint) (*Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	td, ok := r.todos[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &td, nil
+}
+
+func (r *memoryRepository) FindTodos(ctx context.Context, pg PaginateTodos) ([]Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todos := make([]Todo, 0, len(r.todos))
+	for _, td := range r.todos {
+		if pg.matches(td) {
+			todos = append(todos, td)
+		}
+	}
+	sort.Slice(todos, func(i, j int) bool { return pg.Order.less(todos[i], todos[j]) })
+
+	return paginate(todos, pg), nil
+}
+
+func (r *memoryRepository) CountTodos(ctx context.Context, f TodoFilter) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, td := range r.todos {
+		if f.matches(td) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryRepository) CountOverdue(ctx context.Context, now time.Time) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, td := range r.todos {
+		if !td.Done && td.DueDate.Before(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryRepository) AddComment(ctx context.Context, comment *Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.todos[comment.TodoId]; !ok {
+		return ErrNotFound
+	}
+	comment.ID = r.nextID()
+	r.comments[comment.ID] = *comment
+	return nil
+}
+
+func (r *memoryRepository) RemoveComment(ctx context.Context, todoId, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c, ok := r.comments[id]; !ok || c.TodoId != todoId {
+		return ErrNotFound
+	}
+	delete(r.comments, id)
+	return nil
+}
+
+func (r *memoryRepository) FindComments(ctx context.Context, todoId uint, limit int) ([]Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	comments := []Comment{}
+	for _, c := range r.comments {
+		if c.TodoId == todoId {
+			comments = append(comments, c)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].ID < comments[j].ID })
+	if len(comments) > limit {
+		comments = comments[:limit]
+	}
+	return comments, nil
+}
+
+func (r *memoryRepository) AddLabel(ctx context.Context, label *Label) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.todos[label.TodoId]; !ok {
+		return ErrNotFound
+	}
+	label.ID = r.nextID()
+	r.labels[label.ID] = *label
+	return nil
+}
+
+func (r *memoryRepository) RemoveLabel(ctx context.Context, todoId, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if l, ok := r.labels[id]; !ok || l.TodoId != todoId {
+		return ErrNotFound
+	}
+	delete(r.labels, id)
+	return nil
+}
+
+func (r *memoryRepository) FindLabels(ctx context.Context, todoId uint, limit int) ([]Label, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	labels := []Label{}
+	for _, l := range r.labels {
+		if l.TodoId == todoId {
+			labels = append(labels, l)
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].ID < labels[j].ID })
+	if len(labels) > limit {
+		labels = labels[:limit]
+	}
+	return labels, nil
+}
+
+func (r *memoryRepository) AddWatcher(ctx context.Context, watcher *Watcher) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.todos[watcher.TodoId]; !ok {
+		return ErrNotFound
+	}
+	for _, w := range r.watchers {
+		if w.TodoId == watcher.TodoId && w.Username == watcher.Username {
+			return ErrConflict
+		}
+	}
+	watcher.ID = r.nextID()
+	r.watchers[watcher.ID] = *watcher
+	return nil
+}
+
+func (r *memoryRepository) RemoveWatcher(ctx context.Context, todoId, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if w, ok := r.watchers[id]; !ok || w.TodoId != todoId {
+		return ErrNotFound
+	}
+	delete(r.watchers, id)
+	return nil
+}
+
+func (r *memoryRepository) FindWatchers(ctx context.Context, todoId uint) ([]Watcher, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	watchers := []Watcher{}
+	for _, w := range r.watchers {
+		if w.TodoId == todoId {
+			watchers = append(watchers, w)
+		}
+	}
+	sort.Slice(watchers, func(i, j int) bool { return watchers[i].ID < watchers[j].ID })
+	return watchers, nil
+}
+
+func (r *memoryRepository) SetReferences(ctx context.Context, todoId uint, refs []Reference) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.todos[todoId]; !ok {
+		return ErrNotFound
+	}
+	for rid, ref := range r.references {
+		if ref.TodoId == todoId {
+			delete(r.references, rid)
+		}
+	}
+	for i := range refs {
+		refs[i].ID = r.nextID()
+		refs[i].TodoId = todoId
+		r.references[refs[i].ID] = refs[i]
+	}
+	return nil
+}
+
+func (r *memoryRepository) FindReferences(ctx context.Context, todoId uint) ([]Reference, error) {
+	return r.findReferences(func(ref Reference) bool { return ref.TodoId == todoId }, func(a, b Reference) bool {
+		return a.ID < b.ID
+	}), nil
+}
+
+func (r *memoryRepository) FindBackReferences(ctx context.Context, todoId uint) ([]Reference, error) {
+	return r.findReferences(func(ref Reference) bool {
+		return ref.Kind == ReferenceTodo && ref.TargetId == todoId
+	}, func(a, b Reference) bool {
+		return a.TodoId < b.TodoId
+	}), nil
+}
+
+func (r *memoryRepository) findReferences(match func(Reference) bool, less func(a, b Reference) bool) []Reference {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	refs := []Reference{}
+	for _, ref := range r.references {
+		if match(ref) {
+			refs = append(refs, ref)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return less(refs[i], refs[j]) })
+	return refs
+}
+
+func (r *memoryRepository) CreateField(ctx context.Context, field *Field) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.projects[field.ProjectId]; !ok {
+		return ErrNotFound
+	}
+	for _, f := range r.fields {
+		if f.ProjectId == field.ProjectId && f.Name == field.Name {
+			return ErrConflict
+		}
+	}
+	field.ID = r.nextID()
+	r.fields[field.ID] = *field
+	return nil
+}
+
+func (r *memoryRepository) FindFields(ctx context.Context, projectId uint) ([]Field, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	fields := []Field{}
+	for _, f := range r.fields {
+		if f.ProjectId == projectId {
+			fields = append(fields, f)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].ID < fields[j].ID })
+	return fields, nil
+}
+
+func (r *memoryRepository) DeleteField(ctx context.Context, projectId, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	field, ok := r.fields[id]
+	if !ok || field.ProjectId != projectId {
+		return ErrNotFound
+	}
+	delete(r.fields, id)
+	for tid, td := range r.todos {
+		if _, ok := td.Fields[field.Name]; td.ProjectId != projectId || !ok {
+			continue
+		}
+		// the todos handed out keep their values
+		fields := Fields{}
+		for name, v := range td.Fields {
+			if name != field.Name {
+				fields[name] = v
+			}
+		}
+		td.Fields = fields
+		r.todos[tid] = td
+	}
+	return nil
+}
+
+func (r *memoryRepository) CreateProject(ctx context.Context, project *Project, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	project.ID = r.nextID()
+	r.projects[project.ID] = *project
+	if owner != "" {
+		m := Membership{ID: r.nextID(), ProjectId: project.ID, Username: owner, Role: RoleOwner}
+		r.memberships[m.ID] = m
+	}
+	return nil
+}
+
+func (r *memoryRepository) UpdateProject(ctx context.Context, project *Project) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.projects[project.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	p.Name = project.Name
+	r.projects[p.ID] = p
+	return nil
+}
+
+func (r *memoryRepository) GetProject(ctx context.Context, id uint) (*Project, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.projects[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &p, nil
+}
+
+func (r *memoryRepository) FindProjects(ctx context.Context, username string) ([]Project, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	projects := []Project{}
+	for _, p := range r.projects {
+		if _, err := r.membership(p.ID, username); username == "" || err == nil {
+			projects = append(projects, p)
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+	return projects, nil
+}
+
+func (r *memoryRepository) GetMembership(ctx context.Context, projectId uint, username string) (*Membership, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.membership(projectId, username)
+}
+
+func (r *memoryRepository) FindMemberships(ctx context.Context, projectId uint) ([]Membership, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	members := []Membership{}
+	for _, m := range r.memberships {
+		if m.ProjectId == projectId {
+			members = append(members, m)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+	return members, nil
+}
+
+func (r *memoryRepository) UpdateMembership(ctx context.Context, membership *Membership) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, ok := r.memberships[membership.ID]
+	if !ok || m.ProjectId != membership.ProjectId {
+		return ErrNotFound
+	}
+	if m.Role == RoleOwner && membership.Role != RoleOwner && !r.hasOtherOwner(m) {
+		return ErrLastOwner
+	}
+	m.Role = membership.Role
+	r.memberships[m.ID] = m
+	*membership = m
+	return nil
+}
+
+func (r *memoryRepository) RemoveMembership(ctx context.Context, projectId, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, ok := r.memberships[id]
+	if !ok || m.ProjectId != projectId {
+		return ErrNotFound
+	}
+	if m.Role == RoleOwner && !r.hasOtherOwner(m) {
+		return ErrLastOwner
+	}
+	delete(r.memberships, id)
+	return nil
+}
+
+func (r *memoryRepository) CreateInvitation(ctx context.Context, invitation *Invitation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.projects[invitation.ProjectId]; !ok {
+		return ErrNotFound
+	}
+	if _, err := r.membership(invitation.ProjectId, invitation.Username); err == nil {
+		return ErrConflict
+	}
+	for _, inv := range r.invitations {
+		if inv.ProjectId == invitation.ProjectId && inv.Username == invitation.Username {
+			return ErrConflict
+		}
+	}
+	invitation.ID = r.nextID()
+	r.invitations[invitation.ID] = *invitation
+	return nil
+}
+
+func (r *memoryRepository) GetInvitation(ctx context.Context, id uint) (*Invitation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	inv, ok := r.invitations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &inv, nil
+}
+
+func (r *memoryRepository) FindInvitations(ctx context.Context, projectId uint, username string) ([]Invitation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	invitations := []Invitation{}
+	for _, inv := range r.invitations {
+		if (projectId == 0 || inv.ProjectId == projectId) && (username == "" || inv.Username == username) {
+			invitations = append(invitations, inv)
+		}
+	}
+	sort.Slice(invitations, func(i, j int) bool { return invitations[i].ID < invitations[j].ID })
+	return invitations, nil
+}
+
+func (r *memoryRepository) AcceptInvitation(ctx context.Context, id uint) (*Membership, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	inv, ok := r.invitations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	delete(r.invitations, id)
+	m := Membership{ID: r.nextID(), ProjectId: inv.ProjectId, Username: inv.Username, Role: inv.Role}
+	r.memberships[m.ID] = m
+	return &m, nil
+}
+
+func (r *memoryRepository) DeleteInvitation(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.invitations[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.invitations, id)
+	return nil
+}
+
+// membership must be called with the lock held.
+func (r *memoryRepository) membership(projectId uint, username string) (*Membership, error) {
+	for _, m := range r.memberships {
+		if m.ProjectId == projectId && m.Username == username {
+			return &m, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// hasOtherOwner tells whether the project of m has an owner besides m, it
+// must be called with the lock held.
+func (r *memoryRepository) hasOtherOwner(m Membership) bool {
+	for _, other := range r.memberships {
+		if other.ProjectId == m.ProjectId && other.Role == RoleOwner && other.ID != m.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// matches tells whether the filter selects td, the way withFilter does for
+// SQL.
+func (f TodoFilter) matches(td Todo) bool {
+	if f.Assignee != "" && td.Assignee != f.Assignee {
+		return false
+	}
+	if len(f.Priorities) != 0 {
+		found := false
+		for _, p := range f.Priorities {
+			found = found || td.Priority == p
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, fv := range f.Fields {
+		if v, ok := td.Fields[fv.Name]; !ok || v != fv.Value {
+			return false
+		}
+	}
+	if len(f.Projects) == 0 {
+		return td.ProjectId == 0
+	}
+	for _, id := range f.Projects {
+		if td.ProjectId == id {
+			return true
+		}
+	}
+	return false
+}
+
+// less tells whether a comes before b, the way withOrder sorts in SQL.
+func (o TodoOrder) less(a, b Todo) bool {
+	if o.Key == SortByField {
+		_, aok := a.Fields[o.Field]
+		_, bok := b.Fields[o.Field]
+		if aok != bok {
+			return aok
+		}
+	}
+	c := o.compare(a, b)
+	if o.Desc {
+		c = -c
+	}
+	if c != 0 {
+		return c < 0
+	}
+	return a.ID < b.ID
+}
+
+func (o TodoOrder) compare(a, b Todo) int {
+	switch o.Key {
+	case SortByPriority:
+		return compareNumbers(float64(a.Priority), float64(b.Priority))
+	case SortByDueDate:
+		return compareNumbers(float64(a.DueDate.Unix()), float64(b.DueDate.Unix()))
+	case SortByEstimate:
+		return compareNumbers(float64(a.EstimateMinutes), float64(b.EstimateMinutes))
+	case SortByField:
+		av, bv := a.Fields[o.Field], b.Fields[o.Field]
+		if af, ok := av.(float64); ok {
+			bf, _ := bv.(float64)
+			return compareNumbers(af, bf)
+		}
+		as, _ := av.(string)
+		bs, _ := bv.(string)
+		return strings.Compare(as, bs)
+	}
+	return compareNumbers(float64(a.ID), float64(b.ID))
+}
+
+func compareNumbers(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// paginate applies the offset and limit of pg to the sorted todos, the way
+// buildPaginatedScope does for SQL.
+func paginate(todos []Todo, pg PaginateTodos) []Todo {
+	if int(pg.Offset) >= len(todos) {
+		return []Todo{}
+	}
+	todos = todos[pg.Offset:]
+	if pg.Limit != 0 && int(pg.Limit) < len(todos) {
+		todos = todos[:pg.Limit]
+	}
+	return todos
+}
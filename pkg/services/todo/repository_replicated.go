@@ -0,0 +1,422 @@
+package todo
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultStickyWindow  = 5 * time.Second
+	defaultCheckInterval = 5 * time.Second
+)
+
+type clientKey struct{}
+
+// WithClient tags ctx with the identity of the client issuing the request.
+// A ReplicatedRepository serves the reads of a client from the primary for a
+// while after its writes, so clients always read their own writes.
+func WithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+func clientFromContext(ctx context.Context) string {
+	client, _ := ctx.Value(clientKey{}).(string)
+	return client
+}
+
+type (
+	Replica struct {
+		Name       string
+		Repository Repository
+		// Ping tells whether the replica is reachable. A replica without it
+		// is not used again once a read from it failed.
+		Ping func(ctx context.Context) error
+	}
+
+	ReplicationConfig struct {
+		Primary  Repository
+		Replicas []Replica
+		// StickyWindow is how long the reads of a client go to the primary
+		// after its last write, it should exceed the replication lag.
+		StickyWindow time.Duration
+		// CheckInterval is how often the replicas are pinged by Run.
+		CheckInterval time.Duration
+		Logger        log.Logger
+	}
+
+	// ReplicatedRepository sends writes to the primary and spreads reads
+	// over the healthy replicas. Reads fall back to the primary when no
+	// replica is healthy or the chosen one fails.
+	ReplicatedRepository struct {
+		primary  Repository
+		replicas []*replica
+		next     uint32
+		sticky   time.Duration
+		interval time.Duration
+		logger   log.Logger
+		now      func() time.Time
+
+		mu     sync.Mutex
+		writes map[string]time.Time
+	}
+
+	replica struct {
+		Replica
+		healthy int32
+	}
+)
+
+var _ Repository = (*ReplicatedRepository)(nil)
+
+func NewReplicatedRepository(cfg ReplicationConfig) *ReplicatedRepository {
+	r := &ReplicatedRepository{
+		primary:  cfg.Primary,
+		sticky:   cfg.StickyWindow,
+		interval: cfg.CheckInterval,
+		logger:   cfg.Logger,
+		now:      time.Now,
+		writes:   map[string]time.Time{},
+	}
+	if r.sticky <= 0 {
+		r.sticky = defaultStickyWindow
+	}
+	if r.interval <= 0 {
+		r.interval = defaultCheckInterval
+	}
+	for _, rep := range cfg.Replicas {
+		r.replicas = append(r.replicas, &replica{Replica: rep, healthy: 1})
+	}
+	return r
+}
+
+// Run pings the replicas and forgets expired writes every check interval
+// until ctx is done.
+func (r *ReplicatedRepository) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *ReplicatedRepository) check(ctx context.Context) {
+	for _, rep := range r.replicas {
+		if rep.Ping == nil {
+			continue
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, r.interval)
+		err := rep.Ping(pingCtx)
+		cancel()
+		r.setHealthy(rep, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for client, at := range r.writes {
+		if r.now().Sub(at) >= r.sticky {
+			delete(r.writes, client)
+		}
+	}
+}
+
+// setHealthy records the health of a replica, logging the transitions.
+func (r *ReplicatedRepository) setHealthy(rep *replica, err error) {
+	healthy := int32(1)
+	if err != nil {
+		healthy = 0
+	}
+	if atomic.SwapInt32(&rep.healthy, healthy) == healthy {
+		return
+	}
+	if err != nil {
+		r.logger.Log("event", "replica_unhealthy", "replica", rep.Name, "error", err)
+	} else {
+		r.logger.Log("event", "replica_healthy", "replica", rep.Name)
+	}
+}
+
+// Healthy reports the replicas currently serving reads.
+func (r *ReplicatedRepository) Healthy() []string {
+	var names []string
+	for _, rep := range r.replicas {
+		if atomic.LoadInt32(&rep.healthy) == 1 {
+			names = append(names, rep.Name)
+		}
+	}
+	return names
+}
+
+// Sticky tells whether the reads of the client of ctx go to the primary, as
+// it wrote within the sticky window.
+func (r *ReplicatedRepository) Sticky(ctx context.Context) bool {
+	client := clientFromContext(ctx)
+	if client == "" {
+		return false
+	}
+	r.mu.Lock()
+	at, ok := r.writes[client]
+	r.mu.Unlock()
+	return ok && r.now().Sub(at) < r.sticky
+}
+
+// reader picks the repository serving a read, the replica is nil when it is
+// the primary.
+func (r *ReplicatedRepository) reader(ctx context.Context) (Repository, *replica) {
+	if r.Sticky(ctx) {
+		return r.primary, nil
+	}
+
+	n := uint32(len(r.replicas))
+	start := atomic.AddUint32(&r.next, 1)
+	for i := uint32(0); i < n; i++ {
+		rep := r.replicas[(start+i)%n]
+		if atomic.LoadInt32(&rep.healthy) == 1 {
+			return rep.Repository, rep
+		}
+	}
+	return r.primary, nil
+}
+
+// read runs fn on the chosen repository, retrying on the primary when a
+// replica fails. Not found is a valid answer and is not retried.
+func (r *ReplicatedRepository) read(ctx context.Context, fn func(Repository) error) error {
+	repo, rep := r.reader(ctx)
+	err := fn(repo)
+	if rep == nil || err == nil || errors.Is(err, ErrNotFound) || ctx.Err() != nil {
+		return err
+	}
+
+	r.setHealthy(rep, err)
+	return fn(r.primary)
+}
+
+// write runs fn on the primary and makes the client stick to it.
+func (r *ReplicatedRepository) write(ctx context.Context, fn func(Repository) error) error {
+	if err := fn(r.primary); err != nil {
+		return err
+	}
+	if client := clientFromContext(ctx); client != "" {
+		r.mu.Lock()
+		r.writes[client] = r.now()
+		r.mu.Unlock()
+	}
+	return nil
+}
+
+func (r *ReplicatedRepository) CreateTodo(ctx context.Context, todo *Todo) error {
+	return r.write(ctx, func(repo Repository) error { return repo.CreateTodo(ctx, todo) })
+}
+
+func (r *ReplicatedRepository) UpdateTodo(ctx context.Context, todo *Todo) error {
+	return r.write(ctx, func(repo Repository) error { return repo.UpdateTodo(ctx, todo) })
+}
+
+func (r *ReplicatedRepository) DeleteTodo(ctx context.Context, id uint) error {
+	return r.write(ctx, func(repo Repository) error { return repo.DeleteTodo(ctx, id) })
+}
+
+func (r *ReplicatedRepository) GetTodo(ctx context.Context, id uint) (todo *Todo, err error) {
+	err = r.read(ctx, func(repo Repository) error {
+		todo, err = repo.GetTodo(ctx, id)
+		return err
+	})
+	return todo, err
+}
+
+func (r *ReplicatedRepository) FindTodos(ctx context.Context, pg PaginateTodos) (todos []Todo, err error) {
+	err = r.read(ctx, func(repo Repository) error {
+		todos, err = repo.FindTodos(ctx, pg)
+		return err
+	})
+	return todos, err
+}
+
+func (r *ReplicatedRepository) CountTodos(ctx context.Context, f TodoFilter) (count int, err error) {
+	err = r.read(ctx, func(repo Repository) error {
+		count, err = repo.CountTodos(ctx, f)
+		return err
+	})
+	return count, err
+}
+
+func (r *ReplicatedRepository) CountOverdue(ctx context.Context, now time.Time) (count int, err error) {
+	err = r.read(ctx, func(repo Repository) error {
+		count, err = repo.CountOverdue(ctx, now)
+		return err
+	})
+	return count, err
+}
+
+func (r *ReplicatedRepository) AddComment(ctx context.Context, comment *Comment) error {
+	return r.write(ctx, func(repo Repository) error { return repo.AddComment(ctx, comment) })
+}
+
+func (r *ReplicatedRepository) RemoveComment(ctx context.Context, todoId, id uint) error {
+	return r.write(ctx, func(repo Repository) error { return repo.RemoveComment(ctx, todoId, id) })
+}
+
+func (r *ReplicatedRepository) FindComments(ctx context.Context, todoId uint, limit int) (comments []Comment, err error) {
+	err = r.read(ctx, func(repo Repository) error {
+		comments, err = repo.FindComments(ctx, todoId, limit)
+		return err
+	})
+	return comments, err
+}
+
+func (r *ReplicatedRepository) AddLabel(ctx context.Context, label *Label) error {
+	return r.write(ctx, func(repo Repository) error { return repo.AddLabel(ctx, label) })
+}
+
+func (r *ReplicatedRepository) RemoveLabel(ctx context.Context, todoId, id uint) error {
+	return r.write(ctx, func(repo Repository) error { return repo.RemoveLabel(ctx, todoId, id) })
+}
+
+func (r *ReplicatedRepository) FindLabels(ctx context.Context, todoId uint, limit int) (labels []Label, err error) {
+	err = r.read(ctx, func(repo Repository) error {
+		labels, err = repo.FindLabels(ctx, todoId, limit)
+		return err
+	})
+	return labels, err
+}
+
+func (r *ReplicatedRepository) AddWatcher(ctx context.Context, watcher *Watcher) error {
+	return r.write(ctx, func(repo Repository) error { return repo.AddWatcher(ctx, watcher) })
+}
+
+func (r *ReplicatedRepository) RemoveWatcher(ctx context.Context, todoId, id uint) error {
+	return r.write(ctx, func(repo Repository) error { return repo.RemoveWatcher(ctx, todoId, id) })
+}
+
+func (r *ReplicatedRepository) FindWatchers(ctx context.Context, todoId uint) (watchers []Watcher, err error) {
+	err = r.read(ctx, func(repo Repository) error {
+		watchers, err = repo.FindWatchers(ctx, todoId)
+		return err
+	})
+	return watchers, err
+}
+
+func (r *ReplicatedRepository) SetReferences(ctx context.Context, todoId uint, refs []Reference) error {
+	return r.write(ctx, func(repo Repository) error { return repo.SetReferences(ctx, todoId, refs) })
+}
+
+func (r *ReplicatedRepository) FindReferences(ctx context.Context, todoId uint) (refs []Reference, err error) {
+	err = r.read(ctx, func(repo Repository) error {
+		refs, err = repo.FindReferences(ctx, todoId)
+		return err
+	})
+	return refs, err
+}
+
+func (r *ReplicatedRepository) FindBackReferences(ctx context.Context, todoId uint) (refs []Reference, err error) {
+	err = r.read(ctx, func(repo Repository) error {
+		refs, err = repo.FindBackReferences(ctx, todoId)
+		return err
+	})
+	return refs, err
+}
+
+func (r *ReplicatedRepository) CreateField(ctx context.Context, field *Field) error {
+	return r.write(ctx, func(repo Repository) error { return repo.CreateField(ctx, field) })
+}
+
+func (r *ReplicatedRepository) FindFields(ctx context.Context, projectId uint) (fields []Field, err error) {
+	err = r.read(ctx, func(repo Repository) error {
+		fields, err = repo.FindFields(ctx, projectId)
+		return err
+	})
+	return fields, err
+}
+
+func (r *ReplicatedRepository) DeleteField(ctx context.Context, projectId, id uint) error {
+	return r.write(ctx, func(repo Repository) error { return repo.DeleteField(ctx, projectId, id) })
+}
+
+func (r *ReplicatedRepository) CreateProject(ctx context.Context, project *Project, owner string) error {
+	return r.write(ctx, func(repo Repository) error { return repo.CreateProject(ctx, project, owner) })
+}
+
+func (r *ReplicatedRepository) UpdateProject(ctx context.Context, project *Project) error {
+	return r.write(ctx, func(repo Repository) error { return repo.UpdateProject(ctx, project) })
+}
+
+func (r *ReplicatedRepository) GetProject(ctx context.Context, id uint) (project *Project, err error) {
+	err = r.read(ctx, func(repo Repository) error {
+		project, err = repo.GetProject(ctx, id)
+		return err
+	})
+	return project, err
+}
+
+func (r *ReplicatedRepository) FindProjects(ctx context.Context, username string) (projects []Project, err error) {
+	err = r.read(ctx, func(repo Repository) error {
+		projects, err = repo.FindProjects(ctx, username)
+		return err
+	})
+	return projects, err
+}
+
+// GetMembership reads from the primary, so revoked roles don't outlive the
+// replication lag.
+func (r *ReplicatedRepository) GetMembership(ctx context.Context, projectId uint, username string) (*Membership, error) {
+	return r.primary.GetMembership(ctx, projectId, username)
+}
+
+func (r *ReplicatedRepository) FindMemberships(ctx context.Context, projectId uint) (members []Membership, err error) {
+	err = r.read(ctx, func(repo Repository) error {
+		members, err = repo.FindMemberships(ctx, projectId)
+		return err
+	})
+	return members, err
+}
+
+func (r *ReplicatedRepository) UpdateMembership(ctx context.Context, membership *Membership) error {
+	return r.write(ctx, func(repo Repository) error { return repo.UpdateMembership(ctx, membership) })
+}
+
+func (r *ReplicatedRepository) RemoveMembership(ctx context.Context, projectId, id uint) error {
+	return r.write(ctx, func(repo Repository) error { return repo.RemoveMembership(ctx, projectId, id) })
+}
+
+func (r *ReplicatedRepository) CreateInvitation(ctx context.Context, invitation *Invitation) error {
+	return r.write(ctx, func(repo Repository) error { return repo.CreateInvitation(ctx, invitation) })
+}
+
+func (r *ReplicatedRepository) GetInvitation(ctx context.Context, id uint) (invitation *Invitation, err error) {
+	err = r.read(ctx, func(repo Repository) error {
+		invitation, err = repo.GetInvitation(ctx, id)
+		return err
+	})
+	return invitation, err
+}
+
+func (r *ReplicatedRepository) FindInvitations(ctx context.Context, projectId uint, username string) (invitations []Invitation, err error) {
+	err = r.read(ctx, func(repo Repository) error {
+		invitations, err = repo.FindInvitations(ctx, projectId, username)
+		return err
+	})
+	return invitations, err
+}
+
+func (r *ReplicatedRepository) AcceptInvitation(ctx context.Context, id uint) (membership *Membership, err error) {
+	err = r.write(ctx, func(repo Repository) error {
+		membership, err = repo.AcceptInvitation(ctx, id)
+		return err
+	})
+	return membership, err
+}
+
+func (r *ReplicatedRepository) DeleteInvitation(ctx context.Context, id uint) error {
+	return r.write(ctx, func(repo Repository) error { return repo.DeleteInvitation(ctx, id) })
+}
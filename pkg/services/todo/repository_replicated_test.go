@@ -0,0 +1,90 @@
+package todo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// brokenRepository fails every read, like a replica which went away.
+type brokenRepository struct {
+	Repository
+}
+
+func (brokenRepository) CountTodos(ctx context.Context, f TodoFilter) (int, error) {
+	return 0, errors.New("connection refused")
+}
+
+func TestReplicatedRepository(t *testing.T) {
+	ctx := context.Background()
+	primary, replica := NewMemoryRepository(), NewMemoryRepository()
+
+	// the replica lags behind, it only has the first todo
+	require.NoError(t, replica.CreateTodo(ctx, &Todo{Title: "replicated"}))
+
+	now := time.Now()
+	r := NewReplicatedRepository(ReplicationConfig{
+		Primary:      primary,
+		Replicas:     []Replica{{Name: "replica", Repository: replica}},
+		StickyWindow: time.Second,
+		Logger:       log.NewNopLogger(),
+	})
+	r.now = func() time.Time { return now }
+
+	alice := WithClient(ctx, "alice")
+	require.NoError(t, r.CreateTodo(alice, &Todo{Title: "replicated"}))
+	require.NoError(t, r.CreateTodo(alice, &Todo{Title: "lagging"}))
+
+	count, err := r.CountTodos(ctx, TodoFilter{})
+	require.NoError(t, err)
+	require.Equal(t, 1, count, "reads go to the replica")
+
+	count, err = r.CountTodos(alice, TodoFilter{})
+	require.NoError(t, err)
+	require.Equal(t, 2, count, "clients read their own writes")
+	require.True(t, r.Sticky(alice))
+	require.False(t, r.Sticky(ctx))
+
+	now = now.Add(2 * time.Second)
+	count, err = r.CountTodos(alice, TodoFilter{})
+	require.NoError(t, err)
+	require.Equal(t, 1, count, "stickiness expires")
+	require.False(t, r.Sticky(alice))
+}
+
+func TestReplicatedRepositoryFailover(t *testing.T) {
+	ctx := context.Background()
+	primary := NewMemoryRepository()
+	require.NoError(t, primary.CreateTodo(ctx, &Todo{Title: "first"}))
+
+	var pingErr error
+	r := NewReplicatedRepository(ReplicationConfig{
+		Primary: primary,
+		Replicas: []Replica{
+			{Name: "broken", Repository: brokenRepository{NewMemoryRepository()}},
+			{Name: "down", Repository: NewMemoryRepository(), Ping: func(context.Context) error { return pingErr }},
+		},
+		Logger: log.NewNopLogger(),
+	})
+	require.ElementsMatch(t, []string{"broken", "down"}, r.Healthy())
+
+	pingErr = errors.New("connection refused")
+	r.check(ctx)
+	require.Equal(t, []string{"broken"}, r.Healthy())
+
+	count, err := r.CountTodos(ctx, TodoFilter{})
+	require.NoError(t, err)
+	require.Equal(t, 1, count, "a failing replica is retried on the primary")
+	require.Empty(t, r.Healthy())
+
+	_, err = r.GetTodo(ctx, 42)
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	pingErr = nil
+	r.check(ctx)
+	require.Equal(t, []string{"down"}, r.Healthy())
+}
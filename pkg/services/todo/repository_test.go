@@ -0,0 +1,426 @@
+package todo
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Neurostep/todo/pkg/database"
+	"github.com/Neurostep/todo/pkg/types"
+)
+
+// repositories returns every implementation which can run without external
+// services. SQLite is skipped in binaries built without cgo.
+func repositories(t *testing.T) map[string]Repository {
+	repos := map[string]Repository{
+		"memory": NewMemoryRepository(),
+	}
+
+	db, err := database.New(context.Background(), database.Config{Driver: database.DriverSQLite, Address: ":memory:"}, log.NewNopLogger())
+	if err != nil {
+		t.Logf("skipping sqlite: %s", err)
+		return repos
+	}
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, AutoMigrate(db))
+	repos["sqlite"] = NewGormRepository(db)
+
+	return repos
+}
+
+func dueDate(s string) types.DueDate {
+	d, _ := time.Parse(types.DueDateFormat, s)
+	return types.DueDate(d)
+}
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+
+	for name, repo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			s := New(Config{Repository: repo, Logger: log.NewNopLogger()})
+
+			var ids []uint
+			for _, title := range []string{"first", "second", "third"} {
+				td, err := s.CreateTodo(ctx, &CreateTodo{Title: title, DueDate: dueDate("2022-08-01")})
+				require.NoError(t, err)
+				require.NotZero(t, td.ID)
+				ids = append(ids, td.ID)
+			}
+
+			page, err := s.GetTodos(ctx, PaginateTodos{Limit: 2})
+			require.NoError(t, err)
+			require.True(t, page.HasMore)
+			require.Equal(t, 3, page.TotalCount)
+			require.Len(t, page.Items, 2)
+			require.Equal(t, "first", page.Items[0].Title)
+
+			page, err = s.GetTodos(ctx, PaginateTodos{Offset: 2, Limit: 2})
+			require.NoError(t, err)
+			require.False(t, page.HasMore)
+			require.Len(t, page.Items, 1)
+			require.Equal(t, "third", page.Items[0].Title)
+
+			_, err = s.UpdateTodo(ctx, &UpdateTodo{Id: ids[0], Title: "updated", DueDate: dueDate("2022-09-01"), Done: true})
+			require.NoError(t, err)
+			td, err := s.GetTodo(ctx, ids[0])
+			require.NoError(t, err)
+			require.Equal(t, "updated", td.Title)
+			require.True(t, td.Done)
+			require.Equal(t, "2022-09-01", td.DueDate.Format(types.DueDateFormat))
+
+			_, err = s.UpdateTodo(ctx, &UpdateTodo{Id: 1000, DueDate: dueDate("2022-09-01")})
+			require.Equal(t, ErrNotFound, err)
+			_, err = s.GetTodo(ctx, 1000)
+			require.Equal(t, ErrNotFound, err)
+
+			for i := 0; i < MaxComments+1; i++ {
+				_, err := s.AddComment(ctx, AddComment{TodoId: ids[0], Text: "comment"})
+				require.NoError(t, err)
+			}
+			comments, err := s.GetComments(ctx, ids[0])
+			require.NoError(t, err)
+			require.Len(t, comments, MaxComments)
+			_, err = s.AddComment(ctx, AddComment{TodoId: 1000, Text: "orphan"})
+			require.Equal(t, ErrNotFound, err)
+
+			require.Equal(t, ErrNotFound, s.RemoveComment(ctx, ids[1], comments[0].ID), "comment of another todo")
+			require.NoError(t, s.RemoveComment(ctx, ids[0], comments[0].ID))
+
+			lbl, err := s.AddLabel(ctx, AddLabel{TodoId: ids[1], Text: "home", Color: "red"})
+			require.NoError(t, err)
+			labels, err := s.GetLabels(ctx, ids[1])
+			require.NoError(t, err)
+			require.Equal(t, []Label{*lbl}, labels)
+			require.NoError(t, s.RemoveLabel(ctx, ids[1], lbl.ID))
+			require.Equal(t, ErrNotFound, s.RemoveLabel(ctx, ids[1], lbl.ID))
+
+			require.NoError(t, s.DeleteTodo(ctx, ids[0]))
+			require.Equal(t, ErrNotFound, s.DeleteTodo(ctx, ids[0]))
+			comments, err = s.GetComments(ctx, ids[0])
+			require.NoError(t, err)
+			require.Empty(t, comments)
+		})
+	}
+}
+
+func TestCountOverdue(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 8, 15, 12, 0, 0, 0, time.UTC)
+
+	for name, repo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, td := range []*Todo{
+				{Title: "overdue", DueDate: time.Time(dueDate("2022-08-01"))},
+				{Title: "done", DueDate: time.Time(dueDate("2022-08-01")), Done: true},
+				{Title: "due", DueDate: time.Time(dueDate("2022-09-01"))},
+			} {
+				require.NoError(t, repo.CreateTodo(ctx, td))
+			}
+
+			count, err := repo.CountOverdue(ctx, now)
+			require.NoError(t, err)
+			require.Equal(t, 1, count)
+		})
+	}
+}
+
+func TestProjects(t *testing.T) {
+	ctx := context.Background()
+
+	for name, repo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			s := New(Config{Repository: repo, Logger: log.NewNopLogger()})
+
+			p, err := s.CreateProject(ctx, CreateProject{Name: "home", Owner: "alice"})
+			require.NoError(t, err)
+			other, err := s.CreateProject(ctx, CreateProject{Name: "work", Owner: "bob"})
+			require.NoError(t, err)
+
+			projects, err := s.GetProjects(ctx, "alice")
+			require.NoError(t, err)
+			require.Len(t, projects, 1)
+			require.Equal(t, "home", projects[0].Name)
+			projects, err = s.GetProjects(ctx, "")
+			require.NoError(t, err)
+			require.Len(t, projects, 2)
+
+			_, err = s.CreateTodo(ctx, &CreateTodo{Title: "shared", DueDate: dueDate("2022-08-01")})
+			require.NoError(t, err)
+			td, err := s.CreateTodo(ctx, &CreateTodo{Title: "groceries", DueDate: dueDate("2022-08-01"), ProjectId: p.ID})
+			require.NoError(t, err)
+			_, err = s.CreateTodo(ctx, &CreateTodo{Title: "orphan", DueDate: dueDate("2022-08-01"), ProjectId: 1000})
+			require.Equal(t, ErrNotFound, err)
+
+			page, err := s.GetTodos(ctx, PaginateTodos{TodoFilter: TodoFilter{Projects: []uint{p.ID}}})
+			require.NoError(t, err)
+			require.Equal(t, 1, page.TotalCount)
+			require.Equal(t, "groceries", page.Items[0].Title)
+			page, err = s.GetTodos(ctx, PaginateTodos{})
+			require.NoError(t, err)
+			require.Equal(t, 1, page.TotalCount, "todos outside projects")
+			require.Equal(t, "shared", page.Items[0].Title)
+			page, err = s.GetTodos(ctx, PaginateTodos{TodoFilter: TodoFilter{Projects: []uint{other.ID}}})
+			require.NoError(t, err)
+			require.Empty(t, page.Items)
+
+			updated, err := s.UpdateTodo(ctx, &UpdateTodo{Id: td.ID, Title: "groceries", DueDate: dueDate("2022-08-02")})
+			require.NoError(t, err)
+			require.Equal(t, p.ID, updated.ProjectId, "updates keep the project")
+
+			inv, err := s.Invite(ctx, Invite{ProjectId: p.ID, Username: "bob", Role: RoleEditor, InvitedBy: "alice"})
+			require.NoError(t, err)
+			_, err = s.Invite(ctx, Invite{ProjectId: p.ID, Username: "bob", Role: RoleViewer, InvitedBy: "alice"})
+			require.Equal(t, ErrConflict, err, "invited already")
+			_, err = s.Invite(ctx, Invite{ProjectId: p.ID, Username: "alice", Role: RoleViewer, InvitedBy: "alice"})
+			require.Equal(t, ErrConflict, err, "member already")
+
+			invitations, err := s.GetInvitations(ctx, 0, "bob")
+			require.NoError(t, err)
+			require.Len(t, invitations, 1)
+			require.Equal(t, inv.ID, invitations[0].ID)
+
+			m, err := s.AcceptInvitation(ctx, inv.ID)
+			require.NoError(t, err)
+			require.Equal(t, Membership{ID: m.ID, ProjectId: p.ID, Username: "bob", Role: RoleEditor}, *m)
+			_, err = s.AcceptInvitation(ctx, inv.ID)
+			require.Equal(t, ErrNotFound, err)
+
+			members, err := s.GetMembers(ctx, p.ID)
+			require.NoError(t, err)
+			require.Len(t, members, 2)
+			owner := members[0]
+			require.Equal(t, RoleOwner, owner.Role)
+
+			_, err = s.UpdateMember(ctx, UpdateMember{ProjectId: p.ID, Id: owner.ID, Role: RoleViewer})
+			require.Equal(t, ErrLastOwner, err)
+			require.Equal(t, ErrLastOwner, s.RemoveMember(ctx, p.ID, owner.ID))
+			require.Equal(t, ErrNotFound, s.RemoveMember(ctx, other.ID, m.ID), "member of another project")
+
+			promoted, err := s.UpdateMember(ctx, UpdateMember{ProjectId: p.ID, Id: m.ID, Role: RoleOwner})
+			require.NoError(t, err)
+			require.Equal(t, "bob", promoted.Username)
+			require.NoError(t, s.RemoveMember(ctx, p.ID, owner.ID))
+
+			_, err = s.GetMembership(ctx, p.ID, "alice")
+			require.Equal(t, ErrNotFound, err)
+			membership, err := s.GetMembership(ctx, p.ID, "bob")
+			require.NoError(t, err)
+			require.Equal(t, RoleOwner, membership.Role)
+		})
+	}
+}
+
+type recordingNotifier struct {
+	notifications []Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+func TestWatchers(t *testing.T) {
+	for name, repo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			notifier := &recordingNotifier{}
+			s := New(Config{Repository: repo, Logger: log.NewNopLogger(), Notifier: notifier})
+			alice := WithUser(context.Background(), "alice")
+			bob := WithUser(context.Background(), "bob")
+
+			td, err := s.CreateTodo(alice, &CreateTodo{Title: "groceries", DueDate: dueDate("2022-08-01"), Assignee: "bob"})
+			require.NoError(t, err)
+			require.Equal(t, []Notification{{Event: EventTodoAssigned, TodoId: td.ID, Title: "groceries", Actor: "alice", Recipients: []string{"bob"}}},
+				notifier.notifications)
+			_, err = s.CreateTodo(alice, &CreateTodo{Title: "laundry", DueDate: dueDate("2022-08-01")})
+			require.NoError(t, err)
+
+			page, err := s.GetTodos(alice, PaginateTodos{TodoFilter: TodoFilter{Assignee: "bob"}})
+			require.NoError(t, err)
+			require.Equal(t, 1, page.TotalCount)
+			require.Equal(t, "bob", page.Items[0].Assignee)
+
+			_, err = s.AddComment(alice, AddComment{TodoId: td.ID, Text: "milk"})
+			require.NoError(t, err)
+			_, err = s.AddComment(alice, AddComment{TodoId: td.ID, Text: "eggs"})
+			require.NoError(t, err, "commenting again keeps watching")
+			watchers, err := s.GetWatchers(alice, td.ID)
+			require.NoError(t, err)
+			require.Len(t, watchers, 2)
+			require.Equal(t, "alice", watchers[1].Username, "commenters watch")
+
+			_, err = s.AddWatcher(alice, AddWatcher{TodoId: td.ID, Username: "bob"})
+			require.Equal(t, ErrConflict, err)
+			_, err = s.AddWatcher(alice, AddWatcher{TodoId: 1000, Username: "bob"})
+			require.Equal(t, ErrNotFound, err)
+			carol, err := s.AddWatcher(alice, AddWatcher{TodoId: td.ID, Username: "carol"})
+			require.NoError(t, err)
+
+			notifier.notifications = nil
+			_, err = s.UpdateTodo(bob, &UpdateTodo{Id: td.ID, Title: "groceries", DueDate: dueDate("2022-08-01"), Done: true, Assignee: "bob"})
+			require.NoError(t, err)
+			require.NoError(t, s.RemoveWatcher(alice, td.ID, carol.ID))
+			require.NoError(t, s.DeleteTodo(alice, td.ID))
+			require.Equal(t, []Notification{
+				{Event: EventTodoCompleted, TodoId: td.ID, Title: "groceries", Actor: "bob", Recipients: []string{"alice", "carol"}},
+				{Event: EventTodoDeleted, TodoId: td.ID, Title: "groceries", Actor: "alice", Recipients: []string{"bob"}},
+			}, notifier.notifications)
+
+			watchers, err = s.GetWatchers(alice, td.ID)
+			require.NoError(t, err)
+			require.Empty(t, watchers)
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	ctx := context.Background()
+
+	for name, repo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			s := New(Config{Repository: repo, Logger: log.NewNopLogger()})
+
+			target, err := s.CreateTodo(ctx, &CreateTodo{Title: "target", DueDate: dueDate("2022-08-01")})
+			require.NoError(t, err)
+			td, err := s.CreateTodo(ctx, &CreateTodo{
+				Title:       "groceries",
+				DueDate:     dueDate("2022-08-01"),
+				Description: fmt.Sprintf("ask @bob about #%d, #1000 and #%d\n\n`#%d` @bob", target.ID, target.ID+1, target.ID),
+			})
+			require.NoError(t, err)
+			require.Contains(t, td.Description, "ask @bob")
+
+			refs, err := s.GetReferences(ctx, td.ID)
+			require.NoError(t, err)
+			require.Equal(t, []string{"bob"}, refs.Mentions)
+			require.Len(t, refs.Todos, 1, "missing todos and the todo itself are left out")
+			require.Equal(t, target.ID, refs.Todos[0].ID)
+			refs, err = s.GetReferences(ctx, target.ID)
+			require.NoError(t, err)
+			require.Len(t, refs.ReferencedBy, 1)
+			require.Equal(t, td.ID, refs.ReferencedBy[0].ID)
+
+			_, err = s.UpdateTodo(ctx, &UpdateTodo{Id: td.ID, Title: "groceries", DueDate: dueDate("2022-08-01"), Description: "@carol"})
+			require.NoError(t, err)
+			refs, err = s.GetReferences(ctx, td.ID)
+			require.NoError(t, err)
+			require.Equal(t, []string{"carol"}, refs.Mentions)
+			require.Empty(t, refs.Todos)
+			got, err := s.GetTodo(ctx, td.ID)
+			require.NoError(t, err)
+			require.Equal(t, "@carol", got.Description)
+
+			_, err = s.UpdateTodo(ctx, &UpdateTodo{Id: target.ID, Title: "target", DueDate: dueDate("2022-08-01"), Description: fmt.Sprintf("#%d", td.ID)})
+			require.NoError(t, err)
+			require.NoError(t, s.DeleteTodo(ctx, target.ID))
+			refs, err = s.GetReferences(ctx, td.ID)
+			require.NoError(t, err)
+			require.Empty(t, refs.ReferencedBy, "references of deleted todos go with them")
+			back, err := repo.FindBackReferences(ctx, td.ID)
+			require.NoError(t, err)
+			require.Empty(t, back)
+
+			_, err = s.GetReferences(ctx, target.ID)
+			require.Equal(t, ErrNotFound, err)
+		})
+	}
+}
+
+func TestPrioritiesAndFields(t *testing.T) {
+	ctx := context.Background()
+
+	for name, repo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			s := New(Config{Repository: repo, Logger: log.NewNopLogger()})
+
+			p, err := s.CreateProject(ctx, CreateProject{Name: "home", Owner: "alice"})
+			require.NoError(t, err)
+			_, err = s.CreateField(ctx, CreateField{ProjectId: p.ID, Name: "Size", Type: FieldNumber})
+			require.IsType(t, &ValidationError{}, err)
+			_, err = s.CreateField(ctx, CreateField{ProjectId: p.ID, Name: "room", Type: FieldEnum})
+			require.IsType(t, &ValidationError{}, err, "enums have options")
+			room, err := s.CreateField(ctx, CreateField{ProjectId: p.ID, Name: "room", Type: FieldEnum, Options: []string{"kitchen", "garden"}, Required: true})
+			require.NoError(t, err)
+			_, err = s.CreateField(ctx, CreateField{ProjectId: p.ID, Name: "room", Type: FieldText})
+			require.Equal(t, ErrConflict, err)
+			size, err := s.CreateField(ctx, CreateField{ProjectId: p.ID, Name: "size", Type: FieldNumber})
+			require.NoError(t, err)
+			_, err = s.CreateField(ctx, CreateField{ProjectId: p.ID, Name: "until", Type: FieldDate})
+			require.NoError(t, err)
+
+			create := func(title string, priority Priority, fields map[string]interface{}) (*Todo, error) {
+				return s.CreateTodo(ctx, &CreateTodo{Title: title, DueDate: dueDate("2022-08-01"), ProjectId: p.ID, Priority: &priority, Fields: fields})
+			}
+			_, err = create("dishes", PriorityP1, map[string]interface{}{"size": 3.0})
+			require.Equal(t, &ValidationError{Field: "fields.room", Message: "is required"}, err)
+			_, err = create("dishes", PriorityP1, map[string]interface{}{"room": "attic"})
+			require.IsType(t, &ValidationError{}, err)
+			_, err = create("dishes", PriorityP1, map[string]interface{}{"room": "kitchen", "until": "tomorrow"})
+			require.IsType(t, &ValidationError{}, err)
+			_, err = create("dishes", PriorityP1, map[string]interface{}{"room": "kitchen", "color": "red"})
+			require.IsType(t, &ValidationError{}, err, "unknown fields")
+			_, err = s.CreateTodo(ctx, &CreateTodo{Title: "shared", DueDate: dueDate("2022-08-01"), Fields: map[string]interface{}{"room": "kitchen"}})
+			require.IsType(t, &ValidationError{}, err, "todos outside projects have no fields")
+
+			dishes, err := create("dishes", PriorityP1, map[string]interface{}{"room": "kitchen", "size": 3.0})
+			require.NoError(t, err)
+			weeds, err := create("weeds", PriorityP3, map[string]interface{}{"room": "garden", "size": 10.0})
+			require.NoError(t, err)
+			_, err = create("fridge", PriorityP1, map[string]interface{}{"room": "kitchen"})
+			require.NoError(t, err)
+			plain, err := s.CreateTodo(ctx, &CreateTodo{Title: "plain", DueDate: dueDate("2022-08-01"), ProjectId: p.ID, Fields: map[string]interface{}{"room": "garden"}})
+			require.NoError(t, err)
+			require.Equal(t, DefaultPriority, plain.Priority)
+
+			list := func(pg PaginateTodos) []string {
+				pg.Projects = []uint{p.ID}
+				page, err := s.GetTodos(ctx, pg)
+				require.NoError(t, err)
+				titles := []string{}
+				for _, td := range page.Items {
+					titles = append(titles, td.Title)
+				}
+				return titles
+			}
+			order := func(sort string) TodoOrder {
+				o, err := ParseOrder(sort)
+				require.NoError(t, err)
+				return o
+			}
+			require.Equal(t, []string{"dishes", "fridge"}, list(PaginateTodos{TodoFilter: TodoFilter{Priorities: []Priority{PriorityP1}}}))
+			require.Equal(t, []string{"dishes", "fridge"}, list(PaginateTodos{TodoFilter: TodoFilter{Fields: []FieldValue{{Name: "room", Value: "kitchen"}}}}))
+			require.Equal(t, []string{"weeds"}, list(PaginateTodos{TodoFilter: TodoFilter{Fields: []FieldValue{{Name: "size", Value: "10"}}}}))
+			require.Equal(t, []string{"weeds", "plain", "dishes", "fridge"}, list(PaginateTodos{Order: order("-priority")}))
+			require.Equal(t, []string{"weeds", "dishes", "fridge", "plain"}, list(PaginateTodos{Order: order("-field.size")}),
+				"numbers sort numerically, todos without a value last")
+			require.Equal(t, []string{"dishes", "weeds", "fridge", "plain"}, list(PaginateTodos{Order: order("field.size")}))
+
+			_, err = s.GetTodos(ctx, PaginateTodos{TodoFilter: TodoFilter{Fields: []FieldValue{{Name: "room", Value: "kitchen"}}}})
+			require.IsType(t, &ValidationError{}, err, "fields of a single project")
+			_, err = s.GetTodos(ctx, PaginateTodos{TodoFilter: TodoFilter{Projects: []uint{p.ID}}, Order: order("field.color")})
+			require.IsType(t, &ValidationError{}, err)
+			_, err = ParseOrder("title")
+			require.IsType(t, &ValidationError{}, err)
+
+			require.NoError(t, s.DeleteField(ctx, p.ID, size.ID))
+			require.Equal(t, ErrNotFound, s.DeleteField(ctx, p.ID, size.ID))
+			td, err := s.GetTodo(ctx, weeds.ID)
+			require.NoError(t, err)
+			require.Equal(t, Fields{"room": "garden"}, td.Fields, "values go with their field")
+			fields, err := s.GetFields(ctx, p.ID)
+			require.NoError(t, err)
+			require.Len(t, fields, 2)
+			require.Equal(t, room.ID, fields[0].ID)
+
+			estimated, err := s.UpdateTodo(ctx, &UpdateTodo{Id: dishes.ID, Title: "dishes", DueDate: dueDate("2022-08-01"),
+				EstimateMinutes: 30, Fields: map[string]interface{}{"room": "kitchen"}})
+			require.NoError(t, err)
+			require.Equal(t, DefaultPriority, estimated.Priority)
+			require.Equal(t, []string{"dishes", "weeds", "fridge", "plain"}, list(PaginateTodos{Order: order("-estimate")}))
+		})
+	}
+}
@@ -5,12 +5,95 @@ import (
 	"github.com/jinzhu/gorm"
 )
 
+// sortColumns are the columns of the keys of TodoOrder.
+var sortColumns = map[string]string{
+	"":             "id",
+	SortByID:       "id",
+	SortByPriority: "priority",
+	SortByDueDate:  "due_date",
+	SortByEstimate: "estimate_minutes",
+}
+
 func withTodoID(ID uint) db.Scope {
 	return func(tx *gorm.DB) *gorm.DB {
 		return tx.Where("id = ?", ID)
 	}
 }
 
+// withFilter selects the todos of the filter, see TodoFilter.
+func withFilter(f TodoFilter) db.Scope {
+	return func(tx *gorm.DB) *gorm.DB {
+		shared := len(f.Projects) == 0
+		var ids []uint
+		for _, id := range f.Projects {
+			if id == 0 {
+				shared = true
+			} else {
+				ids = append(ids, id)
+			}
+		}
+		switch {
+		case shared && len(ids) != 0:
+			tx = tx.Where("project_id IS NULL OR project_id IN (?)", ids)
+		case shared:
+			tx = tx.Where("project_id IS NULL")
+		default:
+			tx = tx.Where("project_id IN (?)", ids)
+		}
+		if f.Assignee != "" {
+			tx = tx.Where("assignee = ?", f.Assignee)
+		}
+		if len(f.Priorities) != 0 {
+			tx = tx.Where("priority IN (?)", f.Priorities)
+		}
+		for _, fv := range f.Fields {
+			tx = tx.Where(fieldValue(tx, fv.Name, fv.fieldType)+" = ?", fv.Value)
+		}
+		return tx
+	}
+}
+
+// withOrder sorts the todos, see TodoOrder.
+func withOrder(o TodoOrder) db.Scope {
+	return func(tx *gorm.DB) *gorm.DB {
+		column := sortColumns[o.Key]
+		if o.Key == SortByField {
+			column = fieldValue(tx, o.Field, o.fieldType)
+			// the dialects disagree on where nulls go
+			tx = tx.Order(column + " IS NULL")
+		}
+		if o.Desc {
+			column += " DESC"
+		}
+		tx = tx.Order(column)
+		if o.Key != SortByID && o.Key != "" {
+			tx = tx.Order("id")
+		}
+		return tx
+	}
+}
+
+// fieldValue is the SQL expression of the value of a custom field, null
+// when the todo has none. Field names are checked against fieldName, so
+// they are safe to inline.
+func fieldValue(tx *gorm.DB, name string, t FieldType) string {
+	if tx.Dialect().GetName() == "postgres" {
+		if t == FieldNumber {
+			return "CAST(fields->>'" + name + "' AS numeric)"
+		}
+		return "(fields->>'" + name + "')"
+	}
+	return "json_extract(fields, '$." + name + "')"
+}
+
+// withoutField is the fields column with the custom field removed.
+func withoutField(tx *gorm.DB, name string) interface{} {
+	if tx.Dialect().GetName() == "postgres" {
+		return gorm.Expr("fields - ?", name)
+	}
+	return gorm.Expr("json_remove(fields, ?)", "$."+name)
+}
+
 func buildPaginatedScope(pg PaginateTodos) []db.Scope {
 	res := []db.Scope{}
 
@@ -4,11 +4,9 @@ import (
 	"context"
 	"time"
 
-	"github.com/Neurostep/todo/pkg/database"
 	"github.com/Neurostep/todo/pkg/tools/logging"
 	"github.com/Neurostep/todo/pkg/types"
 	"github.com/go-kit/kit/log"
-	"github.com/jinzhu/gorm"
 	"go.opencensus.io/trace"
 )
 
@@ -19,25 +17,87 @@ const (
 	MaxLabels       = 10
 )
 
+// Keys of TodoOrder.
+const (
+	SortByID       = "id"
+	SortByPriority = "priority"
+	SortByDueDate  = "due_date"
+	SortByEstimate = "estimate"
+	SortByField    = "field"
+)
+
 type (
 	Config struct {
-		DB     *gorm.DB
-		Logger log.Logger
+		Repository Repository
+		Logger     log.Logger
+		// Notifier tells the watchers of todos about their changes, nobody
+		// is told when it is nil.
+		Notifier Notifier
 	}
 
+	// CreateTodo creates a todo of DefaultPriority when Priority is nil.
+	// Fields are the values of the custom fields of the project, decoded
+	// from JSON.
 	CreateTodo struct {
-		Title   string
-		DueDate types.DueDate
+		Title           string
+		Description     string
+		DueDate         types.DueDate
+		ProjectId       uint
+		Assignee        string
+		Priority        *Priority
+		EstimateMinutes uint
+		Fields          map[string]interface{}
 	}
 
+	// UpdateTodo replaces the fields of the todo, see CreateTodo.
 	UpdateTodo struct {
-		Id      uint
-		Title   string
-		DueDate types.DueDate
-		Done    bool
+		Id              uint
+		Title           string
+		Description     string
+		DueDate         types.DueDate
+		Done            bool
+		Assignee        string
+		Priority        *Priority
+		EstimateMinutes uint
+		Fields          map[string]interface{}
+	}
+
+	// TodoFilter selects todos. Projects lists the projects of the todos,
+	// zero standing for the todos outside projects, which are the ones
+	// selected when it is empty. Assignee selects the todos assigned to the
+	// user, any todo when empty. Priorities selects the todos of the
+	// priorities, any when empty, and Fields the todos having all the values
+	// of custom fields, the fields of the single project of Projects.
+	TodoFilter struct {
+		Projects   []uint
+		Assignee   string
+		Priorities []Priority
+		Fields     []FieldValue
+	}
+
+	// FieldValue is a value of the custom field Name, as decoded from JSON
+	// or taken from a query string.
+	FieldValue struct {
+		Name  string
+		Value interface{}
+		// fieldType is looked up by the service
+		fieldType FieldType
+	}
+
+	// TodoOrder sorts todos by Key, one of the SortBy constants, and by the
+	// custom field named Field when Key is SortByField. Todos without a value
+	// of the field come last, ties are sorted by id.
+	TodoOrder struct {
+		Key   string
+		Field string
+		Desc  bool
+		// fieldType is looked up by the service
+		fieldType FieldType
 	}
 
 	PaginateTodos struct {
+		TodoFilter
+		Order         TodoOrder
 		Offset, Limit uint32
 	}
 
@@ -58,105 +118,270 @@ type (
 		Text   string
 	}
 
+	AddWatcher struct {
+		TodoId   uint
+		Username string
+	}
+
+	// TodoReferences are the users and todos the description of a todo
+	// refers to, and the todos referring to it in theirs.
+	TodoReferences struct {
+		Mentions     []string
+		Todos        []Todo
+		ReferencedBy []Todo
+	}
+
+	CreateField struct {
+		ProjectId uint
+		Name      string
+		Type      FieldType
+		Options   []string
+		Required  bool
+	}
+
+	// CreateProject creates a project owned by Owner, a project without
+	// owner when it is empty.
+	CreateProject struct {
+		Name  string
+		Owner string
+	}
+
+	UpdateProject struct {
+		Id   uint
+		Name string
+	}
+
+	UpdateMember struct {
+		ProjectId uint
+		Id        uint
+		Role      Role
+	}
+
+	Invite struct {
+		ProjectId uint
+		Username  string
+		Role      Role
+		InvitedBy string
+	}
+
 	ServiceProvider interface {
-		CreateTodo(ctx context.Context, db *gorm.DB, todo *CreateTodo) (*Todo, error)
-		UpdateTodo(ctx context.Context, db *gorm.DB, todo *UpdateTodo) (*Todo, error)
-		GetTodo(ctx context.Context, db *gorm.DB, id int) (*Todo, error)
-		DeleteTodo(ctx context.Context, db *gorm.DB, id int) error
-		GetTodos(ctx context.Context, db *gorm.DB, pg PaginateTodos) (*PaginatedTodos, error)
-		AddComment(ctx context.Context, db *gorm.DB, comment AddComment) (*Comment, error)
-		RemoveComment(ctx context.Context, db *gorm.DB, todoId, id int) error
-		AddLabel(ctx context.Context, db *gorm.DB, comment AddLabel) (*Label, error)
-		RemoveLabel(ctx context.Context, db *gorm.DB, todoId, id int) error
+		CreateTodo(ctx context.Context, todo *CreateTodo) (*Todo, error)
+		UpdateTodo(ctx context.Context, todo *UpdateTodo) (*Todo, error)
+		GetTodo(ctx context.Context, id uint) (*Todo, error)
+		DeleteTodo(ctx context.Context, id uint) error
+		GetTodos(ctx context.Context, pg PaginateTodos) (*PaginatedTodos, error)
+		AddComment(ctx context.Context, comment AddComment) (*Comment, error)
+		RemoveComment(ctx context.Context, todoId, id uint) error
+		GetComments(ctx context.Context, todoId uint) ([]Comment, error)
+		AddLabel(ctx context.Context, label AddLabel) (*Label, error)
+		RemoveLabel(ctx context.Context, todoId, id uint) error
+		GetLabels(ctx context.Context, todoId uint) ([]Label, error)
+		AddWatcher(ctx context.Context, watcher AddWatcher) (*Watcher, error)
+		RemoveWatcher(ctx context.Context, todoId, id uint) error
+		GetWatchers(ctx context.Context, todoId uint) ([]Watcher, error)
+		GetReferences(ctx context.Context, todoId uint) (*TodoReferences, error)
+		CreateField(ctx context.Context, field CreateField) (*Field, error)
+		GetFields(ctx context.Context, projectId uint) ([]Field, error)
+		DeleteField(ctx context.Context, projectId, id uint) error
+
+		CreateProject(ctx context.Context, project CreateProject) (*Project, error)
+		UpdateProject(ctx context.Context, project UpdateProject) (*Project, error)
+		GetProject(ctx context.Context, id uint) (*Project, error)
+		GetProjects(ctx context.Context, username string) ([]Project, error)
+		GetMembership(ctx context.Context, projectId uint, username string) (*Membership, error)
+		GetMembers(ctx context.Context, projectId uint) ([]Membership, error)
+		UpdateMember(ctx context.Context, member UpdateMember) (*Membership, error)
+		RemoveMember(ctx context.Context, projectId, id uint) error
+		Invite(ctx context.Context, invite Invite) (*Invitation, error)
+		GetInvitation(ctx context.Context, id uint) (*Invitation, error)
+		GetInvitations(ctx context.Context, projectId uint, username string) ([]Invitation, error)
+		AcceptInvitation(ctx context.Context, id uint) (*Membership, error)
+		DeleteInvitation(ctx context.Context, id uint) error
 	}
 
 	Service struct {
-		DB     *gorm.DB
-		Logger log.Logger
+		Repository Repository
+		Logger     log.Logger
+		Notifier   Notifier
 	}
 )
 
+var _ ServiceProvider = (*Service)(nil)
+
 func New(cfg Config) *Service {
 	return &Service{
-		Logger: cfg.Logger,
-		DB:     cfg.DB,
+		Logger:     cfg.Logger,
+		Repository: cfg.Repository,
+		Notifier:   cfg.Notifier,
 	}
 }
 
-func (s *Service) CreateTodo(ctx context.Context, db *gorm.DB, todo *CreateTodo) (*Todo, error) {
+func (s *Service) CreateTodo(ctx context.Context, todo *CreateTodo) (*Todo, error) {
 	ctx, span := trace.StartSpan(ctx, "todo.create")
 	defer span.End()
 	logger := logging.FromContext(ctx, s.Logger)
 
+	priority, err := todoPriority(todo.Priority)
+	if err != nil {
+		recordOperation(ctx, "create_todo", err)
+		return nil, err
+	}
+	fields, err := s.todoFields(ctx, todo.ProjectId, todo.Fields)
+	if err != nil {
+		recordOperation(ctx, "create_todo", err)
+		return nil, err
+	}
+
 	td := &Todo{
-		Title:   todo.Title,
-		DueDate: *todo.DueDate.Time(),
-		Done:    false,
+		Title:           todo.Title,
+		Description:     todo.Description,
+		DueDate:         *todo.DueDate.Time(),
+		Done:            false,
+		CreatedAt:       time.Now().UTC(),
+		ProjectId:       todo.ProjectId,
+		Assignee:        todo.Assignee,
+		Priority:        priority,
+		EstimateMinutes: todo.EstimateMinutes,
+		Fields:          fields,
 	}
 
-	err := db.Save(td).Error
+	err = s.Repository.CreateTodo(ctx, td)
+	recordOperation(ctx, "create_todo", err)
 
 	if err != nil {
 		logger.Log("event", "failed to create todo", "error", err)
 		return nil, err
 	}
 
+	s.link(ctx, td)
+	if td.Assignee != "" {
+		s.watch(ctx, td.ID, td.Assignee)
+		s.notify(ctx, EventTodoAssigned, td)
+	}
 	return td, nil
 }
 
-func (s *Service) UpdateTodo(ctx context.Context, db *gorm.DB, todo *UpdateTodo) (*Todo, error) {
+func (s *Service) UpdateTodo(ctx context.Context, todo *UpdateTodo) (*Todo, error) {
 	ctx, span := trace.StartSpan(ctx, "todo.update")
 	defer span.End()
 	logger := logging.FromContext(ctx, s.Logger)
 
+	// the stored todo tells whether the update completes it
+	prev, err := s.Repository.GetTodo(ctx, todo.Id)
+	if err != nil {
+		recordOperation(ctx, "update_todo", err)
+		logger.Log("event", "failed to retrieve todo", "error", err)
+		return nil, err
+	}
+	priority, err := todoPriority(todo.Priority)
+	if err != nil {
+		recordOperation(ctx, "update_todo", err)
+		return nil, err
+	}
+	fields, err := s.todoFields(ctx, prev.ProjectId, todo.Fields)
+	if err != nil {
+		recordOperation(ctx, "update_todo", err)
+		return nil, err
+	}
+
 	td := &Todo{
-		ID:      todo.Id,
-		Title:   todo.Title,
-		DueDate: *todo.DueDate.Time(),
-		Done:    todo.Done,
+		ID:              todo.Id,
+		Title:           todo.Title,
+		Description:     todo.Description,
+		DueDate:         *todo.DueDate.Time(),
+		Done:            todo.Done,
+		CreatedAt:       prev.CreatedAt,
+		ProjectId:       prev.ProjectId,
+		Assignee:        todo.Assignee,
+		Priority:        priority,
+		EstimateMinutes: todo.EstimateMinutes,
+		Fields:          fields,
+	}
+	completed := todo.Done && !prev.Done
+	switch {
+	case completed:
+		now := time.Now().UTC()
+		td.CompletedAt = &now
+	case todo.Done:
+		td.CompletedAt = prev.CompletedAt
 	}
 
-	err := db.Scopes(withTodoID(todo.Id)).Save(td).Error
+	err = s.Repository.UpdateTodo(ctx, td)
+	recordOperation(ctx, "update_todo", err)
 
 	if err != nil {
 		logger.Log("event", "failed to update todo", "error", err)
 		return nil, err
 	}
 
+	s.link(ctx, td)
+	if completed {
+		recordOperation(ctx, "complete_todo", nil)
+		if !prev.CreatedAt.IsZero() {
+			recordCompletion(ctx, td.CompletedAt.Sub(prev.CreatedAt))
+		}
+	}
+
+	event := EventTodoUpdated
+	switch {
+	case completed:
+		event = EventTodoCompleted
+	case td.Assignee != prev.Assignee && td.Assignee != "":
+		event = EventTodoAssigned
+	}
+	if td.Assignee != prev.Assignee {
+		s.watch(ctx, td.ID, td.Assignee)
+	}
+	s.notify(ctx, event, td)
 	return td, nil
 }
 
-func (s *Service) DeleteTodo(ctx context.Context, db *gorm.DB, id uint) error {
+func (s *Service) DeleteTodo(ctx context.Context, id uint) error {
 	ctx, span := trace.StartSpan(ctx, "todo.delete")
 	defer span.End()
 	logger := logging.FromContext(ctx, s.Logger)
 
-	err := db.Scopes(withTodoID(id)).Delete(&Todo{}).Error
+	// the watchers go with the todo, they are told about it afterwards
+	var (
+		td       *Todo
+		watchers []Watcher
+	)
+	if s.Notifier != nil {
+		td, _ = s.Repository.GetTodo(ctx, id)
+		watchers, _ = s.Repository.FindWatchers(ctx, id)
+	}
+
+	err := s.Repository.DeleteTodo(ctx, id)
+	recordOperation(ctx, "delete_todo", err)
 
 	if err != nil {
 		logger.Log("event", "failed to delete todo", "error", err)
 		return err
 	}
 
+	if td != nil {
+		s.send(ctx, EventTodoDeleted, td, watchers)
+	}
 	return nil
 }
 
-func (s *Service) GetTodo(ctx context.Context, db *gorm.DB, id uint) (*Todo, error) {
+func (s *Service) GetTodo(ctx context.Context, id uint) (*Todo, error) {
 	ctx, span := trace.StartSpan(ctx, "todo.get")
 	defer span.End()
 	logger := logging.FromContext(ctx, s.Logger)
 
-	todos, err := findTodos(db, withTodoID(id))
+	td, err := s.Repository.GetTodo(ctx, id)
+	recordOperation(ctx, "get_todo", err)
 
 	if err != nil {
 		logger.Log("event", "failed to retrieve todo", "error", err)
 		return nil, err
 	}
 
-	return &todos[0], nil
+	return td, nil
 }
 
-func (s *Service) GetTodos(ctx context.Context, db *gorm.DB, pg PaginateTodos) (*PaginatedTodos, error) {
+func (s *Service) GetTodos(ctx context.Context, pg PaginateTodos) (*PaginatedTodos, error) {
 	ctx, span := trace.StartSpan(ctx, "todo.list")
 	defer span.End()
 	logger := logging.FromContext(ctx, s.Logger)
@@ -170,17 +395,22 @@ func (s *Service) GetTodos(ctx context.Context, db *gorm.DB, pg PaginateTodos) (
 	}
 	pg.Limit = originalLimit + 1
 
-	var totalCount int
-	scopes := buildPaginatedScope(pg)
-	items, err := findTodos(db, scopes...)
+	if err := s.resolveFields(ctx, &pg); err != nil {
+		recordOperation(ctx, "list_todos", err)
+		return nil, err
+	}
+
+	items, err := s.Repository.FindTodos(ctx, pg)
 	if err != nil {
+		recordOperation(ctx, "list_todos", err)
 		logger.Log("event", "failed to fetch todos", "error", err)
 		return nil, err
 	}
 
-	scopes = append(scopes, database.WithOffset(0))
-	err = db.Model(Todo{}).Scopes(scopes...).Count(&totalCount).Error
+	totalCount, err := s.Repository.CountTodos(ctx, pg.TodoFilter)
+	recordOperation(ctx, "list_todos", err)
 	if err != nil {
+		logger.Log("event", "failed to count todos", "error", err)
 		return nil, err
 	}
 
@@ -190,7 +420,6 @@ func (s *Service) GetTodos(ctx context.Context, db *gorm.DB, pg PaginateTodos) (
 		items = items[:len(items)-1]
 	}
 
-	pg.Limit = originalLimit
 	return &PaginatedTodos{
 		Items:      items,
 		HasMore:    hasMore,
@@ -198,7 +427,7 @@ func (s *Service) GetTodos(ctx context.Context, db *gorm.DB, pg PaginateTodos) (
 	}, nil
 }
 
-func (s *Service) AddComment(ctx context.Context, db *gorm.DB, comment AddComment) (*Comment, error) {
+func (s *Service) AddComment(ctx context.Context, comment AddComment) (*Comment, error) {
 	ctx, span := trace.StartSpan(ctx, "todo.comment.add")
 	defer span.End()
 	logger := logging.FromContext(ctx, s.Logger)
@@ -208,27 +437,28 @@ func (s *Service) AddComment(ctx context.Context, db *gorm.DB, comment AddCommen
 		TodoId: comment.TodoId,
 	}
 
-	err := db.Save(cmnt).Error
+	err := s.Repository.AddComment(ctx, cmnt)
+	recordOperation(ctx, "add_comment", err)
 	if err != nil {
 		logger.Log("event", "failed to store comment", "error", err)
 		return nil, err
 	}
 
+	// commenting subscribes to the todo
+	s.watch(ctx, cmnt.TodoId, userFromContext(ctx))
+	if td, err := s.Repository.GetTodo(ctx, cmnt.TodoId); err == nil {
+		s.notify(ctx, EventCommentAdded, td)
+	}
 	return cmnt, nil
 }
 
-func (s *Service) RemoveComment(ctx context.Context, db *gorm.DB, todoId, id uint) error {
+func (s *Service) RemoveComment(ctx context.Context, todoId, id uint) error {
 	ctx, span := trace.StartSpan(ctx, "todo.comment.remove")
 	defer span.End()
 	logger := logging.FromContext(ctx, s.Logger)
 
-	cmnt := &Comment{
-		id,
-		"",
-		todoId,
-	}
-
-	err := db.Delete(cmnt).Error
+	err := s.Repository.RemoveComment(ctx, todoId, id)
+	recordOperation(ctx, "remove_comment", err)
 	if err != nil {
 		logger.Log("event", "failed to remove comment", "error", err)
 		return err
@@ -237,15 +467,13 @@ func (s *Service) RemoveComment(ctx context.Context, db *gorm.DB, todoId, id uin
 	return nil
 }
 
-func (s *Service) GetComments(ctx context.Context, db *gorm.DB, todoId uint) ([]Comment, error) {
+func (s *Service) GetComments(ctx context.Context, todoId uint) ([]Comment, error) {
 	ctx, span := trace.StartSpan(ctx, "todo.comments.get")
 	defer span.End()
 	logger := logging.FromContext(ctx, s.Logger)
 
-	todo := &Todo{todoId, "", time.Now(), false}
-
-	comments := []Comment{}
-	err := db.Model(todo).Related(&comments).Limit(MaxComments).Error
+	comments, err := s.Repository.FindComments(ctx, todoId, MaxComments)
+	recordOperation(ctx, "list_comments", err)
 
 	if err != nil {
 		logger.Log("event", "failed to retrieve comments", "error", err)
@@ -255,7 +483,7 @@ func (s *Service) GetComments(ctx context.Context, db *gorm.DB, todoId uint) ([]
 	return comments, nil
 }
 
-func (s *Service) AddLabel(ctx context.Context, db *gorm.DB, label AddLabel) (*Label, error) {
+func (s *Service) AddLabel(ctx context.Context, label AddLabel) (*Label, error) {
 	ctx, span := trace.StartSpan(ctx, "todo.label.add")
 	defer span.End()
 	logger := logging.FromContext(ctx, s.Logger)
@@ -266,7 +494,8 @@ func (s *Service) AddLabel(ctx context.Context, db *gorm.DB, label AddLabel) (*L
 		Color:  label.Color,
 	}
 
-	err := db.Create(lbl).Error
+	err := s.Repository.AddLabel(ctx, lbl)
+	recordOperation(ctx, "add_label", err)
 	if err != nil {
 		logger.Log("event", "failed to store label", "error", err)
 		return nil, err
@@ -275,18 +504,13 @@ func (s *Service) AddLabel(ctx context.Context, db *gorm.DB, label AddLabel) (*L
 	return lbl, nil
 }
 
-func (s *Service) RemoveLabel(ctx context.Context, db *gorm.DB, todoId, id uint) error {
+func (s *Service) RemoveLabel(ctx context.Context, todoId, id uint) error {
 	ctx, span := trace.StartSpan(ctx, "todo.label.remove")
 	defer span.End()
 	logger := logging.FromContext(ctx, s.Logger)
 
-	lbl := &Label{id,
-		"",
-		"",
-		todoId,
-	}
-
-	err := db.Delete(lbl).Error
+	err := s.Repository.RemoveLabel(ctx, todoId, id)
+	recordOperation(ctx, "remove_label", err)
 	if err != nil {
 		logger.Log("event", "failed to remove label", "error", err)
 		return err
@@ -295,15 +519,13 @@ func (s *Service) RemoveLabel(ctx context.Context, db *gorm.DB, todoId, id uint)
 	return nil
 }
 
-func (s *Service) GetLabels(ctx context.Context, db *gorm.DB, todoId uint) ([]Label, error) {
+func (s *Service) GetLabels(ctx context.Context, todoId uint) ([]Label, error) {
 	ctx, span := trace.StartSpan(ctx, "todo.labels.get")
 	defer span.End()
 	logger := logging.FromContext(ctx, s.Logger)
 
-	todo := &Todo{todoId, "", time.Now(), false}
-
-	labels := []Label{}
-	err := db.Model(todo).Related(&labels).Limit(MaxLabels).Error
+	labels, err := s.Repository.FindLabels(ctx, todoId, MaxLabels)
+	recordOperation(ctx, "list_labels", err)
 
 	if err != nil {
 		logger.Log("event", "failed to retrieve labels", "error", err)
@@ -312,10 +534,3 @@ func (s *Service) GetLabels(ctx context.Context, db *gorm.DB, todoId uint) ([]La
 
 	return labels, nil
 }
-
-func findTodos(db *gorm.DB, scopes ...database.Scope) ([]Todo, error) {
-	todos := []Todo{}
-	err := db.Scopes(scopes...).Find(&todos).Error
-
-	return todos, err
-}
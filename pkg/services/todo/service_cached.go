@@ -0,0 +1,244 @@
+package todo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/Neurostep/todo/pkg/cache"
+)
+
+// todosVersionKey holds the version of the todo list. Mutations replace it,
+// which orphans every cached page at once.
+const todosVersionKey = "todos:version"
+
+// todoVersionKey holds the version of the todos cached one by one, replaced
+// by the changes of many todos at once. The changes of a single todo replace
+// its revision instead, see todoRevisionKey.
+const todoVersionKey = "todo:version"
+
+const defaultCacheTTL = time.Minute
+
+type (
+	CacheConfig struct {
+		Service ServiceProvider
+		Cache   cache.Cache
+		// TTL bounds how stale a cached read may be, reads from lagging
+		// replicas included. It defaults to a minute.
+		TTL time.Duration
+		// Bypass tells whether the reads of ctx skip the cache, such as the
+		// ones of clients reading their own writes from the primary, see
+		// ReplicatedRepository.Sticky. Nothing is skipped when nil.
+		Bypass func(ctx context.Context) bool
+		Logger log.Logger
+	}
+
+	// CachedService serves the reads of the wrapped service from a cache
+	// and invalidates the affected entries on mutations.
+	CachedService struct {
+		ServiceProvider
+		cache  *cache.ReadThrough
+		bypass func(ctx context.Context) bool
+	}
+)
+
+var _ ServiceProvider = (*CachedService)(nil)
+
+func NewCachedService(cfg CacheConfig) *CachedService {
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultCacheTTL
+	}
+	return &CachedService{
+		ServiceProvider: cfg.Service,
+		cache: &cache.ReadThrough{
+			Cache:  cfg.Cache,
+			TTL:    cfg.TTL,
+			Logger: cfg.Logger,
+		},
+		bypass: cfg.Bypass,
+	}
+}
+
+func todoKey(version, revision string, id uint) string {
+	return fmt.Sprintf("todo:%s:%d:%s", version, id, revision)
+}
+
+// todoRevisionKey holds the revision of a todo, replaced by its changes. A
+// read started before a change caches the todo under the revision it
+// replaced, which is never served again.
+func todoRevisionKey(id uint) string {
+	return fmt.Sprintf("todo:%d:revision", id)
+}
+
+func commentsKey(todoId uint) string {
+	return fmt.Sprintf("todo:%d:comments", todoId)
+}
+
+func labelsKey(todoId uint) string {
+	return fmt.Sprintf("todo:%d:labels", todoId)
+}
+
+func (s *CachedService) GetTodos(ctx context.Context, pg PaginateTodos) (page *PaginatedTodos, err error) {
+	if s.skip(ctx) {
+		return s.ServiceProvider.GetTodos(ctx, pg)
+	}
+	version, ok := s.version(ctx, todosVersionKey, 0)
+	if !ok {
+		return s.ServiceProvider.GetTodos(ctx, pg)
+	}
+
+	key := fmt.Sprintf("todos:%s:%v:%s:%v:%v:%v:%d:%d", version, pg.Projects, pg.Assignee, pg.Priorities, pg.Fields, pg.Order, pg.Offset, pg.Limit)
+	err = s.cache.Fetch(ctx, "todos", key, &page, func() error {
+		page, err = s.ServiceProvider.GetTodos(ctx, pg)
+		return err
+	})
+	return page, err
+}
+
+func (s *CachedService) GetTodo(ctx context.Context, id uint) (todo *Todo, err error) {
+	if s.skip(ctx) {
+		return s.ServiceProvider.GetTodo(ctx, id)
+	}
+	version, ok := s.version(ctx, todoVersionKey, 0)
+	var revision string
+	if ok {
+		// revisions go unused once their todos are deleted, they expire
+		// along with the todos cached under them
+		revision, ok = s.version(ctx, todoRevisionKey(id), s.cache.TTL)
+	}
+	if !ok {
+		return s.ServiceProvider.GetTodo(ctx, id)
+	}
+
+	err = s.cache.Fetch(ctx, "todo", todoKey(version, revision, id), &todo, func() error {
+		todo, err = s.ServiceProvider.GetTodo(ctx, id)
+		return err
+	})
+	return todo, err
+}
+
+func (s *CachedService) GetComments(ctx context.Context, todoId uint) (comments []Comment, err error) {
+	if s.skip(ctx) {
+		return s.ServiceProvider.GetComments(ctx, todoId)
+	}
+	err = s.cache.Fetch(ctx, "comments", commentsKey(todoId), &comments, func() error {
+		comments, err = s.ServiceProvider.GetComments(ctx, todoId)
+		return err
+	})
+	return comments, err
+}
+
+func (s *CachedService) GetLabels(ctx context.Context, todoId uint) (labels []Label, err error) {
+	if s.skip(ctx) {
+		return s.ServiceProvider.GetLabels(ctx, todoId)
+	}
+	err = s.cache.Fetch(ctx, "labels", labelsKey(todoId), &labels, func() error {
+		labels, err = s.ServiceProvider.GetLabels(ctx, todoId)
+		return err
+	})
+	return labels, err
+}
+
+func (s *CachedService) CreateTodo(ctx context.Context, todo *CreateTodo) (*Todo, error) {
+	td, err := s.ServiceProvider.CreateTodo(ctx, todo)
+	if err == nil {
+		s.bumpVersion(ctx, todosVersionKey, 0)
+	}
+	return td, err
+}
+
+func (s *CachedService) UpdateTodo(ctx context.Context, todo *UpdateTodo) (*Todo, error) {
+	td, err := s.ServiceProvider.UpdateTodo(ctx, todo)
+	if err == nil {
+		s.bumpVersion(ctx, todoRevisionKey(todo.Id), s.cache.TTL)
+		s.bumpVersion(ctx, todosVersionKey, 0)
+	}
+	return td, err
+}
+
+func (s *CachedService) DeleteTodo(ctx context.Context, id uint) error {
+	err := s.ServiceProvider.DeleteTodo(ctx, id)
+	if err == nil {
+		s.bumpVersion(ctx, todoRevisionKey(id), s.cache.TTL)
+		s.cache.Delete(ctx, commentsKey(id), labelsKey(id))
+		s.bumpVersion(ctx, todosVersionKey, 0)
+	}
+	return err
+}
+
+func (s *CachedService) AddComment(ctx context.Context, comment AddComment) (*Comment, error) {
+	cmnt, err := s.ServiceProvider.AddComment(ctx, comment)
+	if err == nil {
+		s.cache.Delete(ctx, commentsKey(comment.TodoId))
+	}
+	return cmnt, err
+}
+
+func (s *CachedService) RemoveComment(ctx context.Context, todoId, id uint) error {
+	err := s.ServiceProvider.RemoveComment(ctx, todoId, id)
+	if err == nil {
+		s.cache.Delete(ctx, commentsKey(todoId))
+	}
+	return err
+}
+
+func (s *CachedService) AddLabel(ctx context.Context, label AddLabel) (*Label, error) {
+	lbl, err := s.ServiceProvider.AddLabel(ctx, label)
+	if err == nil {
+		s.cache.Delete(ctx, labelsKey(label.TodoId))
+	}
+	return lbl, err
+}
+
+func (s *CachedService) RemoveLabel(ctx context.Context, todoId, id uint) error {
+	err := s.ServiceProvider.RemoveLabel(ctx, todoId, id)
+	if err == nil {
+		s.cache.Delete(ctx, labelsKey(todoId))
+	}
+	return err
+}
+
+// DeleteField changes the todos of the project, both the lists and the
+// todos cached one by one are invalidated.
+func (s *CachedService) DeleteField(ctx context.Context, projectId, id uint) error {
+	err := s.ServiceProvider.DeleteField(ctx, projectId, id)
+	if err == nil {
+		s.bumpVersion(ctx, todoVersionKey, 0)
+		s.bumpVersion(ctx, todosVersionKey, 0)
+	}
+	return err
+}
+
+// skip tells whether the reads of ctx bypass the cache.
+func (s *CachedService) skip(ctx context.Context) bool {
+	return s.bypass != nil && s.bypass(ctx)
+}
+
+// version returns the current version held by key, starting a new one kept
+// for ttl when there is none. Nothing is cached when the cache fails.
+func (s *CachedService) version(ctx context.Context, key string, ttl time.Duration) (string, bool) {
+	raw, ok, err := s.cache.Cache.Get(ctx, key)
+	if err != nil {
+		s.cache.Logger.Log("event", "cache_get_failed", "key", key, "error", err)
+		return "", false
+	}
+	if ok {
+		return string(raw), true
+	}
+	return s.bumpVersion(ctx, key, ttl)
+}
+
+// bumpVersion starts a new version held by key for ttl, zero keeping it
+// until evicted. Versions never repeat, so entries cached before an evicted
+// or expired version are never served again.
+func (s *CachedService) bumpVersion(ctx context.Context, key string, ttl time.Duration) (string, bool) {
+	version := strconv.FormatInt(time.Now().UnixNano(), 36)
+	if err := s.cache.Cache.Set(ctx, key, []byte(version), ttl); err != nil {
+		s.cache.Logger.Log("event", "cache_set_failed", "key", key, "error", err)
+		return "", false
+	}
+	return version, true
+}
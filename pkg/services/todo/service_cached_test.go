@@ -0,0 +1,174 @@
+package todo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Neurostep/todo/pkg/cache"
+)
+
+// countingRepository counts the reads reaching the storage.
+type countingRepository struct {
+	Repository
+	reads int
+	// afterGetTodo runs once GetTodo read the todo, before returning it
+	afterGetTodo func()
+}
+
+func (r *countingRepository) FindTodos(ctx context.Context, pg PaginateTodos) ([]Todo, error) {
+	r.reads++
+	return r.Repository.FindTodos(ctx, pg)
+}
+
+func (r *countingRepository) GetTodo(ctx context.Context, id uint) (*Todo, error) {
+	r.reads++
+	td, err := r.Repository.GetTodo(ctx, id)
+	if r.afterGetTodo != nil {
+		r.afterGetTodo()
+	}
+	return td, err
+}
+
+func (r *countingRepository) FindComments(ctx context.Context, todoId uint, limit int) ([]Comment, error) {
+	r.reads++
+	return r.Repository.FindComments(ctx, todoId, limit)
+}
+
+func TestCachedService(t *testing.T) {
+	ctx := context.Background()
+	repo := &countingRepository{Repository: NewMemoryRepository()}
+	s := NewCachedService(CacheConfig{
+		Service: New(Config{Repository: repo, Logger: log.NewNopLogger()}),
+		Cache:   cache.NewLRU(100),
+		TTL:     time.Minute,
+		Logger:  log.NewNopLogger(),
+	})
+
+	td, err := s.CreateTodo(ctx, &CreateTodo{Title: "first", DueDate: dueDate("2022-08-01")})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		page, err := s.GetTodos(ctx, PaginateTodos{Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, page.Items, 1)
+		require.Equal(t, 1, page.TotalCount)
+
+		got, err := s.GetTodo(ctx, td.ID)
+		require.NoError(t, err)
+		require.Equal(t, "first", got.Title)
+		require.Equal(t, td.DueDate.Unix(), got.DueDate.Unix())
+	}
+	require.Equal(t, 2, repo.reads, "second round is served from the cache")
+
+	_, err = s.UpdateTodo(ctx, &UpdateTodo{Id: td.ID, Title: "renamed", DueDate: dueDate("2022-08-01")})
+	require.NoError(t, err)
+
+	page, err := s.GetTodos(ctx, PaginateTodos{Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, "renamed", page.Items[0].Title)
+	got, err := s.GetTodo(ctx, td.ID)
+	require.NoError(t, err)
+	require.Equal(t, "renamed", got.Title)
+	require.Equal(t, 5, repo.reads, "updates read the stored todo, reads after them miss")
+
+	comments, err := s.GetComments(ctx, td.ID)
+	require.NoError(t, err)
+	require.Empty(t, comments)
+	_, err = s.AddComment(ctx, AddComment{TodoId: td.ID, Text: "hello"})
+	require.NoError(t, err)
+	comments, err = s.GetComments(ctx, td.ID)
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+
+	require.NoError(t, s.DeleteTodo(ctx, td.ID))
+	_, err = s.GetTodo(ctx, td.ID)
+	require.True(t, errors.Is(err, ErrNotFound))
+	page, err = s.GetTodos(ctx, PaginateTodos{Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, page.Items)
+
+	p, err := s.CreateProject(ctx, CreateProject{Name: "home"})
+	require.NoError(t, err)
+	size, err := s.CreateField(ctx, CreateField{ProjectId: p.ID, Name: "size", Type: FieldNumber})
+	require.NoError(t, err)
+	td, err = s.CreateTodo(ctx, &CreateTodo{Title: "boxes", DueDate: dueDate("2022-08-01"), ProjectId: p.ID, Fields: map[string]interface{}{"size": 3.0}})
+	require.NoError(t, err)
+	got, err = s.GetTodo(ctx, td.ID)
+	require.NoError(t, err)
+	require.Equal(t, Fields{"size": 3.0}, got.Fields)
+	require.NoError(t, s.DeleteField(ctx, p.ID, size.ID))
+	got, err = s.GetTodo(ctx, td.ID)
+	require.NoError(t, err)
+	require.Empty(t, got.Fields, "todos cached one by one lose the values of deleted fields")
+}
+
+func TestCachedServiceConcurrentUpdate(t *testing.T) {
+	ctx := context.Background()
+	repo := &countingRepository{Repository: NewMemoryRepository()}
+	s := NewCachedService(CacheConfig{
+		Service: New(Config{Repository: repo, Logger: log.NewNopLogger()}),
+		Cache:   cache.NewLRU(100),
+		TTL:     time.Minute,
+		Logger:  log.NewNopLogger(),
+	})
+	td, err := s.CreateTodo(ctx, &CreateTodo{Title: "first", DueDate: dueDate("2022-08-01")})
+	require.NoError(t, err)
+
+	// the read gets the todo before the update and caches it after
+	reading, updated, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	repo.afterGetTodo = func() {
+		repo.afterGetTodo = nil
+		close(reading)
+		<-updated
+	}
+	var readErr error
+	go func() {
+		defer close(done)
+		_, readErr = s.GetTodo(ctx, td.ID)
+	}()
+	<-reading
+	_, err = s.UpdateTodo(ctx, &UpdateTodo{Id: td.ID, Title: "renamed", DueDate: dueDate("2022-08-01")})
+	require.NoError(t, err)
+	close(updated)
+	<-done
+	require.NoError(t, readErr)
+
+	got, err := s.GetTodo(ctx, td.ID)
+	require.NoError(t, err)
+	require.Equal(t, "renamed", got.Title, "reads started before an update are not served after it")
+}
+
+func TestCachedServiceBypass(t *testing.T) {
+	ctx := context.Background()
+	repo := &countingRepository{Repository: NewMemoryRepository()}
+	sticky := true
+	s := NewCachedService(CacheConfig{
+		Service: New(Config{Repository: repo, Logger: log.NewNopLogger()}),
+		Cache:   cache.NewLRU(100),
+		TTL:     time.Minute,
+		Bypass:  func(context.Context) bool { return sticky },
+		Logger:  log.NewNopLogger(),
+	})
+	td, err := s.CreateTodo(ctx, &CreateTodo{Title: "first", DueDate: dueDate("2022-08-01")})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = s.GetTodo(ctx, td.ID)
+		require.NoError(t, err)
+		_, err = s.GetTodos(ctx, PaginateTodos{Limit: 10})
+		require.NoError(t, err)
+	}
+	require.Equal(t, 4, repo.reads, "bypassing reads reach the storage")
+
+	sticky = false
+	for i := 0; i < 2; i++ {
+		_, err = s.GetTodo(ctx, td.ID)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 5, repo.reads)
+}
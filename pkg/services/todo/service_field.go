@@ -0,0 +1,159 @@
+package todo
+
+import (
+	"context"
+	"strings"
+
+	"go.opencensus.io/trace"
+
+	"github.com/Neurostep/todo/pkg/tools/logging"
+)
+
+func (s *Service) CreateField(ctx context.Context, field CreateField) (*Field, error) {
+	ctx, span := trace.StartSpan(ctx, "todo.field.create")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	f := &Field{
+		ProjectId: field.ProjectId,
+		Name:      field.Name,
+		Type:      field.Type,
+		Options:   field.Options,
+		Required:  field.Required,
+	}
+
+	err := f.validate()
+	if err == nil {
+		err = s.Repository.CreateField(ctx, f)
+	}
+	recordOperation(ctx, "create_field", err)
+	if err != nil {
+		logger.Log("event", "failed to create field", "error", err)
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (s *Service) GetFields(ctx context.Context, projectId uint) ([]Field, error) {
+	ctx, span := trace.StartSpan(ctx, "todo.fields.get")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	fields, err := s.Repository.FindFields(ctx, projectId)
+	recordOperation(ctx, "list_fields", err)
+	if err != nil {
+		logger.Log("event", "failed to retrieve fields", "error", err)
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// DeleteField removes the custom field along with its values.
+func (s *Service) DeleteField(ctx context.Context, projectId, id uint) error {
+	ctx, span := trace.StartSpan(ctx, "todo.field.delete")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	err := s.Repository.DeleteField(ctx, projectId, id)
+	recordOperation(ctx, "delete_field", err)
+	if err != nil {
+		logger.Log("event", "failed to delete field", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// ParseOrder parses the order of todos from the sort query parameter, the
+// key, or the field.<name> of a custom field, descending when prefixed by
+// a minus sign. Todos are sorted by id when it is empty.
+func ParseOrder(s string) (TodoOrder, error) {
+	o := TodoOrder{Key: SortByID}
+	if strings.HasPrefix(s, "-") {
+		o.Desc = true
+		s = s[1:]
+	}
+	switch {
+	case s == "" && !o.Desc:
+	case s == SortByID, s == SortByPriority, s == SortByDueDate, s == SortByEstimate:
+		o.Key = s
+	case strings.HasPrefix(s, SortByField+".") && len(s) > len(SortByField)+1:
+		o.Key = SortByField
+		o.Field = s[len(SortByField)+1:]
+	default:
+		return o, &ValidationError{Field: "sort", Message: "must be id, priority, due_date, estimate or field.<name>, prefixed by - to sort descending"}
+	}
+	return o, nil
+}
+
+// todoFields checks the values of the custom fields of a todo of the
+// project, todos outside projects have none.
+func (s *Service) todoFields(ctx context.Context, projectId uint, values map[string]interface{}) (Fields, error) {
+	if projectId == 0 {
+		if len(values) != 0 {
+			return nil, &ValidationError{Field: "fields", Message: "are only set on todos of projects"}
+		}
+		return Fields{}, nil
+	}
+
+	defs, err := s.Repository.FindFields(ctx, projectId)
+	if err != nil {
+		logging.FromContext(ctx, s.Logger).Log("event", "failed to retrieve fields", "error", err)
+		return nil, err
+	}
+	return parseFields(defs, values)
+}
+
+// resolveFields looks up the custom fields the todos are filtered and
+// sorted by, which are the fields of the single project listed.
+func (s *Service) resolveFields(ctx context.Context, pg *PaginateTodos) error {
+	if len(pg.Fields) == 0 && pg.Order.Key != SortByField {
+		return nil
+	}
+	if len(pg.Projects) != 1 || pg.Projects[0] == 0 {
+		return &ValidationError{Field: "fields", Message: "are filtered and sorted by within a project"}
+	}
+
+	defs, err := s.Repository.FindFields(ctx, pg.Projects[0])
+	if err != nil {
+		logging.FromContext(ctx, s.Logger).Log("event", "failed to retrieve fields", "error", err)
+		return err
+	}
+
+	// the values are parsed in a copy, the filter of the caller is kept
+	fields := make([]FieldValue, len(pg.Fields))
+	for i, fv := range pg.Fields {
+		def := findField(defs, fv.Name)
+		if def == nil {
+			return &ValidationError{Field: "fields." + fv.Name, Message: "is not a field of the project"}
+		}
+		v, err := def.parse(fv.Value)
+		if err != nil {
+			return err
+		}
+		fields[i] = FieldValue{Name: fv.Name, Value: v, fieldType: def.Type}
+	}
+	pg.Fields = fields
+
+	if pg.Order.Key == SortByField {
+		def := findField(defs, pg.Order.Field)
+		if def == nil {
+			return &ValidationError{Field: "sort", Message: pg.Order.Field + " is not a field of the project"}
+		}
+		pg.Order.fieldType = def.Type
+	}
+	return nil
+}
+
+// todoPriority is p, DefaultPriority when it is nil.
+func todoPriority(p *Priority) (Priority, error) {
+	switch {
+	case p == nil:
+		return DefaultPriority, nil
+	case *p > PriorityP4:
+		return 0, &ValidationError{Field: "priority", Message: "must be one of P0, P1, P2, P3, P4"}
+	}
+	return *p, nil
+}
@@ -0,0 +1,231 @@
+package todo
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/trace"
+
+	"github.com/Neurostep/todo/pkg/tools/logging"
+)
+
+func (s *Service) CreateProject(ctx context.Context, project CreateProject) (*Project, error) {
+	ctx, span := trace.StartSpan(ctx, "todo.project.create")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	p := &Project{
+		Name:      project.Name,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	err := s.Repository.CreateProject(ctx, p, project.Owner)
+	recordOperation(ctx, "create_project", err)
+	if err != nil {
+		logger.Log("event", "failed to create project", "error", err)
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (s *Service) UpdateProject(ctx context.Context, project UpdateProject) (*Project, error) {
+	ctx, span := trace.StartSpan(ctx, "todo.project.update")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	p, err := s.Repository.GetProject(ctx, project.Id)
+	if err == nil {
+		p.Name = project.Name
+		err = s.Repository.UpdateProject(ctx, p)
+	}
+	recordOperation(ctx, "update_project", err)
+	if err != nil {
+		logger.Log("event", "failed to update project", "error", err)
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (s *Service) GetProject(ctx context.Context, id uint) (*Project, error) {
+	ctx, span := trace.StartSpan(ctx, "todo.project.get")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	p, err := s.Repository.GetProject(ctx, id)
+	recordOperation(ctx, "get_project", err)
+	if err != nil {
+		logger.Log("event", "failed to retrieve project", "error", err)
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// GetProjects returns the projects of the user, all of them when username
+// is empty.
+func (s *Service) GetProjects(ctx context.Context, username string) ([]Project, error) {
+	ctx, span := trace.StartSpan(ctx, "todo.projects.get")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	projects, err := s.Repository.FindProjects(ctx, username)
+	recordOperation(ctx, "list_projects", err)
+	if err != nil {
+		logger.Log("event", "failed to retrieve projects", "error", err)
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// GetMembership returns the membership of the user in the project,
+// ErrNotFound when the user is not a member.
+func (s *Service) GetMembership(ctx context.Context, projectId uint, username string) (*Membership, error) {
+	ctx, span := trace.StartSpan(ctx, "todo.membership.get")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	m, err := s.Repository.GetMembership(ctx, projectId, username)
+	if err != nil && err != ErrNotFound {
+		logger.Log("event", "failed to retrieve membership", "error", err)
+	}
+	return m, err
+}
+
+func (s *Service) GetMembers(ctx context.Context, projectId uint) ([]Membership, error) {
+	ctx, span := trace.StartSpan(ctx, "todo.members.get")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	members, err := s.Repository.FindMemberships(ctx, projectId)
+	recordOperation(ctx, "list_members", err)
+	if err != nil {
+		logger.Log("event", "failed to retrieve members", "error", err)
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (s *Service) UpdateMember(ctx context.Context, member UpdateMember) (*Membership, error) {
+	ctx, span := trace.StartSpan(ctx, "todo.member.update")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	m := &Membership{
+		ID:        member.Id,
+		ProjectId: member.ProjectId,
+		Role:      member.Role,
+	}
+
+	err := s.Repository.UpdateMembership(ctx, m)
+	recordOperation(ctx, "update_member", err)
+	if err != nil {
+		logger.Log("event", "failed to update member", "error", err)
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (s *Service) RemoveMember(ctx context.Context, projectId, id uint) error {
+	ctx, span := trace.StartSpan(ctx, "todo.member.remove")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	err := s.Repository.RemoveMembership(ctx, projectId, id)
+	recordOperation(ctx, "remove_member", err)
+	if err != nil {
+		logger.Log("event", "failed to remove member", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) Invite(ctx context.Context, invite Invite) (*Invitation, error) {
+	ctx, span := trace.StartSpan(ctx, "todo.invitation.create")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	inv := &Invitation{
+		ProjectId: invite.ProjectId,
+		Username:  invite.Username,
+		Role:      invite.Role,
+		InvitedBy: invite.InvitedBy,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	err := s.Repository.CreateInvitation(ctx, inv)
+	recordOperation(ctx, "invite", err)
+	if err != nil {
+		logger.Log("event", "failed to create invitation", "error", err)
+		return nil, err
+	}
+
+	return inv, nil
+}
+
+func (s *Service) GetInvitation(ctx context.Context, id uint) (*Invitation, error) {
+	ctx, span := trace.StartSpan(ctx, "todo.invitation.get")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	inv, err := s.Repository.GetInvitation(ctx, id)
+	recordOperation(ctx, "get_invitation", err)
+	if err != nil {
+		logger.Log("event", "failed to retrieve invitation", "error", err)
+		return nil, err
+	}
+
+	return inv, nil
+}
+
+// GetInvitations returns the invitations to the project, or of the user,
+// zero values match any.
+func (s *Service) GetInvitations(ctx context.Context, projectId uint, username string) ([]Invitation, error) {
+	ctx, span := trace.StartSpan(ctx, "todo.invitations.get")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	invitations, err := s.Repository.FindInvitations(ctx, projectId, username)
+	recordOperation(ctx, "list_invitations", err)
+	if err != nil {
+		logger.Log("event", "failed to retrieve invitations", "error", err)
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+func (s *Service) AcceptInvitation(ctx context.Context, id uint) (*Membership, error) {
+	ctx, span := trace.StartSpan(ctx, "todo.invitation.accept")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	m, err := s.Repository.AcceptInvitation(ctx, id)
+	recordOperation(ctx, "accept_invitation", err)
+	if err != nil {
+		logger.Log("event", "failed to accept invitation", "error", err)
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (s *Service) DeleteInvitation(ctx context.Context, id uint) error {
+	ctx, span := trace.StartSpan(ctx, "todo.invitation.delete")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	err := s.Repository.DeleteInvitation(ctx, id)
+	recordOperation(ctx, "delete_invitation", err)
+	if err != nil {
+		logger.Log("event", "failed to delete invitation", "error", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package todo
+
+import (
+	"context"
+
+	"go.opencensus.io/trace"
+
+	"github.com/Neurostep/todo/pkg/markdown"
+	"github.com/Neurostep/todo/pkg/tools/logging"
+)
+
+// GetReferences returns the references of the todo and to it. Todos which
+// are gone since are left out.
+func (s *Service) GetReferences(ctx context.Context, todoId uint) (*TodoReferences, error) {
+	ctx, span := trace.StartSpan(ctx, "todo.references.get")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	refs, err := s.references(ctx, todoId)
+	recordOperation(ctx, "list_references", err)
+	if err != nil {
+		logger.Log("event", "failed to retrieve references", "error", err)
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+func (s *Service) references(ctx context.Context, todoId uint) (*TodoReferences, error) {
+	if _, err := s.Repository.GetTodo(ctx, todoId); err != nil {
+		return nil, err
+	}
+	out, err := s.Repository.FindReferences(ctx, todoId)
+	if err != nil {
+		return nil, err
+	}
+	in, err := s.Repository.FindBackReferences(ctx, todoId)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := &TodoReferences{Mentions: []string{}, Todos: []Todo{}, ReferencedBy: []Todo{}}
+	for _, ref := range out {
+		switch ref.Kind {
+		case ReferenceMention:
+			refs.Mentions = append(refs.Mentions, ref.Username)
+		case ReferenceTodo:
+			td, err := s.Repository.GetTodo(ctx, ref.TargetId)
+			if err == ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			refs.Todos = append(refs.Todos, *td)
+		}
+	}
+	for _, ref := range in {
+		td, err := s.Repository.GetTodo(ctx, ref.TodoId)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		refs.ReferencedBy = append(refs.ReferencedBy, *td)
+	}
+	return refs, nil
+}
+
+// link replaces the references of the todo by the ones of its description.
+// Todos referred to have to exist, a todo referring to itself does not
+// count. The todo is stored already, so failures are only logged.
+func (s *Service) link(ctx context.Context, td *Todo) {
+	extracted := markdown.Extract(td.Description)
+
+	refs := []Reference{}
+	for _, username := range extracted.Mentions {
+		refs = append(refs, Reference{Kind: ReferenceMention, Username: username})
+	}
+	for _, id := range extracted.Todos {
+		if id == td.ID {
+			continue
+		}
+		_, err := s.Repository.GetTodo(ctx, id)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			logging.FromContext(ctx, s.Logger).Log("event", "failed to retrieve todo", "error", err)
+			return
+		}
+		refs = append(refs, Reference{Kind: ReferenceTodo, TargetId: id})
+	}
+
+	err := s.Repository.SetReferences(ctx, td.ID, refs)
+	recordOperation(ctx, "set_references", err)
+	if err != nil {
+		logging.FromContext(ctx, s.Logger).Log("event", "failed to store references", "error", err)
+	}
+}
@@ -0,0 +1,113 @@
+package todo
+
+import (
+	"context"
+
+	"go.opencensus.io/trace"
+
+	"github.com/Neurostep/todo/pkg/tools/logging"
+)
+
+func (s *Service) AddWatcher(ctx context.Context, watcher AddWatcher) (*Watcher, error) {
+	ctx, span := trace.StartSpan(ctx, "todo.watcher.add")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	w := &Watcher{
+		TodoId:   watcher.TodoId,
+		Username: watcher.Username,
+	}
+
+	err := s.Repository.AddWatcher(ctx, w)
+	recordOperation(ctx, "add_watcher", err)
+	if err != nil {
+		logger.Log("event", "failed to add watcher", "error", err)
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (s *Service) RemoveWatcher(ctx context.Context, todoId, id uint) error {
+	ctx, span := trace.StartSpan(ctx, "todo.watcher.remove")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	err := s.Repository.RemoveWatcher(ctx, todoId, id)
+	recordOperation(ctx, "remove_watcher", err)
+	if err != nil {
+		logger.Log("event", "failed to remove watcher", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) GetWatchers(ctx context.Context, todoId uint) ([]Watcher, error) {
+	ctx, span := trace.StartSpan(ctx, "todo.watchers.get")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.Logger)
+
+	watchers, err := s.Repository.FindWatchers(ctx, todoId)
+	recordOperation(ctx, "list_watchers", err)
+	if err != nil {
+		logger.Log("event", "failed to retrieve watchers", "error", err)
+		return nil, err
+	}
+
+	return watchers, nil
+}
+
+// watch subscribes the user to the todo, watching it already is fine.
+func (s *Service) watch(ctx context.Context, todoId uint, username string) {
+	if username == "" {
+		return
+	}
+	err := s.Repository.AddWatcher(ctx, &Watcher{TodoId: todoId, Username: username})
+	if err != nil && err != ErrConflict {
+		logging.FromContext(ctx, s.Logger).Log("event", "failed to add watcher", "error", err)
+	}
+}
+
+// notify tells the watchers of td about the event.
+func (s *Service) notify(ctx context.Context, event string, td *Todo) {
+	if s.Notifier == nil {
+		return
+	}
+	watchers, err := s.Repository.FindWatchers(ctx, td.ID)
+	if err != nil {
+		logging.FromContext(ctx, s.Logger).Log("event", "failed to retrieve watchers", "error", err)
+		return
+	}
+	s.send(ctx, event, td, watchers)
+}
+
+// send notifies the watchers but the user making the change. The change is
+// made already, so failing deliveries are only logged.
+func (s *Service) send(ctx context.Context, event string, td *Todo, watchers []Watcher) {
+	if s.Notifier == nil {
+		return
+	}
+	actor := userFromContext(ctx)
+	var recipients []string
+	for _, w := range watchers {
+		if w.Username != actor {
+			recipients = append(recipients, w.Username)
+		}
+	}
+	if len(recipients) == 0 {
+		return
+	}
+
+	err := s.Notifier.Notify(ctx, Notification{
+		Event:      event,
+		TodoId:     td.ID,
+		Title:      td.Title,
+		Actor:      actor,
+		Recipients: recipients,
+	})
+	recordOperation(ctx, "notify", err)
+	if err != nil {
+		logging.FromContext(ctx, s.Logger).Log("event", "failed to notify watchers", "error", err)
+	}
+}
@@ -5,10 +5,26 @@ import (
 )
 
 type Todo struct {
-	ID      uint      `gorm:"primary_key"`
-	Title   string    `gorm:"title"`
-	DueDate time.Time `gorm:"due_date"`
-	Done    bool      `gorm:"done"`
+	ID    uint   `gorm:"primary_key"`
+	Title string `gorm:"title"`
+	// Description is the Markdown description of the todo
+	Description string    `gorm:"type:text"`
+	DueDate     time.Time `gorm:"due_date"`
+	Done        bool      `gorm:"done"`
+	// CreatedAt is set on creation, CompletedAt when the todo gets done
+	CreatedAt   time.Time  `gorm:"created_at"`
+	CompletedAt *time.Time `gorm:"completed_at"`
+	// ProjectId is the project of the todo, zero for the todos shared by
+	// every user
+	ProjectId uint `gorm:"default:null"`
+	// Assignee is the user doing the todo, empty when nobody is
+	Assignee string   `gorm:"assignee"`
+	Priority Priority `gorm:"priority"`
+	// EstimateMinutes is the time the todo is expected to take, zero when
+	// it is not estimated
+	EstimateMinutes uint `gorm:"estimate_minutes"`
+	// Fields are the values of the custom fields of the project of the todo
+	Fields Fields `gorm:"type:text"`
 }
 
 func (t Todo) TableName() string {
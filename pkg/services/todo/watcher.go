@@ -0,0 +1,12 @@
+package todo
+
+// Watcher is a user notified of the changes of a todo.
+type Watcher struct {
+	ID       uint `gorm:"primary_key"`
+	TodoId   uint
+	Username string `gorm:"username"`
+}
+
+func (w Watcher) TableName() string {
+	return "watchers"
+}
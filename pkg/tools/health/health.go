@@ -0,0 +1,162 @@
+// Package health collects the checks deciding whether the service is ready
+// to receive traffic.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	StatusOK           = "ok"
+	StatusFailing      = "failing"
+	StatusShuttingDown = "shutting_down"
+
+	defaultTimeout = time.Second
+	defaultTTL     = 2 * time.Second
+)
+
+type (
+	// CheckFunc reports the health of a component, ctx carries the timeout
+	// of the check.
+	CheckFunc func(ctx context.Context) error
+
+	// Check is a health check of a single component. Failing critical
+	// checks make the service unready, the others are only reported.
+	Check struct {
+		Name     string
+		Critical bool
+		// Timeout bounds a single run, TTL is how long its result is
+		// reused. Zero values use the defaults of the package.
+		Timeout time.Duration
+		TTL     time.Duration
+		Func    CheckFunc
+	}
+
+	Result struct {
+		Name      string    `json:"name"`
+		Status    string    `json:"status"`
+		Critical  bool      `json:"critical"`
+		Error     string    `json:"error,omitempty"`
+		Duration  string    `json:"duration"`
+		CheckedAt time.Time `json:"checked_at"`
+	}
+
+	Report struct {
+		Status string   `json:"status"`
+		Checks []Result `json:"checks"`
+	}
+
+	// Registry runs the registered checks. The zero value is not usable,
+	// see New.
+	Registry struct {
+		mu           sync.RWMutex
+		checks       []*check
+		shuttingDown int32
+		now          func() time.Time
+	}
+
+	check struct {
+		Check
+		mu     sync.Mutex
+		result Result
+	}
+)
+
+func New() *Registry {
+	return &Registry{now: time.Now}
+}
+
+// Register adds a check to the registry.
+func (r *Registry) Register(c Check) {
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	if c.TTL <= 0 {
+		c.TTL = defaultTTL
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, &check{Check: c})
+}
+
+// Shutdown marks the service as shutting down, it is never ready afterwards
+// so load balancers stop routing to it before the listener closes.
+func (r *Registry) Shutdown() {
+	atomic.StoreInt32(&r.shuttingDown, 1)
+}
+
+// Ready runs the checks concurrently, reusing results younger than their
+// TTL, and reports the service ready unless a critical one fails.
+func (r *Registry) Ready(ctx context.Context) (Report, bool) {
+	r.mu.RLock()
+	checks := r.checks
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make([]Result, len(checks))}
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx, r.now)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, res := range report.Checks {
+		if res.Critical && res.Status != StatusOK {
+			report.Status = StatusFailing
+		}
+	}
+	if atomic.LoadInt32(&r.shuttingDown) == 1 {
+		report.Status = StatusShuttingDown
+	}
+
+	return report, report.Status == StatusOK
+}
+
+// run returns the cached result or runs the check. Holding the lock while
+// running makes concurrent probes wait for a single run.
+func (c *check) run(ctx context.Context, now func() time.Time) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	started := now()
+	if !c.result.CheckedAt.IsZero() && started.Sub(c.result.CheckedAt) < c.TTL {
+		return c.result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	errc := make(chan error, 1)
+	go func() { errc <- c.Func(ctx) }()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = errors.Wrap(ctx.Err(), "check did not finish in time")
+	}
+
+	c.result = Result{
+		Name:      c.Name,
+		Status:    StatusOK,
+		Critical:  c.Critical,
+		Duration:  now().Sub(started).String(),
+		CheckedAt: started,
+	}
+	if err != nil {
+		c.result.Status = StatusFailing
+		c.result.Error = err.Error()
+	}
+
+	return c.result
+}
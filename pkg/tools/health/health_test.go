@@ -0,0 +1,81 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReady(t *testing.T) {
+	var dbErr error
+	r := New()
+	r.Register(Check{Name: "database", Critical: true, TTL: time.Nanosecond, Func: func(context.Context) error {
+		return dbErr
+	}})
+	r.Register(Check{Name: "exporter", Func: func(context.Context) error {
+		return errors.New("export failed")
+	}})
+
+	report, ready := r.Ready(context.Background())
+	require.True(t, ready, "non-critical failures keep the service ready")
+	require.Equal(t, StatusOK, report.Status)
+	require.Len(t, report.Checks, 2)
+	require.Equal(t, "database", report.Checks[0].Name)
+	require.Equal(t, StatusFailing, report.Checks[1].Status)
+	require.Equal(t, "export failed", report.Checks[1].Error)
+
+	dbErr = errors.New("connection refused")
+	time.Sleep(time.Millisecond)
+	report, ready = r.Ready(context.Background())
+	require.False(t, ready)
+	require.Equal(t, StatusFailing, report.Status)
+	require.Equal(t, "connection refused", report.Checks[0].Error)
+}
+
+func TestReadyTimeout(t *testing.T) {
+	r := New()
+	r.Register(Check{Name: "slow", Critical: true, Timeout: 10 * time.Millisecond, Func: func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}})
+
+	started := time.Now()
+	report, ready := r.Ready(context.Background())
+	require.False(t, ready)
+	require.Less(t, int64(time.Since(started)), int64(500*time.Millisecond))
+	require.Contains(t, report.Checks[0].Error, "did not finish in time")
+}
+
+func TestReadyCaching(t *testing.T) {
+	now := time.Now()
+	r := New()
+	r.now = func() time.Time { return now }
+
+	runs := 0
+	r.Register(Check{Name: "database", TTL: time.Second, Func: func(context.Context) error {
+		runs++
+		return nil
+	}})
+
+	r.Ready(context.Background())
+	r.Ready(context.Background())
+	require.Equal(t, 1, runs)
+
+	now = now.Add(2 * time.Second)
+	r.Ready(context.Background())
+	require.Equal(t, 2, runs)
+}
+
+func TestShutdown(t *testing.T) {
+	r := New()
+	_, ready := r.Ready(context.Background())
+	require.True(t, ready)
+
+	r.Shutdown()
+	report, ready := r.Ready(context.Background())
+	require.False(t, ready)
+	require.Equal(t, StatusShuttingDown, report.Status)
+}
@@ -2,16 +2,106 @@ package logging
 
 import (
 	"context"
+	"fmt"
+	"sync/atomic"
 
 	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
 	"go.opencensus.io/trace"
 )
 
+// Formats of the fields correlating log entries with traces.
+const (
+	// FormatOTel names them after the OpenTelemetry log data model
+	FormatOTel = "otel"
+	// FormatECS names them after the Elastic Common Schema
+	FormatECS = "ecs"
+	// FormatStackdriver names them after the special fields of Cloud
+	// Logging, see https://cloud.google.com/logging/docs/structured-logging
+	FormatStackdriver = "stackdriver"
+)
+
+type (
+	requestIDKey struct{}
+
+	// traceFields names the fields of the trace and the span, traceName
+	// formats the trace id. sampled optionally tells whether the trace is
+	// sampled, as formatted by sampledValue.
+	traceFields struct {
+		trace, span, sampled string
+		traceName            func(id string) string
+		sampledValue         func(trace.SpanContext) interface{}
+	}
+)
+
+var fields atomic.Value // traceFields
+
+func init() {
+	f, _ := formatFields(FormatOTel, "")
+	fields.Store(f)
+}
+
+// SetTraceFormat selects the fields FromContext correlates entries with
+// traces by. project is the Google Cloud project of FormatStackdriver, trace
+// ids are resource names of its traces.
+func SetTraceFormat(format, project string) error {
+	f, err := formatFields(format, project)
+	if err != nil {
+		return err
+	}
+	fields.Store(f)
+	return nil
+}
+
+func formatFields(format, project string) (traceFields, error) {
+	f := traceFields{traceName: func(id string) string { return id }}
+	switch format {
+	case "", FormatOTel:
+		f.trace, f.span, f.sampled = "trace_id", "span_id", "trace_flags"
+		f.sampledValue = func(sc trace.SpanContext) interface{} { return fmt.Sprintf("%02x", uint32(sc.TraceOptions)) }
+	case FormatECS:
+		f.trace, f.span = "trace.id", "span.id"
+	case FormatStackdriver:
+		f.trace, f.span, f.sampled = "logging.googleapis.com/trace", "logging.googleapis.com/spanId", "logging.googleapis.com/trace_sampled"
+		f.sampledValue = func(sc trace.SpanContext) interface{} { return sc.IsSampled() }
+		if project != "" {
+			f.traceName = func(id string) string { return "projects/" + project + "/traces/" + id }
+		}
+	default:
+		return f, errors.Errorf("unsupported trace format %q", format)
+	}
+	return f, nil
+}
+
+// WithRequestID returns a context carrying the id of the request, which
+// FromContext adds to the entries.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the id of the request of ctx, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext returns the logger of the entries of the request of ctx, they
+// carry its id and the ids of its trace and span.
 func FromContext(ctx context.Context, defaultLogger log.Logger) log.Logger {
+	logger := defaultLogger
+	if id := RequestID(ctx); id != "" {
+		logger = log.With(logger, "request_id", id)
+	}
+
 	span := trace.FromContext(ctx)
 	if span == nil {
-		return defaultLogger
+		return logger
+	}
+	sc := span.SpanContext()
+	f := fields.Load().(traceFields)
+	logger = log.With(logger, f.trace, f.traceName(sc.TraceID.String()), f.span, sc.SpanID.String())
+	if f.sampled != "" {
+		logger = log.With(logger, f.sampled, f.sampledValue(sc))
 	}
-	// https://cloud.google.com/logging/docs/agent/configuration#special-fields
-	return log.With(defaultLogger, "logging.googleapis.com/spanId", span.SpanContext().TraceID)
+	return logger
 }
@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/trace"
+)
+
+func TestFromContext(t *testing.T) {
+	defer SetTraceFormat(FormatOTel, "")
+
+	var entry map[string]interface{}
+	logger := log.LoggerFunc(func(kv ...interface{}) error {
+		entry = map[string]interface{}{}
+		for i := 0; i < len(kv); i += 2 {
+			entry[kv[i].(string)] = kv[i+1]
+		}
+		return nil
+	})
+
+	FromContext(context.Background(), logger).Log("event", "started")
+	require.Equal(t, map[string]interface{}{"event": "started"}, entry)
+
+	ctx, span := trace.StartSpan(WithRequestID(context.Background(), "req-1"), "test", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+	sc := span.SpanContext()
+	traceID, spanID := sc.TraceID.String(), sc.SpanID.String()
+
+	for format, want := range map[string]map[string]interface{}{
+		FormatOTel: {"trace_id": traceID, "span_id": spanID, "trace_flags": "01"},
+		FormatECS:  {"trace.id": traceID, "span.id": spanID},
+		FormatStackdriver: {
+			"logging.googleapis.com/trace":         "projects/todo-prod/traces/" + traceID,
+			"logging.googleapis.com/spanId":        spanID,
+			"logging.googleapis.com/trace_sampled": true,
+		},
+	} {
+		require.NoError(t, SetTraceFormat(format, "todo-prod"))
+		FromContext(ctx, logger).Log("event", "handled")
+
+		want["event"], want["request_id"] = "handled", "req-1"
+		require.Equal(t, want, entry, format)
+	}
+
+	require.Error(t, SetTraceFormat("syslog", ""))
+}
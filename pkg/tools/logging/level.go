@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"sync/atomic"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// Levels of the entries, from the most to the least verbose.
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+var levels = map[string]int32{LevelDebug: 0, LevelInfo: 1, LevelWarn: 2, LevelError: 3}
+
+// minLevel is the least level of the entries Filter lets through.
+var minLevel = levels[LevelInfo]
+
+type filter struct {
+	next log.Logger
+}
+
+// SetLevel sets the least level of the entries kept by the loggers of
+// Filter, it applies to the entries logged from then on. An empty level is
+// info.
+func SetLevel(name string) error {
+	if name == "" {
+		name = LevelInfo
+	}
+	l, ok := levels[name]
+	if !ok {
+		return errors.Errorf("unsupported log level %q", name)
+	}
+	atomic.StoreInt32(&minLevel, l)
+	return nil
+}
+
+// Filter returns a logger dropping the entries below the level of SetLevel.
+// Entries without a level are error entries when they carry an error, info
+// entries otherwise.
+func Filter(next log.Logger) log.Logger {
+	return filter{next: next}
+}
+
+func (f filter) Log(keyvals ...interface{}) error {
+	if entryLevel(keyvals) < atomic.LoadInt32(&minLevel) {
+		return nil
+	}
+	return f.next.Log(keyvals...)
+}
+
+func entryLevel(keyvals []interface{}) int32 {
+	l := levels[LevelInfo]
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		switch keyvals[i] {
+		case level.Key():
+			if v, ok := keyvals[i+1].(level.Value); ok {
+				if lvl, ok := levels[v.String()]; ok {
+					return lvl
+				}
+			}
+		case "error":
+			l = levels[LevelError]
+		}
+	}
+	return l
+}
@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	defer SetLevel(LevelInfo)
+
+	var entries int
+	logger := Filter(log.LoggerFunc(func(kv ...interface{}) error {
+		entries++
+		return nil
+	}))
+
+	logged := func(kv ...interface{}) bool {
+		entries = 0
+		require.NoError(t, logger.Log(kv...))
+		return entries == 1
+	}
+
+	require.True(t, logged("event", "started"))
+	require.False(t, logged(level.Key(), level.DebugValue(), "event", "query"))
+
+	require.NoError(t, SetLevel(LevelError))
+	require.False(t, logged("event", "started"), "entries without a level are info")
+	require.False(t, logged(level.Key(), level.WarnValue(), "event", "slow"))
+	require.True(t, logged("event", "export_failed", "error", "timeout"), "entries with an error are errors")
+
+	require.NoError(t, SetLevel(LevelDebug))
+	require.True(t, logged(level.Key(), level.DebugValue(), "event", "query"))
+
+	require.Error(t, SetLevel("verbose"))
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// exporterErrorWindow is how long an export error makes the exporter
+// unhealthy.
+const exporterErrorWindow = time.Minute
+
+// ExporterStatus remembers the last error reported by an exporter.
+type ExporterStatus struct {
+	mu  sync.Mutex
+	err error
+	at  time.Time
+}
+
+// Report records an export error, it fits the OnError callbacks of the
+// exporters.
+func (s *ExporterStatus) Report(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err, s.at = err, time.Now()
+}
+
+// Check fails while the last error is recent, it is meant as a health check.
+func (s *ExporterStatus) Check(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil && time.Since(s.at) < exporterErrorWindow {
+		return errors.Wrapf(s.err, "export failed at %s", s.at.Format(time.RFC3339))
+	}
+	return nil
+}
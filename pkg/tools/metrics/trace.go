@@ -1,25 +1,157 @@
 package metrics
 
 import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
 	"contrib.go.opencensus.io/exporter/stackdriver"
 	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
 	"go.opencensus.io/trace"
+	"go.opentelemetry.io/otel"
+	ocbridge "go.opentelemetry.io/otel/bridge/opencensus"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/exporters/zipkin"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterStackdriver = "stackdriver"
+	ExporterOTLPGRPC    = "otlp-grpc"
+	ExporterOTLPHTTP    = "otlp-http"
+	ExporterZipkin      = "zipkin"
+	ExporterStdout      = "stdout"
+
+	SamplerAlways      = "always"
+	SamplerNever       = "never"
+	SamplerRatio       = "ratio"
+	SamplerRateLimited = "ratelimited"
+)
+
+// shutdownTimeout bounds flushing the spans left on shutdown.
+const shutdownTimeout = 5 * time.Second
+
+type (
+	Config struct {
+		// TracingEnable exports traces to Stackdriver, unless Tracing names
+		// another exporter
+		TracingEnable bool
+		Tracing       Tracing
+		// OnError is called with the errors of the exporters, optional
+		OnError func(error)
+	}
+
+	// Tracing configures the export and the sampling of traces.
+	Tracing struct {
+		// Exporter is one of the Exporter* constants, tracing is disabled
+		// when empty unless TracingEnable is set
+		Exporter string
+		// Endpoint is the collector: host:port for OTLP, the URL of the
+		// spans API for Zipkin. OTLP defaults to the local collector.
+		Endpoint string
+		// Insecure disables TLS towards the OTLP collector
+		Insecure bool
+		// Headers are sent with every OTLP export, such as API keys
+		Headers map[string]string
+
+		// Sampler is one of the Sampler* constants, SamplerAlways by
+		// default. SamplerRatio samples Ratio of the traces,
+		// SamplerRateLimited up to PerSecond traces a second.
+		Sampler   string
+		Ratio     float64
+		PerSecond float64
+		// ParentBased follows the decision of the parent span, such as the
+		// one of a traceparent header, and applies Sampler to new traces
+		ParentBased bool
+	}
+
+	// rateLimitedSampler samples up to perSecond traces a second, with
+	// bursts of up to a second worth of traces.
+	rateLimitedSampler struct {
+		mu        sync.Mutex
+		perSecond float64
+		burst     float64
+		tokens    float64
+		last      time.Time
+		now       func() time.Time
+	}
 )
 
-type Config struct {
-	TracingEnable bool
+func (c Config) exporter() string {
+	if c.Tracing.Exporter == "" && c.TracingEnable {
+		return ExporterStackdriver
+	}
+	return c.Tracing.Exporter
 }
 
+// SetupTracer exports the spans of the service as configured. Spans are
+// started with OpenCensus, they are bridged to OpenTelemetry for every
+// exporter but Stackdriver. The returned function flushes and stops the
+// export.
 func SetupTracer(env string, cfg Config, logger log.Logger) (func(), error) {
-	if !cfg.TracingEnable {
+	onError := func(err error) {
+		logger.Log("event", "tracing_export_failed", "error", err)
+		if cfg.OnError != nil {
+			cfg.OnError(err)
+		}
+	}
+
+	switch cfg.exporter() {
+	case "":
 		logger.Log("event", "tracing not enabled")
 		return func() {}, nil
+	case ExporterStackdriver:
+		return setupStackdriver(env, cfg, onError)
+	}
+
+	exporter, err := newExporter(cfg.Tracing)
+	if err != nil {
+		return func() {}, err
+	}
+	sampler, err := cfg.Tracing.sampler()
+	if err != nil {
+		return func() {}, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(env))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(onError))
+
+	previous := trace.DefaultTracer
+	trace.DefaultTracer = ocbridge.NewTracer(provider.Tracer("github.com/Neurostep/todo"))
+	logger.Log("event", "tracing_enabled", "exporter", cfg.Tracing.Exporter, "sampler", sampler.Description())
+
+	return func() {
+		trace.DefaultTracer = previous
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			onError(err)
+		}
+	}, nil
+}
+
+func setupStackdriver(env string, cfg Config, onError func(error)) (func(), error) {
+	sampler, err := cfg.Tracing.ocSampler()
+	if err != nil {
+		return func() {}, err
 	}
 
 	exporter, err := stackdriver.NewExporter(stackdriver.Options{
-		OnError: func(err error) {
-			logger.Log("event", "stackdriver_error", "error", err)
-		},
+		OnError: onError,
 		DefaultTraceAttributes: map[string]interface{}{
 			"env": env,
 		},
@@ -30,8 +162,130 @@ func SetupTracer(env string, cfg Config, logger log.Logger) (func(), error) {
 
 	trace.RegisterExporter(exporter)
 	trace.ApplyConfig(trace.Config{
-		DefaultSampler: trace.AlwaysSample(),
+		DefaultSampler: sampler,
 	})
 
-	return func() { trace.UnregisterExporter(exporter) }, nil
+	return func() {
+		trace.UnregisterExporter(exporter)
+		exporter.Flush()
+	}, nil
+}
+
+func newExporter(cfg Tracing) (sdktrace.SpanExporter, error) {
+	ctx := context.Background()
+	switch cfg.Exporter {
+	case ExporterOTLPGRPC:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithHeaders(cfg.Headers)}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	case ExporterOTLPHTTP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithHeaders(cfg.Headers)}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case ExporterZipkin:
+		return zipkin.New(cfg.Endpoint)
+	case ExporterStdout:
+		return stdouttrace.New()
+	default:
+		return nil, errors.Errorf("unsupported tracing exporter %q", cfg.Exporter)
+	}
+}
+
+// sampler builds the OpenTelemetry sampler of the configuration.
+func (cfg Tracing) sampler() (sdktrace.Sampler, error) {
+	var s sdktrace.Sampler
+	switch cfg.Sampler {
+	case "", SamplerAlways:
+		s = sdktrace.AlwaysSample()
+	case SamplerNever:
+		s = sdktrace.NeverSample()
+	case SamplerRatio:
+		s = sdktrace.TraceIDRatioBased(cfg.Ratio)
+	case SamplerRateLimited:
+		s = newRateLimitedSampler(cfg.PerSecond)
+	default:
+		return nil, errors.Errorf("unsupported trace sampler %q", cfg.Sampler)
+	}
+	if cfg.ParentBased {
+		s = sdktrace.ParentBased(s)
+	}
+	return s, nil
+}
+
+// ocSampler builds the OpenCensus sampler of the configuration, spans with
+// a parent in the process always follow it.
+func (cfg Tracing) ocSampler() (trace.Sampler, error) {
+	var s trace.Sampler
+	switch cfg.Sampler {
+	case "", SamplerAlways:
+		s = trace.AlwaysSample()
+	case SamplerNever:
+		s = trace.NeverSample()
+	case SamplerRatio:
+		s = trace.ProbabilitySampler(cfg.Ratio)
+	case SamplerRateLimited:
+		rl := newRateLimitedSampler(cfg.PerSecond)
+		s = func(trace.SamplingParameters) trace.SamplingDecision {
+			return trace.SamplingDecision{Sample: rl.take()}
+		}
+	default:
+		return nil, errors.Errorf("unsupported trace sampler %q", cfg.Sampler)
+	}
+	if !cfg.ParentBased {
+		return s, nil
+	}
+	return func(p trace.SamplingParameters) trace.SamplingDecision {
+		if p.ParentContext != (trace.SpanContext{}) {
+			return trace.SamplingDecision{Sample: p.ParentContext.IsSampled()}
+		}
+		return s(p)
+	}, nil
+}
+
+func newRateLimitedSampler(perSecond float64) *rateLimitedSampler {
+	burst := math.Max(perSecond, 1)
+	return &rateLimitedSampler{perSecond: perSecond, burst: burst, tokens: burst, now: time.Now}
+}
+
+// take reports whether a trace may be sampled.
+func (s *rateLimitedSampler) take() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if !s.last.IsZero() {
+		s.tokens += now.Sub(s.last).Seconds() * s.perSecond
+		s.tokens = math.Min(s.tokens, s.burst)
+	}
+	s.last = now
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+func (s *rateLimitedSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	decision := sdktrace.Drop
+	if s.take() {
+		decision = sdktrace.RecordAndSample
+	}
+	return sdktrace.SamplingResult{
+		Decision:   decision,
+		Tracestate: oteltrace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+func (s *rateLimitedSampler) Description() string {
+	return "RateLimited"
 }
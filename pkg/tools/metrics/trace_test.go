@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/trace"
+)
+
+func TestSamplers(t *testing.T) {
+	for _, tc := range []struct {
+		cfg  Tracing
+		want string
+	}{
+		{cfg: Tracing{}, want: "AlwaysOnSampler"},
+		{cfg: Tracing{Sampler: SamplerNever}, want: "AlwaysOffSampler"},
+		{cfg: Tracing{Sampler: SamplerRatio, Ratio: 0.25}, want: "TraceIDRatioBased{0.25}"},
+		{cfg: Tracing{Sampler: SamplerRateLimited}, want: "RateLimited"},
+		{
+			cfg:  Tracing{Sampler: SamplerRatio, ParentBased: true},
+			want: "ParentBased{root:TraceIDRatioBased{0},remoteParentSampled:AlwaysOnSampler,remoteParentNotSampled:AlwaysOffSampler,localParentSampled:AlwaysOnSampler,localParentNotSampled:AlwaysOffSampler}",
+		},
+	} {
+		cfg := tc.cfg
+		s, err := cfg.sampler()
+		require.NoError(t, err)
+		require.Equal(t, tc.want, s.Description())
+
+		_, err = cfg.ocSampler()
+		require.NoError(t, err)
+	}
+
+	_, err := Tracing{Sampler: "sometimes"}.sampler()
+	require.Error(t, err)
+
+	parentBased, err := Tracing{Sampler: SamplerNever, ParentBased: true}.ocSampler()
+	require.NoError(t, err)
+	sampled := trace.SpanContext{TraceID: trace.TraceID{1}, TraceOptions: 1}
+	require.True(t, parentBased(trace.SamplingParameters{ParentContext: sampled}).Sample)
+	require.False(t, parentBased(trace.SamplingParameters{}).Sample)
+}
+
+func TestRateLimitedSampler(t *testing.T) {
+	now := time.Date(2022, 8, 1, 10, 0, 0, 0, time.UTC)
+	s := newRateLimitedSampler(2)
+	s.now = func() time.Time { return now }
+
+	require.True(t, s.take())
+	require.True(t, s.take())
+	require.False(t, s.take(), "the traces of the second are sampled")
+
+	now = now.Add(500 * time.Millisecond)
+	require.True(t, s.take())
+	require.False(t, s.take())
+
+	s = newRateLimitedSampler(0.5)
+	s.now = func() time.Time { return now }
+	require.True(t, s.take(), "a trace at least is sampled")
+	require.False(t, s.take())
+	now = now.Add(2 * time.Second)
+	require.True(t, s.take())
+}
+
+func TestSetupTracer(t *testing.T) {
+	done, err := SetupTracer("test", Config{}, log.NewNopLogger())
+	require.NoError(t, err)
+	done()
+
+	_, err = SetupTracer("test", Config{Tracing: Tracing{Exporter: "carrier-pigeon"}}, log.NewNopLogger())
+	require.Error(t, err)
+
+	previous := trace.DefaultTracer
+	done, err = SetupTracer("test", Config{Tracing: Tracing{Exporter: ExporterStdout}}, log.NewNopLogger())
+	require.NoError(t, err)
+	require.False(t, previous == trace.DefaultTracer, "OpenCensus spans are bridged")
+	done()
+	require.True(t, previous == trace.DefaultTracer)
+}